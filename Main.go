@@ -2,16 +2,25 @@ package main
 
 import (
 	"bufio"
+	"encoding/csv"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -20,6 +29,10 @@ const (
 	SCHEDULE_DIR             = "plans"
 	CONFIG_FILE              = "config.json"
 	STATE_FILE               = "data/schedule_state.json"
+	STATE_BACKUP_DIR         = "data/backups"
+	STATE_BACKUP_LIMIT       = 10
+	EVENT_LOG_FILE           = "data/events.jsonl"
+	PERFORMANCE_FILE         = "performance_state.json"
 	PROGRESS_FILE            = "session_progess.tmp"
 	REVISION_TIME_HRS        = 0.5 
 	MAX_REVISIONS            = 4   
@@ -46,6 +59,12 @@ type Config struct {
 	RestDayActivity          string        `json:"rest_day_activity"`
 	InitialDifficultyRating  float64       `json:"initial_difficulty_rating"`
 	DifficultyAdjustmentRate float64       `json:"difficulty_adjustment_rate"`
+	RevisionModel            string        `json:"revision_model"` // fixed | sm2 | fsrs
+	PerformanceWindowDays    int           `json:"performance_window_days"` // 0 = all time
+	Rotation                 RotationPolicy `json:"rotation"`
+	ActivityMix              map[string]map[string]float64 `json:"activity_mix,omitempty"` // chapter ID or subject -> activity -> share
+	Timetable                map[string][]string `json:"timetable,omitempty"` // weekday or "default" -> "HH:MM-HH:MM" study blocks
+	Calendar                 []CalendarOverride `json:"calendar,omitempty"` // date-specific rest days and reduced days
 	InitialWorkload          []ChapterWorkload `json:"initial_workload"`
 }
 
@@ -62,6 +81,21 @@ type ChapterWorkload struct {
 	NextRevisionDate            string  `json:"next_revision_date"`
 	RevisionCount               int     `json:"revision_count"`
 	PriorityScore               float64 `json:"priority_score"`
+	Prerequisites               []string `json:"prerequisites,omitempty"` // chapter IDs to finish studying first
+	SubTopics                   []SubTopic `json:"sub_topics,omitempty"`  // when set, RemainingTime is derived from them
+
+	// Spaced-repetition memory, maintained by the configured RevisionModel.
+	EaseFactor      float64 `json:"ease_factor,omitempty"`
+	IntervalDays    int     `json:"interval_days,omitempty"`
+	RecallStreak    int     `json:"recall_streak,omitempty"`
+	Stability       float64 `json:"stability,omitempty"`
+	LastReviewDate  string  `json:"last_review_date,omitempty"`
+	LastRecallGrade int     `json:"last_recall_grade,omitempty"`
+
+	// Mean of how far below target the chapter scored in its MockScores mock
+	// test results (0 = on target, 1 = nothing right); see applyMockScore.
+	MockWeakness float64 `json:"mock_weakness,omitempty"`
+	MockScores   int     `json:"mock_scores,omitempty"`
 }
 
 type ScheduleState struct {
@@ -81,6 +115,10 @@ type Session struct {
 	ChapterID string  `json:"chapter_id"`
 	Type      string  `json:"type"` 
 	Status    string  `json:"status"` 
+	Topics    []string `json:"topics,omitempty"` // sub-topics to cover in a study session
+	Activity  string   `json:"activity,omitempty"` // kind of work, see ActivityTheory etc.
+	Start     string   `json:"start,omitempty"`    // "HH:MM", only with a timetable
+	End       string   `json:"end,omitempty"`
 }
 
 type Progress struct {
@@ -91,13 +129,45 @@ type Progress struct {
 
 type command struct {
 	action string
+	raw    string // the line as typed, for free-text answers
+}
+
+// SessionRating is the self-assessment asked for after every completed
+// session. Understanding and Focus run from 1 (poor) to 5 (excellent).
+type SessionRating struct {
+	Understanding int    `json:"understanding"`
+	Focus         int    `json:"focus"`
+	Notes         string `json:"notes,omitempty"`
 }
 
 var rawConfig Config
 var randSource *rand.Rand
 
+// clock is where the scheduler reads "now" from. Everything that decides which
+// day it is goes through it, so a simulation or test can move the calendar.
+var clock Clock = systemClock{}
+
+// logOut receives the scheduler's status and warning messages. It is stdout,
+// except that json and csv commands move it to stderr so that stdout carries
+// only the document, and a simulation discards it.
+var logOut io.Writer = os.Stdout
+
 func init() {
-	randSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+	seedRandom(0)
+}
+
+// seedRandom makes the scheduler's random choices reproducible. Seed 0 picks a
+// time-based seed.
+func seedRandom(seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	randSource = rand.New(rand.NewSource(seed))
+}
+
+// currentDay is today's date at midnight according to clock.
+func currentDay() time.Time {
+	return clock.Now().Truncate(24 * time.Hour)
 }
 
 func startMusic() {
@@ -132,11 +202,11 @@ func stopMusic()   { exec.Command("pkill", "mpv").Run() }
 func loadConfig() Config {
 	data, err := os.ReadFile(CONFIG_FILE)
 	if err != nil {
-		fmt.Println(ColorRed + "[WARNING] Creating default config.json. Please edit it with your full syllabus." + ColorReset)
+		fmt.Fprintln(logOut, ColorRed + "[WARNING] Creating default config.json. Please edit it with your full syllabus." + ColorReset)
 
 		defaultConfig := Config{
-			SyllabusEndDate:          time.Now().AddDate(0, 3, 0).Format(TIME_FORMAT),
-			ExamDate:                 time.Now().AddDate(0, 3, 10).Format(TIME_FORMAT),
+			SyllabusEndDate:          clock.Now().AddDate(0, 3, 0).Format(TIME_FORMAT),
+			ExamDate:                 clock.Now().AddDate(0, 3, 10).Format(TIME_FORMAT),
 			DailyStudyHrs:            8.0,
 			MaxSessionHrs:            1.5,
 			DailyBufferMins:          30,
@@ -144,13 +214,20 @@ func loadConfig() Config {
 			RestDayActivity:          "Mock Test & Review",
 			InitialDifficultyRating:  3.0,
 			DifficultyAdjustmentRate: 0.1,
+			RevisionModel:            RevisionModelSM2,
+			PerformanceWindowDays:    14,
+			ActivityMix: map[string]map[string]float64{
+				"Physics":   {ActivityTheory: 0.4, ActivityProblems: 0.6},
+				"Chemistry": {ActivityTheory: 0.5, ActivityProblems: 0.3, ActivityPYQ: 0.2},
+				"Biology":   {ActivityTheory: 0.7, ActivityPYQ: 0.3},
+			},
 			InitialWorkload: []ChapterWorkload{
 
     {ID: "PH001", Subject: "Physics", Chapter: "Motion in a Straight Line", InitialTotalTime: 12.5, Weightage: 1.2, InitialRevisionIntervalDays: 3, Difficulty: 3.0, RemainingTime: 8.5, IsStudyCompleted: false},
-    {ID: "PH002", Subject: "Physics", Chapter: "Laws of Motion", InitialTotalTime: 15.0, Weightage: 2.0, InitialRevisionIntervalDays: 2, Difficulty: 4.0, RemainingTime: 15.0, IsStudyCompleted: false},
+    {ID: "PH002", Subject: "Physics", Chapter: "Laws of Motion", InitialTotalTime: 15.0, Weightage: 2.0, InitialRevisionIntervalDays: 2, Difficulty: 4.0, RemainingTime: 15.0, IsStudyCompleted: false, Prerequisites: []string{"PH001"}},
     {ID: "PH003", Subject: "Physics", Chapter: "Motion in a Plane", InitialTotalTime: 12.0, Weightage: 1.8, InitialRevisionIntervalDays: 4, Difficulty: 3.0, RemainingTime: 12.0, IsStudyCompleted: false},
-    {ID: "PH004", Subject: "Physics", Chapter: "Work, Energy and Power", InitialTotalTime: 15.0, Weightage: 2.0, InitialRevisionIntervalDays: 3, Difficulty: 3.5, RemainingTime: 15.0, IsStudyCompleted: false},
-    {ID: "PH005", Subject: "Physics", Chapter: "System of Particles and Rotational Motion", InitialTotalTime: 12.0 ,Weightage: 2.0, InitialRevisionIntervalDays: 3, Difficulty: 4.0, RemainingTime: 12.0, IsStudyCompleted: false},
+    {ID: "PH004", Subject: "Physics", Chapter: "Work, Energy and Power", InitialTotalTime: 15.0, Weightage: 2.0, InitialRevisionIntervalDays: 3, Difficulty: 3.5, RemainingTime: 15.0, IsStudyCompleted: false, Prerequisites: []string{"PH002"}},
+    {ID: "PH005", Subject: "Physics", Chapter: "System of Particles and Rotational Motion", InitialTotalTime: 12.0 ,Weightage: 2.0, InitialRevisionIntervalDays: 3, Difficulty: 4.0, RemainingTime: 12.0, IsStudyCompleted: false, Prerequisites: []string{"PH004"}},
     {ID: "PH006", Subject: "Physics", Chapter: "Gravitation", InitialTotalTime: 10.0, Weightage: 2.0, InitialRevisionIntervalDays: 4, Difficulty: 3.5, RemainingTime: 10.0, IsStudyCompleted: false},
     {ID: "PH007", Subject: "Physics", Chapter: "Mechanical Properties of Solids", InitialTotalTime: 10.0, Weightage: 1.2, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 8.0, IsStudyCompleted: false},
     {ID: "PH008", Subject: "Physics", Chapter: "Mechanical Properties of Fluids", InitialTotalTime: 10.0, Weightage: 1.2, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 8.0, IsStudyCompleted: false},
@@ -160,12 +237,12 @@ func loadConfig() Config {
     {ID: "PH012", Subject: "Physics", Chapter: "Oscillations", InitialTotalTime: 9.0, Weightage: 1.2, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 8.0, IsStudyCompleted: false},
     {ID: "PH013", Subject: "Physics", Chapter: "Waves", InitialTotalTime: 8.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 8.0, IsStudyCompleted: false},
     {ID: "PH014", Subject: "Physics", Chapter: "Electrostatics", InitialTotalTime: 12.0, Weightage: 2.0, InitialRevisionIntervalDays: 3, Difficulty: 4.0, RemainingTime: 12.0, IsStudyCompleted: false},
-    {ID: "PH015", Subject: "Physics", Chapter: "Current Electricity", InitialTotalTime: 12.0, Weightage: 2.0, InitialRevisionIntervalDays: 3, Difficulty: 4.0, RemainingTime: 12.0, IsStudyCompleted: false},
-    {ID: "PH016", Subject: "Physics", Chapter: "Magnetic Effects of Current and Magnetism", InitialTotalTime: 15.0, Weightage: 2.5, InitialRevisionIntervalDays: 2, Difficulty: 4.0, RemainingTime: 15.0, IsStudyCompleted: false},
-    {ID: "PH017", Subject: "Physics", Chapter: "Electromagnetic Induction and Alternating Currents", InitialTotalTime: 18.0, Weightage: 2.5, InitialRevisionIntervalDays: 2, Difficulty: 4.5, RemainingTime: 18.0, IsStudyCompleted: false},
+    {ID: "PH015", Subject: "Physics", Chapter: "Current Electricity", InitialTotalTime: 12.0, Weightage: 2.0, InitialRevisionIntervalDays: 3, Difficulty: 4.0, RemainingTime: 12.0, IsStudyCompleted: false, Prerequisites: []string{"PH014"}},
+    {ID: "PH016", Subject: "Physics", Chapter: "Magnetic Effects of Current and Magnetism", InitialTotalTime: 15.0, Weightage: 2.5, InitialRevisionIntervalDays: 2, Difficulty: 4.0, RemainingTime: 15.0, IsStudyCompleted: false, Prerequisites: []string{"PH015"}},
+    {ID: "PH017", Subject: "Physics", Chapter: "Electromagnetic Induction and Alternating Currents", InitialTotalTime: 18.0, Weightage: 2.5, InitialRevisionIntervalDays: 2, Difficulty: 4.5, RemainingTime: 18.0, IsStudyCompleted: false, Prerequisites: []string{"PH016"}},
     {ID: "PH018", Subject: "Physics", Chapter: "Electromagnetic Waves", InitialTotalTime: 10.0, Weightage: 1.4, InitialRevisionIntervalDays: 4, Difficulty: 3.5, RemainingTime: 10.0, IsStudyCompleted: false},
     {ID: "PH019", Subject: "Physics", Chapter: "Ray Optics", InitialTotalTime: 8.0, Weightage: 1.6, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 8.0, IsStudyCompleted: false},
-    {ID: "PH020", Subject: "Physics", Chapter: "Wave Optics", InitialTotalTime: 8.0, Weightage: 1.2, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 8.0, IsStudyCompleted: false},
+    {ID: "PH020", Subject: "Physics", Chapter: "Wave Optics", InitialTotalTime: 8.0, Weightage: 1.2, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 8.0, IsStudyCompleted: false, Prerequisites: []string{"PH019"}},
     {ID: "PH021", Subject: "Physics", Chapter: "Dual Nature of Matter and Radiation", InitialTotalTime: 10.0, Weightage: 1.4, InitialRevisionIntervalDays: 4, Difficulty: 2.5, RemainingTime: 10.0, IsStudyCompleted: false},
     {ID: "PH022", Subject: "Physics", Chapter: "Atoms and Nuclei", InitialTotalTime: 12.0, Weightage: 2.0, InitialRevisionIntervalDays: 3, Difficulty: 3.5, RemainingTime: 12.0, IsStudyCompleted: false},
     {ID: "PH023", Subject: "Physics", Chapter: "Electronic Devices", InitialTotalTime: 8.0, Weightage: 2.0, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 8.0, IsStudyCompleted: false},
@@ -173,17 +250,17 @@ func loadConfig() Config {
     {ID: "CH001", Subject: "Chemistry", Chapter: "Some Basic Concepts of Chemistry", InitialTotalTime: 6.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 6.0, IsStudyCompleted: false},
     {ID: "CH002", Subject: "Chemistry", Chapter: "Structure of Atom", InitialTotalTime: 6.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 6.0, IsStudyCompleted: false},
     {ID: "CH003", Subject: "Chemistry", Chapter: "Classification of Elements and Periodicity in Properties", InitialTotalTime: 7.0, Weightage: 1.2, InitialRevisionIntervalDays: 4, Difficulty: 3.0, RemainingTime: 7.0, IsStudyCompleted: false},
-    {ID: "CH004", Subject: "Chemistry", Chapter: "Chemical Bonding and Molecular Structure", InitialTotalTime: 11.0, Weightage: 1.8, InitialRevisionIntervalDays: 3, Difficulty: 4.5, RemainingTime: 11.0, IsStudyCompleted: false},
+    {ID: "CH004", Subject: "Chemistry", Chapter: "Chemical Bonding and Molecular Structure", InitialTotalTime: 11.0, Weightage: 1.8, InitialRevisionIntervalDays: 3, Difficulty: 4.5, RemainingTime: 11.0, IsStudyCompleted: false, Prerequisites: []string{"CH002"}},
     {ID: "CH005", Subject: "Chemistry", Chapter: "States of Matter: Gases and Liquids", InitialTotalTime: 6.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 6.0, IsStudyCompleted: false},
     {ID: "CH006", Subject: "Chemistry", Chapter: "Thermodynamics", InitialTotalTime: 8.0, Weightage: 1.4, InitialRevisionIntervalDays: 4, Difficulty: 3.5, RemainingTime: 8.0, IsStudyCompleted: false},
-    {ID: "CH007", Subject: "Chemistry", Chapter: "Equilibrium", InitialTotalTime: 8.0, Weightage: 1.4, InitialRevisionIntervalDays: 4, Difficulty: 3.5, RemainingTime: 8.0, IsStudyCompleted: false},
+    {ID: "CH007", Subject: "Chemistry", Chapter: "Equilibrium", InitialTotalTime: 8.0, Weightage: 1.4, InitialRevisionIntervalDays: 4, Difficulty: 3.5, RemainingTime: 8.0, IsStudyCompleted: false, Prerequisites: []string{"CH006"}},
     {ID: "CH008", Subject: "Chemistry", Chapter: "Redox Reactions", InitialTotalTime: 6.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 3.5, RemainingTime: 6.0, IsStudyCompleted: false},
     {ID: "CH010", Subject: "Chemistry", Chapter: "s-Block Elements (Alkali and Alkaline earth metals)", InitialTotalTime: 7.0, Weightage: 1.2, InitialRevisionIntervalDays: 5, Difficulty: 3.0, RemainingTime: 7.0, IsStudyCompleted: false},
     {ID: "CH011", Subject: "Chemistry", Chapter: "p-Block Elements", InitialTotalTime: 9.0, Weightage: 1.5, InitialRevisionIntervalDays: 3, Difficulty: 3.5, RemainingTime: 9.0, IsStudyCompleted: false},
     {ID: "CH012", Subject: "Chemistry", Chapter: "Organic Chemistry - Some Basic Principles and Techniques", InitialTotalTime: 9.0, Weightage: 1.5, InitialRevisionIntervalDays: 4, Difficulty: 3.0, RemainingTime: 9.0, IsStudyCompleted: false},
-    {ID: "CH013", Subject: "Chemistry", Chapter: "Hydrocarbons", InitialTotalTime: 11.0, Weightage: 1.8, InitialRevisionIntervalDays: 3, Difficulty: 3.5, RemainingTime: 11.0, IsStudyCompleted: false},
-    {ID: "CH014", Subject: "Chemistry", Chapter: "Biomolecules", InitialTotalTime: 9.0, Weightage: 1.5, InitialRevisionIntervalDays: 3, Difficulty: 2.5, RemainingTime: 9.0, IsStudyCompleted: false},
-    {ID: "CH015", Subject: "Chemistry", Chapter: "Polymers", InitialTotalTime: 5.0, Weightage: 0.8, InitialRevisionIntervalDays: 6, Difficulty: 3.0, RemainingTime: 5.0, IsStudyCompleted: false},
+    {ID: "CH013", Subject: "Chemistry", Chapter: "Hydrocarbons", InitialTotalTime: 11.0, Weightage: 1.8, InitialRevisionIntervalDays: 3, Difficulty: 3.5, RemainingTime: 11.0, IsStudyCompleted: false, Prerequisites: []string{"CH012"}},
+    {ID: "CH014", Subject: "Chemistry", Chapter: "Biomolecules", InitialTotalTime: 9.0, Weightage: 1.5, InitialRevisionIntervalDays: 3, Difficulty: 2.5, RemainingTime: 9.0, IsStudyCompleted: false, Prerequisites: []string{"CH012"}},
+    {ID: "CH015", Subject: "Chemistry", Chapter: "Polymers", InitialTotalTime: 5.0, Weightage: 0.8, InitialRevisionIntervalDays: 6, Difficulty: 3.0, RemainingTime: 5.0, IsStudyCompleted: false, Prerequisites: []string{"CH013"}},
     {ID: "CH016", Subject: "Chemistry", Chapter: "Chemistry in Everyday Life", InitialTotalTime: 5.0, Weightage: 0.8, InitialRevisionIntervalDays: 6, Difficulty: 2.5, RemainingTime: 5.0, IsStudyCompleted: false},
 
         {ID: "BI001", Subject: "Biology", Chapter: "The Living World", InitialTotalTime: 6.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.0, RemainingTime: 6.0, IsStudyCompleted: false},
@@ -214,13 +291,13 @@ func loadConfig() Config {
         {ID: "BI025", Subject: "Biology", Chapter: "Human Reproduction", InitialTotalTime: 8.0, Weightage: 1.5, InitialRevisionIntervalDays: 4, Difficulty: 3.0, RemainingTime: 8.0, IsStudyCompleted: false},
         {ID: "BI026", Subject: "Biology", Chapter: "Reproductive Health", InitialTotalTime: 5.0, Weightage: 0.8, InitialRevisionIntervalDays: 6, Difficulty: 2.0, RemainingTime: 5.0, IsStudyCompleted: false},
         {ID: "BI027", Subject: "Biology", Chapter: "Principles of Inheritance and Variation", InitialTotalTime: 8.0, Weightage: 1.5, InitialRevisionIntervalDays: 4, Difficulty: 3.5, RemainingTime: 8.0, IsStudyCompleted: false},
-        {ID: "BI028", Subject: "Biology", Chapter: "Molecular Basis of Inheritance", InitialTotalTime: 9.0, Weightage: 1.8, InitialRevisionIntervalDays: 3, Difficulty: 4.0, RemainingTime: 9.0, IsStudyCompleted: false},
+        {ID: "BI028", Subject: "Biology", Chapter: "Molecular Basis of Inheritance", InitialTotalTime: 9.0, Weightage: 1.8, InitialRevisionIntervalDays: 3, Difficulty: 4.0, RemainingTime: 9.0, IsStudyCompleted: false, Prerequisites: []string{"BI027"}},
         {ID: "BI029", Subject: "Biology", Chapter: "Evolution", InitialTotalTime: 6.5, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 6.5, IsStudyCompleted: false},
         {ID: "BI030", Subject: "Biology", Chapter: "Human Health and Disease", InitialTotalTime: 6.5, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 6.5, IsStudyCompleted: false},
         {ID: "BI031", Subject: "Biology", Chapter: "Strategies for Enhancement in Food Production", InitialTotalTime: 6.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 6.0, IsStudyCompleted: false},
         {ID: "BI032", Subject: "Biology", Chapter: "Microbes in Human Welfare", InitialTotalTime: 5.5, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.0, RemainingTime: 5.5, IsStudyCompleted: false},
         {ID: "BI033", Subject: "Biology", Chapter: "Biotechnology: Principles and Processes", InitialTotalTime: 7.5, Weightage: 1.5, InitialRevisionIntervalDays: 4, Difficulty: 3.5, RemainingTime: 7.5, IsStudyCompleted: false},
-        {ID: "BI034", Subject: "Biology", Chapter: "Biotechnology and Its Applications", InitialTotalTime: 7.0, Weightage: 1.2, InitialRevisionIntervalDays: 4, Difficulty: 3.0, RemainingTime: 7.0, IsStudyCompleted: false},
+        {ID: "BI034", Subject: "Biology", Chapter: "Biotechnology and Its Applications", InitialTotalTime: 7.0, Weightage: 1.2, InitialRevisionIntervalDays: 4, Difficulty: 3.0, RemainingTime: 7.0, IsStudyCompleted: false, Prerequisites: []string{"BI033"}},
         {ID: "BI035", Subject: "Biology", Chapter: "Organisms and Populations", InitialTotalTime: 6.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 6.0, IsStudyCompleted: false},
         {ID: "BI036", Subject: "Biology", Chapter: "Ecosystem", InitialTotalTime: 6.5, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 6.5, IsStudyCompleted: false},
         {ID: "BI037", Subject: "Biology", Chapter: "Biodiversity and Conservation", InitialTotalTime: 6.0, Weightage: 1.0, InitialRevisionIntervalDays: 5, Difficulty: 2.5, RemainingTime: 6.0, IsStudyCompleted: false},
@@ -232,6 +309,8 @@ func loadConfig() Config {
 	}
 	var config Config
 	json.Unmarshal(data, &config)
+	validatePrerequisites(&config)
+	validateSubTopics(&config)
 	return config
 }
 
@@ -241,8 +320,105 @@ type PerformanceState struct {
 	TotalSessions     int     `json:"total_sessions"`
 	CompletedSessions int     `json:"completed_sessions"`
 	MissedSessions    int     `json:"missed_sessions"`
-	AverageFocusScore float64 `json:"average_focus_score"`
+	AverageFocusScore float64 `json:"average_focus_score"` // 0.0 - 1.0
 	ConsistencyFactor float64 `json:"consistency_factor"` // 0.0 - 1.0
+
+	DailyLedger map[string]DayTally `json:"daily_ledger,omitempty"` // keyed by plan date
+
+	FocusSessions int                       `json:"focus_sessions"`
+	DailyFocus    map[string]FocusAggregate `json:"daily_focus,omitempty"`   // keyed by plan date
+	SubjectFocus  map[string]FocusAggregate `json:"subject_focus,omitempty"` // keyed by subject
+}
+
+// DayTally counts the study/revision sessions of one past plan day.
+type DayTally struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Missed    int `json:"missed"`
+}
+
+// recomputeTotals rebuilds the session totals and consistency factor from the
+// ledger, counting only days inside the window before today (0 = all days).
+func (p *PerformanceState) recomputeTotals(today time.Time, windowDays int) {
+	p.TotalSessions, p.CompletedSessions, p.MissedSessions = 0, 0, 0
+	for day, tally := range p.DailyLedger {
+		if windowDays > 0 {
+			date, err := time.Parse(TIME_FORMAT, day)
+			if err != nil || date.Before(today.AddDate(0, 0, -windowDays)) {
+				continue
+			}
+		}
+		p.TotalSessions += tally.Total
+		p.CompletedSessions += tally.Completed
+		p.MissedSessions += tally.Missed
+	}
+	if p.TotalSessions > 0 {
+		p.ConsistencyFactor = float64(p.CompletedSessions) / float64(p.TotalSessions)
+	} else {
+		p.ConsistencyFactor = 1.0
+	}
+}
+
+// FocusMetrics is what the study timer observes during one session.
+type FocusMetrics struct {
+	PauseCount     int     `json:"pause_count"`
+	PausedSeconds  int     `json:"paused_seconds"`
+	MusicToggles   int     `json:"music_toggles"`
+	ElapsedSeconds int     `json:"elapsed_seconds"`
+	PlannedSeconds int     `json:"planned_seconds"`
+	EarlyFinish    bool    `json:"early_finish"`
+	Score          float64 `json:"score"`
+}
+
+type FocusAggregate struct {
+	Sessions   int     `json:"sessions"`
+	TotalScore float64 `json:"total_score"`
+	Average    float64 `json:"average"`
+}
+
+func (a FocusAggregate) add(score float64) FocusAggregate {
+	a.Sessions++
+	a.TotalScore += score
+	a.Average = a.TotalScore / float64(a.Sessions)
+	return a
+}
+
+// computeFocusScore turns timer behaviour into a 0.0 - 1.0 focus score. Each
+// pause costs a little, time spent paused relative to time studied costs more,
+// finishing early costs in proportion to the unstudied part, and fiddling with
+// the music costs a little.
+func computeFocusScore(m FocusMetrics) float64 {
+	score := 1.0
+	score -= math.Min(0.3, 0.05*float64(m.PauseCount))
+	if total := m.ElapsedSeconds + m.PausedSeconds; total > 0 {
+		score -= 0.5 * float64(m.PausedSeconds) / float64(total)
+	}
+	if m.EarlyFinish && m.PlannedSeconds > 0 {
+		score -= 0.3 * (1 - math.Min(1, float64(m.ElapsedSeconds)/float64(m.PlannedSeconds)))
+	}
+	score -= math.Min(0.1, 0.02*float64(m.MusicToggles))
+	return math.Max(0, math.Min(1, score))
+}
+
+// recordFocus folds one session's focus score into the overall, per-day and
+// per-subject averages in the performance state.
+func recordFocus(date time.Time, session Session, m FocusMetrics) {
+	perf := PerformanceState{}
+	_ = loadJSON(PERFORMANCE_FILE, &perf)
+	if perf.DailyFocus == nil {
+		perf.DailyFocus = map[string]FocusAggregate{}
+	}
+	if perf.SubjectFocus == nil {
+		perf.SubjectFocus = map[string]FocusAggregate{}
+	}
+	day := date.Format(TIME_FORMAT)
+	perf.DailyFocus[day] = perf.DailyFocus[day].add(m.Score)
+	perf.SubjectFocus[session.Subject] = perf.SubjectFocus[session.Subject].add(m.Score)
+	perf.AverageFocusScore = (perf.AverageFocusScore*float64(perf.FocusSessions) + m.Score) / float64(perf.FocusSessions+1)
+	perf.FocusSessions++
+	if err := saveJSON(PERFORMANCE_FILE, &perf); err != nil {
+		fmt.Fprintln(logOut, "[WARN] Could not save focus metrics:", err)
+	}
 }
 
 // Helper to save a struct as JSON
@@ -251,7 +427,48 @@ func saveJSON(path string, v interface{}) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return writeFileAtomic(path, data, 0644)
+}
+
+// writeFileAtomic writes data to a temp file in the same directory, fsyncs it
+// and renames it over path, so readers see either the old or the new file and
+// never a half-written one.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once the rename succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	// Persist the rename itself; not every platform can fsync a directory.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
 }
 
 // Helper to load a JSON file into a struct (safe if file missing)
@@ -264,34 +481,119 @@ func loadJSON(path string, v interface{}) error {
 }
 
 func saveConfig(c Config) {
-	data, _ := json.MarshalIndent(c, "", "  ")
-	os.WriteFile(CONFIG_FILE, data, 0644)
+	if err := saveJSON(CONFIG_FILE, c); err != nil {
+		fmt.Fprintf(logOut, ColorRed+"[ERROR] Failed to save %s: %v\n"+ColorReset, CONFIG_FILE, err)
+	}
 }
 func loadState() (ScheduleState, bool) {
 	os.MkdirAll("data", os.ModePerm)
-    data, err := os.ReadFile(STATE_FILE)
-    if err != nil {
-        fmt.Println(ColorYellow + "[INIT] State file not found. Initializing ScheduleState from config." + ColorReset)
-        state := initializeState(loadConfig())
-        saveState(state) // <-- important: save immediately
-        return state, false
-    }
+	data, err := os.ReadFile(STATE_FILE)
+	if os.IsNotExist(err) {
+		fmt.Fprintln(logOut, ColorYellow + "[INIT] State file not found. Initializing ScheduleState from config." + ColorReset)
+		state := initializeState(loadConfig())
+		saveState(state) // <-- important: save immediately
+		return state, false
+	}
+
+	state, err := decodeState(data, err)
+	if err == nil {
+		return state, true
+	}
+
+	fmt.Fprintf(logOut, ColorRed+"[ERROR] State file is unreadable (%v). Attempting recovery from backups."+ColorReset+"\n", err)
+	quarantine := fmt.Sprintf("%s.corrupt-%s", STATE_FILE, time.Now().Format("20060102-150405"))
+	if renameErr := os.Rename(STATE_FILE, quarantine); renameErr == nil {
+		fmt.Fprintf(logOut, "[RECOVERY] Damaged state moved to %s.\n", quarantine)
+	}
+
+	if restored, backupPath, ok := restoreLatestStateBackup(); ok {
+		fmt.Fprintf(logOut, ColorGreen+"[RECOVERY] Restored schedule state from %s."+ColorReset+"\n", backupPath)
+		return restored, true
+	}
+
+	if events, err := loadEventLog(); err == nil && len(events) > 0 {
+		fmt.Fprintf(logOut, ColorYellow+"[RECOVERY] No usable backup found. Rebuilding progress from %d logged events."+ColorReset+"\n", len(events))
+		state = replayEventLog(loadConfig(), events)
+		saveState(state)
+		return state, false
+	}
+
+	fmt.Fprintln(logOut, ColorRed + "[ERROR] No usable backup found. Re-initializing from config." + ColorReset)
+	state = initializeState(loadConfig())
+	saveState(state) // <-- save after fixing corruption
+	return state, false
+}
+
+// decodeState parses a state file, treating a read error or an empty workload
+// as corruption.
+func decodeState(data []byte, readErr error) (ScheduleState, error) {
+	var state ScheduleState
+	if readErr != nil {
+		return state, readErr
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	if len(state.Workload) == 0 {
+		return state, fmt.Errorf("state has no workload")
+	}
+	return state, nil
+}
 
-    var state ScheduleState
-    if err := json.Unmarshal(data, &state); err != nil {
-        fmt.Println(ColorRed + "[ERROR] Failed to unmarshal state file. Re-initializing." + ColorReset)
-        state := initializeState(loadConfig())
-        saveState(state) // <-- save after fixing corruption
-        return state, false
-    }
+// stateBackups returns the backup files, newest first.
+func stateBackups() []string {
+	matches, _ := filepath.Glob(filepath.Join(STATE_BACKUP_DIR, "schedule_state-*.json"))
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	return matches
+}
+
+func restoreLatestStateBackup() (ScheduleState, string, bool) {
+	for _, path := range stateBackups() {
+		data, err := os.ReadFile(path)
+		state, err := decodeState(data, err)
+		if err != nil {
+			fmt.Fprintf(logOut, "[RECOVERY] Skipping unusable backup %s: %v\n", path, err)
+			continue
+		}
+		if err := writeFileAtomic(STATE_FILE, data, 0644); err != nil {
+			fmt.Fprintf(logOut, "[RECOVERY] Could not restore %s: %v\n", path, err)
+			continue
+		}
+		return state, path, true
+	}
+	return ScheduleState{}, "", false
+}
 
-    return state, true
+// backupState copies the current state file into the rolling backup set
+// before it gets replaced, and prunes backups beyond STATE_BACKUP_LIMIT.
+// A state file that no longer parses is never backed up.
+func backupState() error {
+	data, err := os.ReadFile(STATE_FILE)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if _, err := decodeState(data, nil); err != nil {
+		return nil
+	}
+	name := fmt.Sprintf("schedule_state-%s.json", time.Now().Format("20060102-150405.000000"))
+	if err := writeFileAtomic(filepath.Join(STATE_BACKUP_DIR, name), data, 0644); err != nil {
+		return err
+	}
+	if backups := stateBackups(); len(backups) > STATE_BACKUP_LIMIT {
+		for _, old := range backups[STATE_BACKUP_LIMIT:] {
+			os.Remove(old)
+		}
+	}
+	return nil
 }
 
 func initializeState(c Config) ScheduleState {
 	state := ScheduleState{
 		Workload:          make(map[string]ChapterWorkload),
-		LastScheduledDate: time.Now().AddDate(0, 0, -1).Format(TIME_FORMAT),
+		LastScheduledDate: clock.Now().AddDate(0, 0, -1).Format(TIME_FORMAT),
 	}
 	for i, wl := range c.InitialWorkload {
 
@@ -299,15 +601,29 @@ func initializeState(c Config) ScheduleState {
 			wl.ID = fmt.Sprintf("C%03d", i+1)
 		}
 		wl.RemainingTime = wl.InitialTotalTime
+		if len(wl.SubTopics) > 0 {
+			cloneSubTopics(&wl)
+			wl.InitialTotalTime, _ = subTopicTotals(wl)
+			syncRemainingTime(&wl)
+		}
 		wl.Difficulty = c.InitialDifficultyRating
 		state.Workload[wl.ID] = wl
 	}
 	return state
 }
 
-func saveState(s ScheduleState) {
-	data, _ := json.MarshalIndent(s, "", "  ")
-	os.WriteFile(STATE_FILE, data, 0644)
+func saveState(s ScheduleState) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		if backupErr := backupState(); backupErr != nil {
+			fmt.Fprintln(logOut, "[WARN] Could not back up schedule state:", backupErr)
+		}
+		err = writeFileAtomic(STATE_FILE, data, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(logOut, ColorRed+"[ERROR] Failed to save schedule state: %v"+ColorReset+"\n", err)
+	}
+	return err
 }
 
 func deleteScheduleState() {
@@ -321,44 +637,184 @@ func contains(slice []string, item string) bool {
 	}
 	return false
 }
-func dayPlanFilePath(date time.Time) string {
-	return filepath.Join(SCHEDULE_DIR, date.Format(TIME_FORMAT)+".txt")
+// ------------------ Plan Store: versioned day-plan files ------------------
+
+const (
+	PLAN_FILE_HEADER    = "# ADAPTIVE NEET SCHEDULER DAY PLAN"
+	PLAN_SCHEMA_VERSION = 2
+)
+
+// Plan file formats understood by PlanStore. Only planFormatV2 is ever written;
+// the legacy formats are read once and migrated on the spot.
+const (
+	planFormatUnknown    = iota
+	planFormatLegacyBlock // "SESSION n:" blocks without a header (old writeDayPlan)
+	planFormatLegacyPipe  // "Subject | Chapter | Duration | Type | Status" (old saveDayPlan)
+	planFormatV2
+)
+
+// PlanStore owns every read and write of the plans/ directory so that all
+// callers share one on-disk format and session IDs survive every rewrite.
+type PlanStore struct {
+	Dir string
 }
 
-func writeDayPlan(date time.Time, sessions []Session) {
-	if err := os.MkdirAll(SCHEDULE_DIR, os.ModePerm); err != nil {
-		fmt.Printf(ColorRed+"[CRITICAL ERROR] Failed to create directory '%s': %v\n"+ColorReset, SCHEDULE_DIR, err)
-		return
+var planStore = NewPlanStore(SCHEDULE_DIR)
+
+func NewPlanStore(dir string) *PlanStore {
+	return &PlanStore{Dir: dir}
+}
+
+func (ps *PlanStore) Path(date time.Time) string {
+	return filepath.Join(ps.Dir, date.Format(TIME_FORMAT)+".txt")
+}
+
+// Dates lists every date that has a plan file, oldest first.
+func (ps *PlanStore) Dates() ([]time.Time, error) {
+	files, err := os.ReadDir(ps.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var dates []time.Time
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".txt") {
+			continue
+		}
+		date, err := time.Parse(TIME_FORMAT, strings.TrimSuffix(f.Name(), ".txt"))
+		if err != nil {
+			continue
+		}
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates, nil
+}
+
+func (ps *PlanStore) Save(date time.Time, sessions []Session) error {
+	if err := os.MkdirAll(ps.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", ps.Dir, err)
 	}
-	filepath := dayPlanFilePath(date)
+	return writeFileAtomic(ps.Path(date), []byte(encodePlan(date, sessions)), 0644)
+}
+
+// Load reads a plan file in any known format. Legacy files are rewritten in
+// the current format before returning, so each file is migrated at most once.
+func (ps *PlanStore) Load(date time.Time) ([]Session, error) {
+	data, err := os.ReadFile(ps.Path(date))
+	if err != nil {
+		return nil, fmt.Errorf("could not read plan file for %s: %w", date.Format(TIME_FORMAT), err)
+	}
+	sessions, format, err := decodePlan(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse plan file for %s: %w", date.Format(TIME_FORMAT), err)
+	}
+	if format != planFormatV2 {
+		if err := ps.Save(date, sessions); err != nil {
+			return sessions, fmt.Errorf("could not migrate plan file for %s: %w", date.Format(TIME_FORMAT), err)
+		}
+	}
+	return sessions, nil
+}
+
+// MigrateAll upgrades every legacy plan file in the store and returns how many
+// files were rewritten.
+func (ps *PlanStore) MigrateAll() (int, error) {
+	dates, err := ps.Dates()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	migrated := 0
+	for _, date := range dates {
+		data, err := os.ReadFile(ps.Path(date))
+		if err != nil {
+			continue
+		}
+		if detectPlanFormat(string(data)) == planFormatV2 {
+			continue
+		}
+		if _, err := ps.Load(date); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+func encodePlan(date time.Time, sessions []Session) string {
 	var sb strings.Builder
+	sb.WriteString(PLAN_FILE_HEADER + "\n")
+	sb.WriteString(fmt.Sprintf("VERSION: %d\n", PLAN_SCHEMA_VERSION))
 	sb.WriteString(fmt.Sprintf("DATE: %s (%s)\n\n", date.Format(TIME_FORMAT), date.Weekday()))
 	for i, session := range sessions {
 		header := fmt.Sprintf("SESSION %d:", i+1)
-		if session.Type == "Buffer" || session.Type == "Rest" {
+		if session.Type == "Buffer" || session.Type == "Rest" || session.Type == "Break" {
 			header = strings.ToUpper(session.Type) + ":"
 		}
 		sb.WriteString(fmt.Sprintf("%s\n", header))
 		sb.WriteString(fmt.Sprintf("  Subject:  %s\n", session.Subject))
 		sb.WriteString(fmt.Sprintf("  Chapter:  %s\n", session.Chapter))
-		sb.WriteString(fmt.Sprintf("  Duration: %.1f hrs\n", session.Duration))
+		sb.WriteString(fmt.Sprintf("  Duration: %.2f hrs\n", session.Duration))
 		sb.WriteString(fmt.Sprintf("  Status:   %s\n", session.Status))
 		sb.WriteString(fmt.Sprintf("  Type:     %s\n", session.Type))
 		if session.ChapterID != "" {
 			sb.WriteString(fmt.Sprintf("  ID:       %s\n", session.ChapterID))
 		}
+		if len(session.Topics) > 0 {
+			sb.WriteString(fmt.Sprintf("  Topics:   %s\n", strings.Join(session.Topics, "; ")))
+		}
+		if session.Activity != "" {
+			sb.WriteString(fmt.Sprintf("  Activity: %s\n", session.Activity))
+		}
+		if session.Start != "" {
+			sb.WriteString(fmt.Sprintf("  Time:     %s-%s\n", session.Start, session.End))
+		}
 		sb.WriteString("\n")
 	}
-	os.WriteFile(filepath, []byte(sb.String()), 0644)
+	return sb.String()
 }
 
-func readDayPlan(date time.Time) ([]Session, error) {
-	filepath := dayPlanFilePath(date)
-	data, err := os.ReadFile(filepath)
-	if err != nil {
-		return nil, fmt.Errorf("could not read plan file for %s: %w", date.Format(TIME_FORMAT), err)
+func detectPlanFormat(content string) int {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, PLAN_FILE_HEADER) {
+		return planFormatV2
+	}
+	if trimmed == "" || strings.HasPrefix(trimmed, "DATE:") || strings.Contains(trimmed, "\n  Subject:") {
+		return planFormatLegacyBlock
+	}
+	firstLine := strings.SplitN(trimmed, "\n", 2)[0]
+	if strings.Count(firstLine, "|") >= 4 {
+		return planFormatLegacyPipe
 	}
-	content := string(data)
+	return planFormatUnknown
+}
+
+func decodePlan(content string) ([]Session, int, error) {
+	format := detectPlanFormat(content)
+	switch format {
+	case planFormatV2:
+		header, body, _ := strings.Cut(content, "\n\n")
+		version := 0
+		for _, line := range strings.Split(header, "\n") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(line), "VERSION:"); ok {
+				version, _ = strconv.Atoi(strings.TrimSpace(v))
+			}
+		}
+		if version > PLAN_SCHEMA_VERSION {
+			return nil, format, fmt.Errorf("plan schema version %d is newer than supported version %d", version, PLAN_SCHEMA_VERSION)
+		}
+		return decodeBlockPlan(body), format, nil
+	case planFormatLegacyBlock:
+		return decodeBlockPlan(content), format, nil
+	case planFormatLegacyPipe:
+		return decodePipePlan(content), format, nil
+	}
+	return nil, format, fmt.Errorf("unrecognised plan file format")
+}
+
+func decodeBlockPlan(content string) []Session {
 	sessions := []Session{}
 	blocks := strings.Split(content, "\n\n")
 	for _, block := range blocks {
@@ -394,1280 +850,6509 @@ func readDayPlan(date time.Time) ([]Session, error) {
 				session.Type = value
 			case "ID":
 				session.ChapterID = value
+			case "Topics":
+				session.Topics = strings.Split(value, "; ")
+			case "Activity":
+				session.Activity = value
+			case "Time":
+				session.Start, session.End, _ = strings.Cut(value, "-")
 			}
 		}
 		if (session.Subject != "" && session.Duration > 0) || session.Type == "Rest" || session.Type == "Buffer" {
 			sessions = append(sessions, session)
 		}
 	}
-	return sessions, nil
+	return sessions
 }
 
-func loadProgress(today time.Time) (Progress, bool) {
-	data, err := os.ReadFile(PROGRESS_FILE)
-	if err != nil {
-		return Progress{}, false
-	}
-	var p Progress
-	if err := json.Unmarshal(data, &p); err != nil {
-		deleteProgress()
-		return Progress{}, false
+// decodePipePlan reads the old pipe-delimited format. That format never stored
+// the chapter ID, so it is recovered from the configured syllabus by subject
+// and chapter name.
+func decodePipePlan(content string) []Session {
+	sessions := []Session{}
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		parts := strings.Split(line, "|")
+		if len(parts) < 5 {
+			continue
+		}
+		duration, _ := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		session := Session{
+			Subject:  strings.TrimSpace(parts[0]),
+			Chapter:  strings.TrimSpace(parts[1]),
+			Duration: duration,
+			Type:     strings.TrimSpace(parts[3]),
+			Status:   strings.TrimSpace(parts[4]),
+		}
+		if session.Type == "Study" || session.Type == "Revision" {
+			session.ChapterID = lookupChapterID(session.Subject, session.Chapter)
+		}
+		sessions = append(sessions, session)
 	}
+	return sessions
+}
 
-	if p.Date != today.Format(TIME_FORMAT) {
-		deleteProgress()
-		return Progress{}, false
+func lookupChapterID(subject, chapter string) string {
+	if idx := strings.Index(chapter, " (Revision #"); idx != -1 {
+		chapter = chapter[:idx]
 	}
-	return p, true
+	for _, wl := range rawConfig.InitialWorkload {
+		if wl.Subject == subject && wl.Chapter == chapter {
+			return wl.ID
+		}
+	}
+	return ""
 }
 
-func saveProgress(chapterID string, elapsed int) {
-	p := Progress{
-		ChapterID:      chapterID,
-		ElapsedSeconds: elapsed,
-		Date:           time.Now().Truncate(24 * time.Hour).Format(TIME_FORMAT),
+func writeDayPlan(date time.Time, sessions []Session) {
+	if err := planStore.Save(date, sessions); err != nil {
+		fmt.Fprintf(logOut, ColorRed+"[CRITICAL ERROR] Failed to write plan for %s: %v\n"+ColorReset, date.Format(TIME_FORMAT), err)
 	}
-	data, _ := json.MarshalIndent(p, "", "  ")
-
-	os.WriteFile(PROGRESS_FILE, data, 0644) 
 }
 
-func deleteProgress() {
-	os.Remove(PROGRESS_FILE)
+func readDayPlan(date time.Time) ([]Session, error) {
+	return planStore.Load(date)
 }
 
-func updateChapterPerformance(wl ChapterWorkload, success bool) ChapterWorkload {
-	rate := rawConfig.DifficultyAdjustmentRate
-	if success {
+// ------------------ Plan Changes ------------------
 
-		wl.Difficulty = math.Max(1.0, wl.Difficulty-rate)
-	} else {
+// PLAN_CHANGES_FILE keeps one JSON line per regeneration listing what changed.
+const PLAN_CHANGES_FILE = "data/plan_changes.jsonl"
 
-		wl.Difficulty = math.Min(5.0, wl.Difficulty+rate*2.0)
-	}
-	return wl
+// DayPlanDiff is what a regeneration changed on one day. Sessions are matched
+// on everything the plan file stores, so a session whose length changed shows
+// up as one removed and one added.
+type DayPlanDiff struct {
+	Date        string    `json:"date"`
+	Added       []Session `json:"added,omitempty"`
+	Removed     []Session `json:"removed,omitempty"`
+	HoursBefore float64   `json:"hours_before"` // study + revision hours
+	HoursAfter  float64   `json:"hours_after"`
 }
 
-func calculateWeightedTime(wl ChapterWorkload) float64 {
+type PlanChangeRecord struct {
+	Timestamp string        `json:"timestamp"` // RFC 3339
+	Days      []DayPlanDiff `json:"days"`
+}
 
-	return wl.RemainingTime * (1 + wl.Difficulty/5.0) * (wl.Weightage * 2.0)
+func (d DayPlanDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
 }
 
-func calculateQuotas(state *ScheduleState) []ChapterWorkload {
-	today := time.Now().Truncate(24 * time.Hour)
-	syllabusEndDate, _ := time.Parse(TIME_FORMAT, rawConfig.SyllabusEndDate)
-	totalWorkload := 0.0
-	totalRemainingTime := 0.0
-	netStudyDays := 0
-	var allChapters []ChapterWorkload
-	for id, wl := range state.Workload {
-		if !wl.IsStudyCompleted && wl.RemainingTime > 0.001 {
-			wl.PriorityScore = calculateWeightedTime(wl)
-			totalWorkload += wl.PriorityScore
-			totalRemainingTime += wl.RemainingTime
-		} else if wl.RevisionCount < MAX_REVISIONS && wl.NextRevisionDate != "" {
-			revDate, _ := time.Parse(TIME_FORMAT, wl.NextRevisionDate)
-			daysUntilDue := revDate.Sub(today).Hours() / 24.0
+func sessionKey(s Session) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%.2f|%s|%s|%s", s.Type, s.ChapterID, s.Subject, s.Chapter, s.Status, s.Duration, strings.Join(s.Topics, ";"), s.Activity, sessionTime(s))
+}
 
-			wl.PriorityScore = (wl.Difficulty / 5.0) * wl.Weightage * (10.0 / math.Max(1.0, daysUntilDue))
-			totalWorkload += wl.PriorityScore * REVISION_TIME_HRS
-		} else {
-			wl.PriorityScore = 0.0
+func studyHours(sessions []Session) float64 {
+	total := 0.0
+	for _, s := range sessions {
+		if s.Type == "Study" || s.Type == "Revision" {
+			total += s.Duration
 		}
-		state.Workload[id] = wl
-		allChapters = append(allChapters, wl)
 	}
-	currentDate := today
-	if state.LastScheduledDate != "" {
-		stateDate, _ := time.Parse(TIME_FORMAT, state.LastScheduledDate)
-		if stateDate.After(today) {
-			currentDate = stateDate
-		}
+	return total
+}
+
+func diffDayPlan(date time.Time, before, after []Session) DayPlanDiff {
+	diff := DayPlanDiff{
+		Date:        date.Format(TIME_FORMAT),
+		HoursBefore: studyHours(before),
+		HoursAfter:  studyHours(after),
 	}
-	for currentDate.Before(syllabusEndDate.AddDate(0, 0, 1)) {
-		if currentDate.Weekday() != rawConfig.WeeklyRestDay {
-			netStudyDays++
+	unmatched := map[string]int{}
+	for _, s := range before {
+		unmatched[sessionKey(s)]++
+	}
+	for _, s := range after {
+		if key := sessionKey(s); unmatched[key] > 0 {
+			unmatched[key]--
+		} else {
+			diff.Added = append(diff.Added, s)
 		}
-		currentDate = currentDate.AddDate(0, 0, 1)
 	}
-	dailyQuotaWT := 0.0
-	if netStudyDays > 0 {
-		dailyQuotaWT = totalWorkload / float64(netStudyDays)
+	for _, s := range before {
+		if key := sessionKey(s); unmatched[key] > 0 {
+			unmatched[key]--
+			diff.Removed = append(diff.Removed, s)
+		}
 	}
-	state.TotalWeightedWorkload = totalWorkload
-	state.TotalRemainingTime = totalRemainingTime
-	state.NetStudyDays = netStudyDays
-	state.DailyQuotaWT = dailyQuotaWT
-	return allChapters
+	return diff
 }
 
-func prioritizeChapters(allChapters []ChapterWorkload) []ChapterWorkload {
-	var activeStudyChapters []ChapterWorkload
-	for _, wl := range allChapters {
-		if !wl.IsStudyCompleted && wl.RemainingTime > 0.001 {
-			activeStudyChapters = append(activeStudyChapters, wl)
+// recordPlanChanges appends a regeneration's changes to PLAN_CHANGES_FILE and
+// prints a one-line summary.
+func recordPlanChanges(changes []DayPlanDiff) {
+	if len(changes) == 0 {
+		fmt.Fprintln(logOut, "[PLAN] No plan files changed.")
+		return
+	}
+	added, removed, delta := 0, 0, 0.0
+	for _, c := range changes {
+		added += len(c.Added)
+		removed += len(c.Removed)
+		delta += c.HoursAfter - c.HoursBefore
+	}
+	fmt.Fprintf(logOut, "[PLAN] %d days changed (+%d / -%d sessions, %+.2f study hrs). Details in %s\n",
+		len(changes), added, removed, delta, PLAN_CHANGES_FILE)
+
+	line, err := json.Marshal(PlanChangeRecord{Timestamp: clock.Now().Format(time.RFC3339), Days: changes})
+	if err == nil {
+		err = appendLine(PLAN_CHANGES_FILE, line)
+	}
+	if err != nil {
+		fmt.Fprintln(logOut, "[WARN] Could not record plan changes:", err)
+	}
+}
+
+// PREVIEW_DAY_LIMIT caps how many changed days a preview lists in full.
+const PREVIEW_DAY_LIMIT = 14
+
+// RevisionChange is a chapter whose next revision date a re-balance moves.
+type RevisionChange struct {
+	ChapterID string `json:"chapter_id"`
+	Subject   string `json:"subject"`
+	Chapter   string `json:"chapter"`
+	Before    string `json:"before"`
+	After     string `json:"after"`
+}
+
+func revisionChanges(before, after ScheduleState) []RevisionChange {
+	var changes []RevisionChange
+	for _, id := range sortedChapterIDs(after.Workload) {
+		wl := after.Workload[id]
+		if old, ok := before.Workload[id]; ok && old.NextRevisionDate != wl.NextRevisionDate {
+			changes = append(changes, RevisionChange{ChapterID: id, Subject: wl.Subject, Chapter: wl.Chapter, Before: old.NextRevisionDate, After: wl.NextRevisionDate})
 		}
 	}
+	return changes
+}
 
-	sort.Slice(activeStudyChapters, func(i, j int) bool {
-		return activeStudyChapters[i].PriorityScore > activeStudyChapters[j].PriorityScore
-	})
-	return activeStudyChapters
+// sessionMoves pairs sessions removed on one day with the same chapter's
+// session of the same type added on another, so a preview can say "moved"
+// instead of listing an unrelated removal and addition. The maps are keyed by
+// day index and session index within Removed / Added.
+func sessionMoves(changes []DayPlanDiff) (movedTo, movedFrom map[[2]int]string) {
+	movedTo, movedFrom = map[[2]int]string{}, map[[2]int]string{}
+	for d, day := range changes {
+		for i, removed := range day.Removed {
+			if removed.ChapterID == "" || removed.Status != "Pending" {
+				continue
+			}
+		search:
+			for d2, other := range changes {
+				if d2 == d {
+					continue
+				}
+				for j, added := range other.Added {
+					key := [2]int{d2, j}
+					if _, taken := movedFrom[key]; !taken && added.Status == "Pending" && added.ChapterID == removed.ChapterID && added.Type == removed.Type {
+						movedTo[[2]int{d, i}] = other.Date
+						movedFrom[key] = day.Date
+						break search
+					}
+				}
+			}
+		}
+	}
+	return movedTo, movedFrom
 }
 
-func getDueRevisions(state ScheduleState, today time.Time) []ChapterWorkload {
-	var dueRevisions []ChapterWorkload
-	for _, wl := range state.Workload {
-		if wl.IsStudyCompleted && wl.RevisionCount < MAX_REVISIONS && wl.NextRevisionDate != "" {
-			revDate, err := time.Parse(TIME_FORMAT, wl.NextRevisionDate)
-			if err == nil && !revDate.After(today) {
-				dueRevisions = append(dueRevisions, wl)
+// printPlanPreview shows a per-day diff of a planned change against plans/.
+func printPlanPreview(changes []DayPlanDiff, revisions []RevisionChange) {
+	added, removed, delta := 0, 0, 0.0
+	for _, c := range changes {
+		added += len(c.Added)
+		removed += len(c.Removed)
+		delta += c.HoursAfter - c.HoursBefore
+	}
+	fmt.Printf("\n--- Preview: %d days change (+%d / -%d sessions, %+.2f study hrs) ---\n", len(changes), added, removed, delta)
+	movedTo, movedFrom := sessionMoves(changes)
+	line := func(mark, color string, s Session, note string) {
+		status := ""
+		if s.Status != "Pending" {
+			status = ", " + s.Status
+		}
+		fmt.Printf("  %s%s %-8s %s%s: %s (%.2fh%s)%s%s\n", color, mark, s.Type, sessionTime(s), s.Subject, sessionTitle(s), s.Duration, status, note, ColorReset)
+	}
+	for d, c := range changes {
+		if d == PREVIEW_DAY_LIMIT {
+			fmt.Printf("... and %d more days.\n", len(changes)-d)
+			break
+		}
+		date, _ := time.Parse(TIME_FORMAT, c.Date)
+		fmt.Printf("%s (%s)  %.2f -> %.2f hrs (%+.2f)\n", c.Date, date.Weekday(), c.HoursBefore, c.HoursAfter, c.HoursAfter-c.HoursBefore)
+		for i, s := range c.Removed {
+			note := ""
+			if to, ok := movedTo[[2]int{d, i}]; ok {
+				note = "  [moved to " + to + "]"
+			}
+			line("-", ColorRed, s, note)
+		}
+		for i, s := range c.Added {
+			note := ""
+			if from, ok := movedFrom[[2]int{d, i}]; ok {
+				note = "  [moved from " + from + "]"
 			}
+			line("+", ColorGreen, s, note)
 		}
 	}
-	return dueRevisions
+	if len(revisions) > 0 {
+		fmt.Println("Revision dates:")
+		for _, r := range revisions {
+			before := r.Before
+			if before == "" {
+				before = "none"
+			}
+			fmt.Printf("  %s: %s  %s -> %s\n", r.Subject, r.Chapter, before, r.After)
+		}
+	}
+}
+
+// Preview prints what Commit would change.
+func (r *Rebalance) Preview() {
+	var revisions []RevisionChange
+	if r.Plan != nil {
+		current, _ := loadState()
+		revisions = revisionChanges(current, r.Plan.State)
+	}
+	printPlanPreview(r.Changes(), revisions)
 }
 
-// Safely parse a float value from string
-func parseFloatSafe(s string) float64 {
-	v, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
-	return v
+// confirm asks a yes/no question on the given reader; anything but y is no.
+func confirm(reader *bufio.Reader, question string) bool {
+	fmt.Print(question + " (y/N): ")
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(input)) == "y"
 }
 
-// Rewrites a day plan file with updated sessions
-func saveDayPlan(date time.Time, sessions []Session) {
-	fileName := filepath.Join(SCHEDULE_DIR, date.Format(TIME_FORMAT)+".txt")
-	f, err := os.Create(fileName)
-	if err != nil {
-		fmt.Println("[WARN] Could not update day plan:", err)
+// previewAndGenerate regenerates the schedule only after showing the per-day
+// diff and getting confirmation.
+func previewAndGenerate(reader *bufio.Reader) {
+	state, _ := loadState()
+	plan := planSchedule(state)
+	if plan == nil {
 		return
 	}
-	defer f.Close()
-
-	for _, s := range sessions {
-		fmt.Fprintf(f, "%s | %s | %.2f | %s | %s\n",
-			s.Subject, s.Chapter, s.Duration, s.Type, s.Status)
+	printPlanPreview(plan.Changes, nil)
+	if len(plan.Changes) == 0 {
+		fmt.Println("[INFO] The schedule is already up to date.")
+		return
+	}
+	if !confirm(reader, "\n> Apply these changes?") {
+		fmt.Println("[INFO] Nothing changed.")
+		return
 	}
+	markMissedSessions()
+	commitSchedule(plan)
 }
 
-func markMissedSessions() {
-	files, err := os.ReadDir(SCHEDULE_DIR)
+func loadProgress(today time.Time) (Progress, bool) {
+	data, err := os.ReadFile(PROGRESS_FILE)
 	if err != nil {
-		return
+		return Progress{}, false
+	}
+	var p Progress
+	if err := json.Unmarshal(data, &p); err != nil {
+		deleteProgress()
+		return Progress{}, false
 	}
 
-	today := time.Now().Truncate(24 * time.Hour)
-
-	for _, f := range files {
-		if !strings.HasSuffix(f.Name(), ".txt") {
-			continue
-		}
-		dateStr := strings.TrimSuffix(f.Name(), ".txt")
-		planDate, err := time.Parse(TIME_FORMAT, dateStr)
-		if err != nil || !planDate.Before(today) {
-			continue
-		}
+	if p.Date != today.Format(TIME_FORMAT) {
+		deleteProgress()
+		return Progress{}, false
+	}
+	return p, true
+}
 
-		path := filepath.Join(SCHEDULE_DIR, f.Name())
-		data, err := os.ReadFile(path)
-		if err != nil {
-			continue
-		}
+func saveProgress(chapterID string, elapsed int) {
+	p := Progress{
+		ChapterID:      chapterID,
+		ElapsedSeconds: elapsed,
+		Date:           currentDay().Format(TIME_FORMAT),
+	}
+	if err := saveJSON(PROGRESS_FILE, p); err != nil {
+		fmt.Fprintln(logOut, "\n[WARN] Could not save session progress:", err)
+	}
+}
 
-		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
-		newSessions := []Session{}
+func deleteProgress() {
+	os.Remove(PROGRESS_FILE)
+}
 
-		for _, line := range lines {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			parts := strings.Split(line, "|")
-			if len(parts) < 5 {
-				continue
-			}
+// ------------------ Event Journal ------------------
 
-			status := strings.TrimSpace(parts[4])
-			if status == "Pending" {
-				status = "Missed"
-			}
+// Session event kinds. complete, early_finish, revision, reschedule and mock
+// change a chapter's workload and are what replayEventLog applies; the rest
+// only record how the session went.
+const (
+	EventStart       = "start"
+	EventPause       = "pause"
+	EventResume      = "resume"
+	EventComplete    = "complete"
+	EventEarlyFinish = "early_finish"
+	EventMiss        = "miss"
+	EventRevision    = "revision"
+	EventReschedule  = "reschedule"
+	EventMock        = "mock"  // one chapter's score in a mock test
+	EventTopic       = "topic" // a sub-topic marked done by hand
+)
 
-			newSessions = append(newSessions, Session{
-				Subject:  strings.TrimSpace(parts[0]),
-				Chapter:  strings.TrimSpace(parts[1]),
-				Duration: parseFloatSafe(parts[2]),
-				Type:     strings.TrimSpace(parts[3]),
-				Status:   status,
-			})
-		}
+// SessionEvent is one line of the append-only journal in EVENT_LOG_FILE.
+type SessionEvent struct {
+	Timestamp      string            `json:"timestamp"`
+	Kind           string            `json:"kind"`
+	PlanDate       string            `json:"plan_date"`
+	ChapterID      string            `json:"chapter_id,omitempty"`
+	Subject        string            `json:"subject"`
+	Chapter        string            `json:"chapter"`
+	SessionType    string            `json:"session_type"`
+	PlannedHours   float64           `json:"planned_hours"`
+	ElapsedSeconds int               `json:"elapsed_seconds"`
+	RecallGrade    *int              `json:"recall_grade,omitempty"` // revisions only
+	Rating         *SessionRating    `json:"rating,omitempty"`
+	Focus          *FocusMetrics     `json:"focus,omitempty"`
+	Mock           *MockChapterScore `json:"mock,omitempty"`
+	Topics         []string          `json:"topics,omitempty"` // sub-topics the session covered
+	Activity       string            `json:"activity,omitempty"`
+	Topic          string            `json:"topic,omitempty"` // sub-topic marked done (topic events)
+}
 
-		saveDayPlan(planDate, newSessions)
+// logSessionEvent appends one event to the journal. Failures are reported but
+// never interrupt a running session.
+func logSessionEvent(kind string, planDate time.Time, s Session, elapsedSeconds int) {
+	if err := appendEvent(newSessionEvent(kind, planDate, s, elapsedSeconds)); err != nil {
+		fmt.Fprintln(logOut, "\n[WARN] Could not write to event log:", err)
 	}
-	fmt.Println("[INFO] Marked past pending sessions as Missed.")
 }
 
+func newSessionEvent(kind string, planDate time.Time, s Session, elapsedSeconds int) SessionEvent {
+	return SessionEvent{
+		Timestamp:      clock.Now().Format(time.RFC3339),
+		Kind:           kind,
+		PlanDate:       planDate.Format(TIME_FORMAT),
+		ChapterID:      s.ChapterID,
+		Subject:        s.Subject,
+		Chapter:        s.Chapter,
+		SessionType:    s.Type,
+		PlannedHours:   s.Duration,
+		ElapsedSeconds: elapsedSeconds,
+		Topics:         s.Topics,
+		Activity:       s.Activity,
+	}
+}
 
-// updatePerformance scans past schedule files and updates the performance_state.json
-func updatePerformance() {
-	perfPath := "performance_state.json"
-	perf := PerformanceState{}
-	_ = loadJSON(perfPath, &perf)
-
-	files, err := os.ReadDir(SCHEDULE_DIR)
+func appendEvent(event SessionEvent) error {
+	line, err := json.Marshal(event)
 	if err != nil {
-		fmt.Println("[WARN] Could not read schedule directory for performance update:", err)
-		return
+		return err
 	}
+	return appendLine(EVENT_LOG_FILE, line)
+}
 
-	today := time.Now().Truncate(24 * time.Hour)
-	totalObserved, completedObserved, missedObserved := 0, 0, 0
+// appendLine adds one line to a journal file and syncs it, so a crash can at
+// worst tear the last line.
+func appendLine(path string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
 
-	for _, f := range files {
-		if !strings.HasSuffix(f.Name(), ".txt") {
+// loadEventLog reads the whole journal. A torn last line from a crash is
+// skipped rather than failing the read.
+func loadEventLog() ([]SessionEvent, error) {
+	data, err := os.ReadFile(EVENT_LOG_FILE)
+	if err != nil {
+		return nil, err
+	}
+	var events []SessionEvent
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
 			continue
 		}
-		dateStr := strings.TrimSuffix(f.Name(), ".txt")
-		planDate, err := time.Parse(TIME_FORMAT, dateStr)
-		if err != nil || !planDate.Before(today) {
+		var event SessionEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
 			continue
 		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
 
-		sessions, err := readDayPlan(planDate)
+// replayEventLog rebuilds the per-chapter progress of a ScheduleState by
+// applying every workload-changing event to a fresh state from config. The
+// planning cursor is left at its initial value, so a schedule has to be
+// generated afterwards.
+func replayEventLog(c Config, events []SessionEvent) ScheduleState {
+	state := initializeState(c)
+	for _, event := range events {
+		workload, ok := state.Workload[event.ChapterID]
+		if !ok {
+			continue
+		}
+		planDate, err := time.Parse(TIME_FORMAT, event.PlanDate)
 		if err != nil {
 			continue
 		}
-
-		for _, s := range sessions {
-			if s.Type != "Study" && s.Type != "Revision" {
+		session := Session{
+			Subject:   event.Subject,
+			Chapter:   event.Chapter,
+			Duration:  event.PlannedHours,
+			ChapterID: event.ChapterID,
+			Type:      event.SessionType,
+			Topics:    event.Topics,
+			Activity:  event.Activity,
+		}
+		switch event.Kind {
+		case EventComplete, EventEarlyFinish, EventRevision:
+			grade := RECALL_GRADE_DEFAULT
+			if event.RecallGrade != nil {
+				grade = *event.RecallGrade
+			}
+			workload = applyCompletedSession(workload, session, event.ElapsedSeconds, planDate, grade, event.Rating)
+		case EventReschedule:
+			workload = applyMissedSession(workload, session, planDate)
+		case EventMock:
+			if event.Mock == nil {
 				continue
 			}
-			totalObserved++
-			switch s.Status {
-			case "Completed":
-				completedObserved++
-			case "Missed":
-				missedObserved++
+			workload = applyMockScore(workload, *event.Mock, planDate)
+		case EventTopic:
+			if workload, err = completeSubTopic(workload, event.Topic, planDate); err != nil {
+				continue
 			}
+		default:
+			continue
 		}
+		state.Workload[event.ChapterID] = workload
 	}
+	return state
+}
+
+// ------------------ Spaced Repetition Models ------------------
+
+const (
+	RevisionModelFixed = "fixed"
+	RevisionModelSM2   = "sm2"
+	RevisionModelFSRS  = "fsrs"
+
+	SM2_DEFAULT_EASE     = 2.5
+	SM2_MIN_EASE         = 1.3
+	FSRS_TARGET_RECALL   = 0.9
+	FSRS_MIN_STABILITY   = 1.0
+	RECALL_GRADE_DEFAULT = 4
+)
+
+// RevisionModel decides when a chapter is revised next. Start is called once
+// initial study of a chapter is finished; Review after every revision with the
+// recall grade (0 = blackout .. 5 = perfect). Both return the number of days
+// until the next revision, or 0 when the chapter needs no further revision.
+type RevisionModel interface {
+	Name() string
+	Start(wl *ChapterWorkload, today time.Time) int
+	Review(wl *ChapterWorkload, grade int, today time.Time) int
+	// MaxRevisions caps RevisionCount; 0 means the model has no cap.
+	MaxRevisions() int
+}
 
-	if totalObserved > 0 {
-		perf.TotalSessions += totalObserved
-		perf.CompletedSessions += completedObserved
-		perf.MissedSessions += missedObserved
-		perf.ConsistencyFactor = float64(perf.CompletedSessions) / float64(perf.TotalSessions)
-	} else if perf.TotalSessions == 0 {
-		perf.ConsistencyFactor = 1.0
+func revisionModelFor(c Config) RevisionModel {
+	switch strings.ToLower(c.RevisionModel) {
+	case RevisionModelSM2:
+		return sm2Model{}
+	case RevisionModelFSRS:
+		return fsrsModel{}
 	}
+	return fixedIntervalModel{}
+}
 
-	if err := saveJSON(perfPath, &perf); err != nil {
-		fmt.Println("[WARN] Could not save performance state:", err)
-	} else {
-		fmt.Printf("[STATS] Performance updated. Consistency: %.1f%% (%d/%d done)\n",
-			perf.ConsistencyFactor*100.0, perf.CompletedSessions, perf.TotalSessions)
+// fixedIntervalModel is the original schedule: InitialRevisionIntervalDays
+// multiplied by the revision number, for at most MAX_REVISIONS revisions.
+type fixedIntervalModel struct{}
+
+func (fixedIntervalModel) Name() string      { return RevisionModelFixed }
+func (fixedIntervalModel) MaxRevisions() int { return MAX_REVISIONS }
+
+func (fixedIntervalModel) Start(wl *ChapterWorkload, today time.Time) int {
+	return wl.InitialRevisionIntervalDays
+}
+
+func (fixedIntervalModel) Review(wl *ChapterWorkload, grade int, today time.Time) int {
+	if wl.RevisionCount >= MAX_REVISIONS {
+		return 0
 	}
+	return wl.InitialRevisionIntervalDays * (wl.RevisionCount + 1)
 }
 
-func generateSchedule() {
-	fmt.Println("--- Starting Schedule Generation ---")
-	markMissedSessions()
-	state, _ := loadState()
-	realToday := time.Now().Truncate(24 * time.Hour)
-	stateDate, _ := time.Parse(TIME_FORMAT, state.LastScheduledDate)
-	syllabusEndDate, _ := time.Parse(TIME_FORMAT, rawConfig.SyllabusEndDate)
+// sm2Model is the SuperMemo-2 algorithm: a failed recall (grade < 3) restarts
+// the streak at a one day interval, a successful one grows the interval by the
+// chapter's ease factor, which itself moves with the grade.
+type sm2Model struct{}
 
-	if stateDate.Before(realToday) {
-		state.LastScheduledDate = realToday.Format(TIME_FORMAT)
-		saveState(state)
-		fmt.Printf("[FIX] Schedule path reset detected. Starting generation from today: %s\n", realToday.Format(TIME_FORMAT))
+func (sm2Model) Name() string      { return RevisionModelSM2 }
+func (sm2Model) MaxRevisions() int { return 0 }
+
+func (sm2Model) Start(wl *ChapterWorkload, today time.Time) int {
+	if wl.EaseFactor < SM2_MIN_EASE {
+		wl.EaseFactor = SM2_DEFAULT_EASE
 	}
+	wl.RecallStreak = 0
+	wl.IntervalDays = max(1, wl.InitialRevisionIntervalDays)
+	return wl.IntervalDays
+}
 
-	// --- Adaptive scaling ---
-	perf := PerformanceState{}
-	_ = loadJSON("performance_state.json", &perf)
-	consistency := perf.ConsistencyFactor
-	if consistency <= 0 || math.IsNaN(consistency) {
-		consistency = 1.0
+func (sm2Model) Review(wl *ChapterWorkload, grade int, today time.Time) int {
+	if wl.EaseFactor < SM2_MIN_EASE {
+		wl.EaseFactor = SM2_DEFAULT_EASE
 	}
-	minScale := 0.8
-	maxScale := 1.15
-	scale := minScale + (maxScale-minScale)*consistency
-	adaptedDailyStudyHrsGlobal := rawConfig.DailyStudyHrs * scale
-	adaptedMaxSessionHrsGlobal := rawConfig.MaxSessionHrs * (0.9 + 0.2*consistency)
-	if adaptedDailyStudyHrsGlobal <= 0 {
-		adaptedDailyStudyHrsGlobal = rawConfig.DailyStudyHrs
+	q := float64(grade)
+	wl.EaseFactor = math.Max(SM2_MIN_EASE, wl.EaseFactor+0.1-(5-q)*(0.08+(5-q)*0.02))
+
+	if grade < 3 {
+		wl.RecallStreak = 0
+		wl.IntervalDays = 1
+		return wl.IntervalDays
 	}
-	if adaptedMaxSessionHrsGlobal <= 0 {
-		adaptedMaxSessionHrsGlobal = rawConfig.MaxSessionHrs
+	switch wl.RecallStreak {
+	case 0:
+		wl.IntervalDays = max(1, wl.InitialRevisionIntervalDays)
+	case 1:
+		wl.IntervalDays = max(6, wl.InitialRevisionIntervalDays*2)
+	default:
+		wl.IntervalDays = int(math.Round(float64(wl.IntervalDays) * wl.EaseFactor))
 	}
+	wl.RecallStreak++
+	return wl.IntervalDays
+}
 
-	allChapters := calculateQuotas(&state)
-	allChapters = prioritizeChapters(allChapters)
-	currentDate, _ := time.Parse(TIME_FORMAT, state.LastScheduledDate)
+// fsrsModel is a simplified FSRS memory model. Each chapter carries a
+// stability S (days until recall probability drops to FSRS_TARGET_RECALL).
+// Retrievability decays as R(t) = (1 + t/(9S))^-1; a successful review grows S
+// more when recall was harder (low R) and the chapter is easier, a lapse
+// shrinks it. Chapter Difficulty (1-5) stands in for FSRS difficulty.
+type fsrsModel struct{}
 
-	if state.TotalRemainingTime <= 0.001 && len(getDueRevisions(state, currentDate)) == 0 {
-		if currentDate.After(syllabusEndDate) {
-			fmt.Println("[SUCCESS] All chapters are studied and all revisions are up-to-date. No new schedule generated.")
-			return
+func (fsrsModel) Name() string      { return RevisionModelFSRS }
+func (fsrsModel) MaxRevisions() int { return 0 }
+
+func (fsrsModel) Start(wl *ChapterWorkload, today time.Time) int {
+	wl.Stability = math.Max(FSRS_MIN_STABILITY, float64(wl.InitialRevisionIntervalDays))
+	return fsrsInterval(wl.Stability)
+}
+
+func (fsrsModel) Review(wl *ChapterWorkload, grade int, today time.Time) int {
+	s := math.Max(FSRS_MIN_STABILITY, wl.Stability)
+	elapsed := 0.0
+	if last, err := time.Parse(TIME_FORMAT, wl.LastReviewDate); err == nil {
+		elapsed = math.Max(0, today.Sub(last).Hours()/24.0)
+	}
+	r := fsrsRetrievability(elapsed, s)
+	d := math.Min(10, math.Max(1, wl.Difficulty*2)) // FSRS difficulty runs 1-10
+
+	if grade < 3 {
+		wl.Stability = math.Min(s, 2.18*math.Pow(d, -0.05)*(math.Pow(s+1, 0.34)-1)*math.Exp(1.26*(1-r)))
+	} else {
+		factor := math.Exp(1.49) * (11 - d) * math.Pow(s, -0.14) * (math.Exp(0.94*(1-r)) - 1)
+		switch grade {
+		case 3:
+			factor *= 0.29 // hard recall
+		case 5:
+			factor *= 2.61 // easy recall
 		}
+		wl.Stability = s * (1 + factor)
 	}
+	wl.Stability = math.Max(FSRS_MIN_STABILITY, wl.Stability)
+	return fsrsInterval(wl.Stability)
+}
+
+func fsrsRetrievability(elapsedDays, stability float64) float64 {
+	return math.Pow(1+elapsedDays/(9*stability), -1)
+}
 
-	fmt.Printf("[INFO] Required Daily Quota (WT): %.2f | Regenerating from %s\n", state.DailyQuotaWT, currentDate.Format(TIME_FORMAT))
+// fsrsInterval is the number of days until retrievability falls to
+// FSRS_TARGET_RECALL.
+func fsrsInterval(stability float64) int {
+	days := 9 * stability * (1/FSRS_TARGET_RECALL - 1)
+	return max(1, int(math.Round(days)))
+}
 
-	var activeStudyChapters []*ChapterWorkload
-	for i := range allChapters {
-		if !allChapters[i].IsStudyCompleted && allChapters[i].RemainingTime > 0.001 {
-			activeStudyChapters = append(activeStudyChapters, &allChapters[i])
+// startRevisionCycle marks a chapter's initial study as done on date and lets
+// the configured model pick its first revision date.
+func startRevisionCycle(wl *ChapterWorkload, date time.Time) {
+	wl.IsStudyCompleted = true
+	wl.LastReviewDate = date.Format(TIME_FORMAT)
+	days := revisionModelFor(rawConfig).Start(wl, date)
+	wl.NextRevisionDate = date.AddDate(0, 0, days).Format(TIME_FORMAT)
+}
+
+// recordRevision applies a graded revision and sets the next revision date.
+// Adaptive models stop scheduling revisions that would fall after the exam.
+func recordRevision(wl *ChapterWorkload, grade int, today time.Time) {
+	model := revisionModelFor(rawConfig)
+	wl.RevisionCount++
+	wl.LastRecallGrade = grade
+	days := model.Review(wl, grade, today)
+	wl.LastReviewDate = today.Format(TIME_FORMAT)
+	if days <= 0 {
+		wl.NextRevisionDate = ""
+		return
+	}
+	next := today.AddDate(0, 0, days)
+	if model.MaxRevisions() == 0 {
+		if examDate, err := time.Parse(TIME_FORMAT, rawConfig.ExamDate); err == nil && next.After(examDate) {
+			wl.NextRevisionDate = ""
+			return
 		}
 	}
+	wl.NextRevisionDate = next.Format(TIME_FORMAT)
+}
 
-	lastSubjects := map[string]bool{}
-	if len(state.LastSubjects) > 0 {
-		for _, s := range state.LastSubjects {
-			lastSubjects[s] = true
-		}
+// hasRevisionsLeft reports whether a chapter still has a revision scheduled.
+func hasRevisionsLeft(wl ChapterWorkload) bool {
+	if wl.NextRevisionDate == "" {
+		return false
 	}
+	limit := revisionModelFor(rawConfig).MaxRevisions()
+	return limit == 0 || wl.RevisionCount < limit
+}
 
-	for currentDate.Before(syllabusEndDate.AddDate(0, 0, 1)) {
-		dailySessions := []Session{}
-		dailyProgressWT := 0.0
+func revisionLabel(wl ChapterWorkload) string {
+	if limit := revisionModelFor(rawConfig).MaxRevisions(); limit > 0 {
+		return fmt.Sprintf("Rev #%d of %d", wl.RevisionCount+1, limit)
+	}
+	return fmt.Sprintf("Rev #%d", wl.RevisionCount+1)
+}
 
-		// Base hours for the day
-		dailyTotalStudyHrs := adaptedDailyStudyHrsGlobal - (float64(rawConfig.DailyBufferMins) / 60.0)
-		if dailyTotalStudyHrs < 0.25 {
-			dailyTotalStudyHrs = math.Min(0.25, rawConfig.DailyStudyHrs-(float64(rawConfig.DailyBufferMins)/60.0))
+func parseRecallGrade(input string) (int, bool) {
+	grade, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || grade < 0 || grade > 5 {
+		return 0, false
+	}
+	return grade, true
+}
+
+// updateChapterPerformance nudges a chapter's difficulty after a session.
+// Without a rating a success always makes the chapter easier; with one the
+// understanding score decides the direction and size of the move, so a
+// session that was completed but poorly understood makes it harder. A low
+// focus score halves any easing, since the understanding claim is less
+// reliable.
+func updateChapterPerformance(wl ChapterWorkload, success bool, rating *SessionRating) ChapterWorkload {
+	rate := rawConfig.DifficultyAdjustmentRate
+	if success && rating != nil {
+		delta := rate * float64(3-rating.Understanding)
+		if delta < 0 && rating.Focus <= 2 {
+			delta /= 2
 		}
+		wl.Difficulty = math.Min(5.0, math.Max(1.0, wl.Difficulty+delta))
+		return wl
+	}
+	if success {
 
-		maxSessionsPerDay := int(math.Max(1.0, math.Floor(dailyTotalStudyHrs/adaptedMaxSessionHrsGlobal)))
-		sessionCount := 0
-		hoursAssigned := 0.0
-		todaySubjects := map[string]bool{}
+		wl.Difficulty = math.Max(1.0, wl.Difficulty-rate)
+	} else {
 
-		if currentDate.Weekday() == rawConfig.WeeklyRestDay {
-			dailySessions = append(dailySessions, Session{
-				Subject:  "Rest",
-				Chapter:  rawConfig.RestDayActivity,
-				Duration: rawConfig.DailyStudyHrs,
-				Type:     "Rest",
-				Status:   "Pending",
-			})
-		} else {
-			// Handle due revisions first
-			dueRevisions := getDueRevisions(state, currentDate)
-			sort.Slice(dueRevisions, func(i, j int) bool {
-				return dueRevisions[i].PriorityScore > dueRevisions[j].PriorityScore
-			})
-			for len(dueRevisions) > 0 && hoursAssigned < dailyTotalStudyHrs && sessionCount < maxSessionsPerDay {
-				revChapter := dueRevisions[0]
-				revDuration := math.Min(REVISION_TIME_HRS, dailyTotalStudyHrs-hoursAssigned)
-				if revDuration <= 0.001 {
-					break
-				}
-				dailySessions = append(dailySessions, Session{
-					Subject:   revChapter.Subject,
-					Chapter:   fmt.Sprintf("%s (Revision #%d)", revChapter.Chapter, revChapter.RevisionCount+1),
-					Duration:  revDuration,
-					ChapterID: revChapter.ID,
-					Type:      "Revision",
-					Status:    "Pending",
-				})
-				hoursAssigned += revDuration
-				todaySubjects[revChapter.Subject] = true
-				dueRevisions = dueRevisions[1:]
-				sessionCount++
-			}
+		wl.Difficulty = math.Min(5.0, wl.Difficulty+rate*2.0)
+	}
+	return wl
+}
 
-			// Filter active chapters
-			var currentActive []*ChapterWorkload
-			for _, ch := range activeStudyChapters {
-				if !ch.IsStudyCompleted && ch.RemainingTime > 0.001 {
-					currentActive = append(currentActive, ch)
-				}
-			}
-			activeStudyChapters = currentActive
-			sort.Slice(activeStudyChapters, func(i, j int) bool {
-				return activeStudyChapters[i].PriorityScore > activeStudyChapters[j].PriorityScore
-			})
-
-			// Pick 2 unique subjects for the day, not in lastSubjects
-			allSubjects := []string{}
-			for _, ch := range activeStudyChapters {
-				if !contains(allSubjects, ch.Subject) && !lastSubjects[ch.Subject] {
-					allSubjects = append(allSubjects, ch.Subject)
-				}
-			}
-			if len(allSubjects) < 2 {
-				// fallback if not enough unique subjects
-				for _, ch := range activeStudyChapters {
-					if !contains(allSubjects, ch.Subject) {
-						allSubjects = append(allSubjects, ch.Subject)
-					}
-				}
-			}
-			rand.Shuffle(len(allSubjects), func(i, j int) { allSubjects[i], allSubjects[j] = allSubjects[j], allSubjects[i] })
-			if len(allSubjects) > 2 {
-				allSubjects = allSubjects[:2]
-			}
-
-			// Assign study sessions for selected subjects
-			for dailyProgressWT < state.DailyQuotaWT && hoursAssigned < dailyTotalStudyHrs && len(activeStudyChapters) > 0 && sessionCount < maxSessionsPerDay {
-				foundChapterIndex := -1
-				maxP := -1e18
-				for i, ch := range activeStudyChapters {
-					if contains(allSubjects, ch.Subject) && ch.PriorityScore > maxP {
-						maxP = ch.PriorityScore
-						foundChapterIndex = i
-					}
-				}
-				if foundChapterIndex == -1 {
-					break
-				}
-				currentChapter := activeStudyChapters[foundChapterIndex]
-				sessionDuration := math.Min(adaptedMaxSessionHrsGlobal, currentChapter.RemainingTime)
-				if sessionDuration > (dailyTotalStudyHrs - hoursAssigned) {
-					sessionDuration = dailyTotalStudyHrs - hoursAssigned
-				}
-				if sessionDuration <= 0.001 {
-					activeStudyChapters = append(activeStudyChapters[:foundChapterIndex], activeStudyChapters[foundChapterIndex+1:]...)
-					continue
-				}
-
-				dailySessions = append(dailySessions, Session{
-					Subject:   currentChapter.Subject,
-					Chapter:   currentChapter.Chapter,
-					Duration:  sessionDuration,
-					ChapterID: currentChapter.ID,
-					Type:      "Study",
-					Status:    "Pending",
-				})
-
-				sessionWT := sessionDuration * (1 + currentChapter.Difficulty/5.0) * (currentChapter.Weightage * 2.0)
-				dailyProgressWT += sessionWT
-				hoursAssigned += sessionDuration
-				todaySubjects[currentChapter.Subject] = true
-				currentChapter.RemainingTime -= sessionDuration
-				sessionCount++
-				state.Workload[currentChapter.ID] = *currentChapter
-
-				if currentChapter.RemainingTime <= 0.001 {
-					currentChapter.IsStudyCompleted = true
-					currentChapter.NextRevisionDate = currentDate.AddDate(0, 0, currentChapter.InitialRevisionIntervalDays).Format(TIME_FORMAT)
-					activeStudyChapters = append(activeStudyChapters[:foundChapterIndex], activeStudyChapters[foundChapterIndex+1:]...)
-				}
-			}
+func calculateWeightedTime(wl ChapterWorkload) float64 {
 
-			// Add buffer
-			dailySessions = append(dailySessions, Session{
-				Subject:  "Buffer",
-				Chapter:  "Recovery/Review",
-				Duration: float64(rawConfig.DailyBufferMins) / 60.0,
-				Type:     "Buffer",
-				Status:   "Pending",
-			})
-		}
+	return wl.RemainingTime * (1 + wl.Difficulty/5.0) * (wl.Weightage * 2.0)
+}
 
-		// Update LastSubjects for next day
-		state.LastSubjects = []string{}
-		for s := range todaySubjects {
-			state.LastSubjects = append(state.LastSubjects, s)
-		}
+func calculateQuotas(state *ScheduleState) []ChapterWorkload {
+	today := currentDay()
+	syllabusEndDate, _ := time.Parse(TIME_FORMAT, rawConfig.SyllabusEndDate)
+	totalWorkload := 0.0
+	totalRemainingTime := 0.0
+	netStudyDays := 0
+	var allChapters []ChapterWorkload
+	for _, id := range sortedChapterIDs(state.Workload) {
+		wl := state.Workload[id]
+		if !wl.IsStudyCompleted && wl.RemainingTime > 0.001 {
+			wl.PriorityScore = calculateWeightedTime(wl)
+			totalWorkload += wl.PriorityScore
+			totalRemainingTime += wl.RemainingTime
+		} else if hasRevisionsLeft(wl) {
+			revDate, _ := time.Parse(TIME_FORMAT, wl.NextRevisionDate)
+			daysUntilDue := revDate.Sub(today).Hours() / 24.0
 
-		for _, chPtr := range activeStudyChapters {
-			state.Workload[chPtr.ID] = *chPtr
+			wl.PriorityScore = (wl.Difficulty / 5.0) * wl.Weightage * (10.0 / math.Max(1.0, daysUntilDue))
+			wl.PriorityScore *= 1 + MOCK_PRIORITY_WEIGHT*wl.MockWeakness
+			totalWorkload += wl.PriorityScore * REVISION_TIME_HRS
+		} else {
+			wl.PriorityScore = 0.0
 		}
-
-		writeDayPlan(currentDate, dailySessions)
-		currentDate = currentDate.AddDate(0, 0, 1)
-		state.LastScheduledDate = currentDate.Format(TIME_FORMAT)
+		state.Workload[id] = wl
+		allChapters = append(allChapters, wl)
 	}
-
-	saveState(state)
-	fmt.Println("\n--- Schedule Generation Complete ---")
-	fmt.Printf("Syllabus plans saved in '%s/' until %s.\n", SCHEDULE_DIR, syllabusEndDate.Format(TIME_FORMAT))
-	updatePerformance()
-}
-func processMissedSessionsForDate(date time.Time) ([]Session, error) {
-	sessions, err := readDayPlan(date)
-	if err != nil {
-		return nil, err
+	currentDate := today
+	if state.LastScheduledDate != "" {
+		stateDate, _ := time.Parse(TIME_FORMAT, state.LastScheduledDate)
+		if stateDate.After(today) {
+			currentDate = stateDate
+		}
 	}
-	missedSessions := []Session{}
-	updated := false
-	modifiedSessions := make([]Session, len(sessions))
-	copy(modifiedSessions, sessions)
-	for i, s := range modifiedSessions {
-		if s.Status == "Pending" && (s.Type == "Study" || s.Type == "Revision") {
-			modifiedSessions[i].Status = "Missed"
-			missedSessions = append(missedSessions, modifiedSessions[i])
-			updated = true
+	for currentDate.Before(syllabusEndDate.AddDate(0, 0, 1)) {
+		if isStudyDay(currentDate) {
+			netStudyDays++
 		}
+		currentDate = currentDate.AddDate(0, 0, 1)
 	}
-	if updated {
-		writeDayPlan(date, modifiedSessions)
+	dailyQuotaWT := 0.0
+	if netStudyDays > 0 {
+		dailyQuotaWT = totalWorkload / float64(netStudyDays)
 	}
-	return missedSessions, nil
+	state.TotalWeightedWorkload = totalWorkload
+	state.TotalRemainingTime = totalRemainingTime
+	state.NetStudyDays = netStudyDays
+	state.DailyQuotaWT = dailyQuotaWT
+	return allChapters
 }
 
-func adjustWorkload(missedSessions []Session, auditDate time.Time) {
-	fmt.Println("\n[ADJUSTMENT] Recalculating workload due to missed sessions...")
-	state, _ := loadState()
-	if len(state.Workload) == 0 {
-		fmt.Println("[WARNING] No active workload in state. Skipping adjustment.")
-		return
+func prioritizeChapters(allChapters []ChapterWorkload) []ChapterWorkload {
+	var activeStudyChapters []ChapterWorkload
+	for _, wl := range allChapters {
+		if !wl.IsStudyCompleted && wl.RemainingTime > 0.001 {
+			activeStudyChapters = append(activeStudyChapters, wl)
+		}
 	}
-	for _, session := range missedSessions {
-		chID := session.ChapterID
-		duration := session.Duration
-		if chID != "" {
-			if workload, ok := state.Workload[chID]; ok {
 
-				workload = updateChapterPerformance(workload, false) 
-				if session.Type == "Revision" {
+	sort.SliceStable(activeStudyChapters, func(i, j int) bool {
+		return activeStudyChapters[i].PriorityScore > activeStudyChapters[j].PriorityScore
+	})
+	return activeStudyChapters
+}
 
-					workload.NextRevisionDate = auditDate.AddDate(0, 0, 1).Format(TIME_FORMAT)
-					workload.RevisionCount--
-					workload.RevisionCount = int(math.Max(0, float64(workload.RevisionCount)))
-					fmt.Printf("  -> Missed Revision for %s. Resetting due date.\n", workload.Chapter)
-				} else {
+// sortedChapterIDs returns the workload's chapter IDs in a fixed order, so that
+// ties in priority are broken the same way on every run.
+func sortedChapterIDs(workload map[string]ChapterWorkload) []string {
+	ids := make([]string, 0, len(workload))
+	for id := range workload {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
 
-					workload.RemainingTime += duration
-					fmt.Printf("  -> Added %.1f hrs back to initial study of %s.\n", duration, workload.Chapter)
-				}
-				state.Workload[chID] = workload
+func getDueRevisions(state ScheduleState, today time.Time) []ChapterWorkload {
+	var dueRevisions []ChapterWorkload
+	for _, id := range sortedChapterIDs(state.Workload) {
+		wl := state.Workload[id]
+		if wl.IsStudyCompleted && hasRevisionsLeft(wl) {
+			revDate, err := time.Parse(TIME_FORMAT, wl.NextRevisionDate)
+			if err == nil && !revDate.After(today) {
+				dueRevisions = append(dueRevisions, wl)
 			}
 		}
 	}
-
-	restartDate := auditDate.AddDate(0, 0, 1)
-	state.LastScheduledDate = restartDate.Format(TIME_FORMAT)
-	saveState(state)
-	fmt.Printf("[ADJUSTMENT] Re-generating schedule from %s with adjusted workload...\n", restartDate.Format(TIME_FORMAT))
-	generateSchedule()
-	fmt.Println("[ADJUSTMENT] Schedule successfully updated and re-balanced.")
+	return dueRevisions
 }
 
-func inputReader(cmdChan chan<- command) {
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
-		if input != "" {
-			cmdChan <- command{action: input}
+// ------------------ Chapter Prerequisites ------------------
+
+// prerequisiteGraph maps each chapter ID to the chapter IDs it requires, as
+// declared in the config.
+func prerequisiteGraph(c Config) map[string][]string {
+	graph := map[string][]string{}
+	for _, wl := range c.InitialWorkload {
+		if len(wl.Prerequisites) > 0 {
+			graph[wl.ID] = wl.Prerequisites
 		}
 	}
+	return graph
 }
 
-func runStudyTimer(sessions []Session, sessionIndex int, initialElapsed int, today time.Time) (bool, []Session) {
-	session := &sessions[sessionIndex]
-	totalSeconds := int(session.Duration * 3600)
-	elapsedSeconds := initialElapsed
-	var startTime time.Time
-
-	if initialElapsed == 0 {
-		startTime = time.Now()
-		fmt.Printf("\n[START] Starting %s session for %.1f hrs (Total: %d seconds). Press 'p' to pause.\n", session.Type, session.Duration, totalSeconds)
-	} else {
-
-		startTime = time.Now().Add(time.Duration(-initialElapsed) * time.Second)
-		fmt.Printf("\n[RESUME] Resuming %s session. %s/%s complete. Press 'p' to pause.\n", session.Type, time.Duration(initialElapsed)*time.Second, time.Duration(totalSeconds)*time.Second)
-		remaining := totalSeconds - elapsedSeconds
-		fmt.Printf("\033[2K\r[TIMER] Remaining: %s | Status: RUNNING  ",time.Duration(remaining)*time.Second)
+// checkPrerequisites reports prerequisites that name unknown chapters and
+// returns the first dependency cycle found, as the path of IDs that closes it.
+func checkPrerequisites(workload []ChapterWorkload) (problems []string, cycle []string) {
+	known := map[string]bool{}
+	graph := map[string][]string{}
+	var ids []string
+	for _, wl := range workload {
+		known[wl.ID] = true
+		graph[wl.ID] = wl.Prerequisites
+		ids = append(ids, wl.ID)
 	}
-
-	musicOn := true
-	startMusic()
-
-	paused := false
-	missedSessions := []Session{}
-	ticker := time.NewTicker(time.Second)
-	saveTicker := time.NewTicker(PROGRESS_SAVE_INTERVAL) 
-	stopTimerChan := make(chan bool)
-	cmdChan := make(chan command)
-	go inputReader(cmdChan)
-
-	go func() {
-		for {
-			select {
-			case <-saveTicker.C:
-
-				if !paused && elapsedSeconds < totalSeconds && session.ChapterID != "" {
-					saveProgress(session.ChapterID, elapsedSeconds)
-				}
-			case <-stopTimerChan:
-				saveTicker.Stop()
-				return
+	sort.Strings(ids)
+	for _, id := range ids {
+		for _, p := range graph[id] {
+			if !known[p] {
+				problems = append(problems, fmt.Sprintf("%s lists unknown prerequisite %q", id, p))
 			}
 		}
-	}()
-	
-	fmt.Printf("[Timer] %s\n", session.Chapter)
-	finished := false
-	for elapsedSeconds < totalSeconds && !finished {
-		select {
-		case cmd := <-cmdChan:
-			switch cmd.action {
-				case "o" :
-				if musicOn {
-					stopMusic() 
-					musicOn = false
-					fmt.Println("\n[ACTION] Music OFF. Press 'o' to turn music back on. (Timer continues)")
-				}else {
-					startMusic()
-					musicOn = true 
-					fmt.Println("\n[ACTION] Music On. Press 'o' to turn music back off. (Timer continues)")
-				}
-			case "p":
-				if !paused {
-					if musicOn {
-					pauseMusic()
-					}
-					paused = true
-					fmt.Print("\n[ACTION] Paused. Enter 'r' to resume, 'f' to finish early, or 'm' to mark missed. ")
-					if session.ChapterID != "" {
-						saveProgress(session.ChapterID, elapsedSeconds)
-					}
-				}
-			case "r":
-				if paused {
-					if musicOn {
-					resumeMusic()
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	color := map[string]int{}
+	var path []string
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		color[id] = visiting
+		path = append(path, id)
+		for _, p := range graph[id] {
+			switch color[p] {
+			case visiting:
+				for i, onPath := range path {
+					if onPath == p {
+						cycle = append(append([]string{}, path[i:]...), p)
+						return true
 					}
-					paused = false
-					startTime = time.Now().Add(time.Duration(-elapsedSeconds) * time.Second)
-					remaining := totalSeconds - elapsedSeconds
-					fmt.Printf("\033[2K\r[TIMER] Remaining: %s | Status: RUNNING  ",time.Duration(remaining)*time.Second)
 				}
-			case "f":
-				session.Status = "Completed"
-				fmt.Println("\n[ACTION] Session finished early/forced completion.")
-				finished = true
-			case "m":
-				session.Status = "Missed"
-				missedSessions = append(missedSessions, *session)
-				fmt.Println("\n[ACTION] Session marked as MISSED. This will be rescheduled.")
-				finished = true
-			default:
-				if paused {
-					fmt.Print("Invalid command. Options: p, r, f, m. ")
+			case unvisited:
+				if known[p] && visit(p) {
+					return true
 				}
 			}
-			case <-ticker.C:
-				if !paused {
-					elapsedSeconds = int(time.Since(startTime).Seconds())
-				}
-				remaining := totalSeconds - elapsedSeconds
-					status := "RUNNING"
-					if paused {
-						status = "PAUSED"
-					}
-					fmt.Printf("\033[2K\r[TIMER] Remaining: %s | Status: %s", time.Duration(remaining)*time.Second, status)
-				if remaining <= 0 {
-					finished = true
-				}
 		}
+		path = path[:len(path)-1]
+		color[id] = visited
+		return false
 	}
+	for _, id := range ids {
+		if color[id] == unvisited && visit(id) {
+			return problems, cycle
+		}
+	}
+	return problems, nil
+}
 
-	close(stopTimerChan)
-	ticker.Stop()
-	if musicOn {
-	stopMusic()
+// prerequisiteWarnings holds the last problems printed by
+// validatePrerequisites, so reloading the same config does not repeat them.
+var prerequisiteWarnings string
+
+// validatePrerequisites prints problems with the declared prerequisites and,
+// if they form a cycle, drops them all so that no chapter is blocked forever.
+func validatePrerequisites(c *Config) {
+	problems, cycle := checkPrerequisites(c.InitialWorkload)
+	var out strings.Builder
+	for _, problem := range problems {
+		out.WriteString(ColorYellow + "[CONFIG] " + problem + "; ignoring it." + ColorReset + "\n")
+	}
+	if cycle != nil {
+		fmt.Fprintf(&out, ColorRed+"[CONFIG ERROR] Chapter prerequisites form a cycle: %s. Prerequisites are ignored until %s is fixed."+ColorReset+"\n",
+			strings.Join(cycle, " -> "), CONFIG_FILE)
+	}
+	if out.String() != prerequisiteWarnings {
+		fmt.Fprint(logOut, out.String())
+		prerequisiteWarnings = out.String()
+	}
+	if cycle == nil {
+		return
+	}
+	for i := range c.InitialWorkload {
+		c.InitialWorkload[i].Prerequisites = nil
 	}
+}
 
-	if session.Status != "Missed" {
-		session.Status = "Completed"
-		if elapsedSeconds >= totalSeconds {
-			fmt.Println("\n\n" + ColorGreen + "[COMPLETED] Session finished! Great job. 🔔" + ColorReset)
+// unmetPrerequisites lists the prerequisites of wl whose initial study is not
+// complete yet. A chapter that is already under way is never held back, and
+// prerequisites missing from the syllabus are ignored.
+func unmetPrerequisites(wl ChapterWorkload, graph map[string][]string, lookup func(id string) (ChapterWorkload, bool)) []string {
+	if wl.IsStudyCompleted || wl.RemainingTime < wl.InitialTotalTime-0.001 {
+		return nil
+	}
+	var unmet []string
+	for _, id := range graph[wl.ID] {
+		if p, ok := lookup(id); ok && !p.IsStudyCompleted && p.RemainingTime > 0.001 {
+			unmet = append(unmet, id)
 		}
+	}
+	return unmet
+}
 
-		if session.ChapterID != "" {
-			state, _ := loadState()
-			if workload, ok := state.Workload[session.ChapterID]; ok {
-				workload = updateChapterPerformance(workload, true)
-				if session.Type == "Revision" {
-					workload.RevisionCount++
-					if workload.RevisionCount < MAX_REVISIONS {
+// BlockedChapter is a chapter whose study cannot start yet.
+type BlockedChapter struct {
+	ChapterID  string   `json:"chapter_id"`
+	Subject    string   `json:"subject"`
+	Chapter    string   `json:"chapter"`
+	WaitingFor []string `json:"waiting_for"` // chapter IDs
+}
 
-						nextInterval := workload.InitialRevisionIntervalDays * (workload.RevisionCount + 1)
-						workload.NextRevisionDate = today.AddDate(0, 0, nextInterval).Format(TIME_FORMAT)
-					} else {
-						workload.NextRevisionDate = "" 
-					}
-				} else {
+// blockedChapters lists the chapters of state held back by prerequisites.
+func blockedChapters(state ScheduleState) []BlockedChapter {
+	graph := prerequisiteGraph(rawConfig)
+	lookup := func(id string) (ChapterWorkload, bool) {
+		wl, ok := state.Workload[id]
+		return wl, ok
+	}
+	blocked := []BlockedChapter{}
+	for _, id := range sortedChapterIDs(state.Workload) {
+		wl := state.Workload[id]
+		if unmet := unmetPrerequisites(wl, graph, lookup); len(unmet) > 0 {
+			blocked = append(blocked, BlockedChapter{ChapterID: wl.ID, Subject: wl.Subject, Chapter: wl.Chapter, WaitingFor: unmet})
+		}
+	}
+	return blocked
+}
 
-					timeSpent := session.Duration
-					if elapsedSeconds < totalSeconds {
-						timeSpent = float64(elapsedSeconds) / 3600.0
-					}
-					workload.RemainingTime = math.Max(0, workload.RemainingTime-timeSpent)
+// ------------------ Sub-topics ------------------
 
-					if workload.RemainingTime <= 0.001 {
-						workload.IsStudyCompleted = true
+// SubTopic is an optional part of a chapter with its own estimate. DoneHours
+// is the study time already credited to it; Completed is set once it is
+// covered, either by studying or by marking it done.
+type SubTopic struct {
+	Name      string  `json:"name"`
+	Hours     float64 `json:"hours"`
+	DoneHours float64 `json:"done_hrs,omitempty"`
+	Completed bool    `json:"completed"`
+}
 
-						workload.NextRevisionDate = today.AddDate(0, 0, workload.InitialRevisionIntervalDays).Format(TIME_FORMAT)
-					}
-				}
-				state.Workload[session.ChapterID] = workload
-				saveState(state)
-			}
-		}
-		deleteProgress()
-		writeDayPlan(today, sessions)
-		return true, sessions
+func (t SubTopic) Remaining() float64 {
+	if t.Completed {
+		return 0
 	}
-
-	deleteProgress()
-	writeDayPlan(today, sessions)
-	adjustWorkload(missedSessions, today)
-	return true, sessions
+	return math.Max(0, t.Hours-t.DoneHours)
 }
 
-func runBreakTimer(durationMins int) {
-	totalSeconds := durationMins * 60
-	elapsedSeconds := 0
-	startTime := time.Now()
-	paused := false
-	ticker := time.NewTicker(time.Second)
-	cmdChan := make(chan command)
-	go inputReader(cmdChan)
-	fmt.Printf("\n" + ColorCyan + "[BREAK] Starting %d minute break. Press 'q' to skip, 'p' to pause. ☕️" + ColorReset + "\n", durationMins)
-	for elapsedSeconds < totalSeconds {
-		select {
-		case cmd := <-cmdChan:
-			switch cmd.action {
-			case "q":
-				fmt.Println("\n[ACTION] Break skipped.")
-				elapsedSeconds = totalSeconds
-			case "p":
-				if !paused {
-					paused = true
-					fmt.Print("\n[ACTION] Break Paused. Enter 'r' to resume. ")
-				}
-			case "r":
-				if paused {
-					paused = false
-					startTime = time.Now().Add(time.Duration(-elapsedSeconds) * time.Second)
-					fmt.Print("\n[ACTION] Break Resumed. ")
-				}
-			}
-		case <-ticker.C:
-			if !paused {
-				elapsedSeconds = int(time.Since(startTime).Seconds())
-			}
-			remaining := totalSeconds - elapsedSeconds
-			if elapsedSeconds%1 == 0 || remaining <= 5 {
-				status := "RUNNING"
-				if paused {
-					status = "PAUSED"
-				}
-				fmt.Printf("\033[2K\r[TIMER] Break Remaining: %s | Status: %s ", time.Duration(remaining)*time.Second, status)
+// subTopicWarnings holds the last problems printed by validateSubTopics.
+var subTopicWarnings string
+
+// validateSubTopics renames sub-topics whose names cannot be stored in a plan
+// file: plans list a session's topics on one line separated by "; ".
+func validateSubTopics(c *Config) {
+	var out strings.Builder
+	for i := range c.InitialWorkload {
+		wl := &c.InitialWorkload[i]
+		for j := range wl.SubTopics {
+			name := wl.SubTopics[j].Name
+			fixed := strings.TrimSpace(strings.NewReplacer(";", ",", "\n", " ", "\r", " ").Replace(name))
+			if fixed == "" {
+				fixed = fmt.Sprintf("Part %d", j+1)
 			}
-			if remaining <= 0 {
+			if fixed != name {
+				fmt.Fprintf(&out, ColorYellow+"[CONFIG] %s sub-topic %q cannot contain ';' or line breaks or be empty; using %q."+ColorReset+"\n", wl.ID, name, fixed)
+				wl.SubTopics[j].Name = fixed
 			}
 		}
 	}
-	ticker.Stop()
-	if elapsedSeconds >= totalSeconds {
-		fmt.Println("\n\n" + ColorGreen + "[BREAK] Break finished! Time to select your next session." + ColorReset)
+	if out.String() != subTopicWarnings {
+		fmt.Fprint(logOut, out.String())
+		subTopicWarnings = out.String()
 	}
 }
 
-func runTimerCLI() {
-	rawConfig = loadConfig()
-	realToday := time.Now().Truncate(24 * time.Hour)
-	fmt.Printf("\n--- Timer CLI for %s ---\n", realToday.Format(TIME_FORMAT))
-	state, _ := loadState()
-
-	lastScheduled, _ := time.Parse(TIME_FORMAT, state.LastScheduledDate)
-	missedSessionsAcrossDays := []Session{}
-
-	for d := lastScheduled; d.Before(realToday); d = d.AddDate(0, 0, 1) {
-		missed, err := processMissedSessionsForDate(d)
-		if err == nil && len(missed) > 0 {
-			fmt.Printf("[AUDIT] Found %d missed sessions on %s. Adjusting workload.\n", len(missed), d.Format(TIME_FORMAT))
-			missedSessionsAcrossDays = append(missedSessionsAcrossDays, missed...)
-		}
+// adoptConfigSubTopics brings sub-topics added to the config into the saved
+// state. It runs when a command or the menu loads the config, not on every
+// state load; a missing or damaged state is left to loadState.
+func adoptConfigSubTopics() {
+	data, err := os.ReadFile(STATE_FILE)
+	state, err := decodeState(data, err)
+	if err != nil {
+		return
 	}
-
-	if len(missedSessionsAcrossDays) > 0 {
-		fmt.Printf("[RE-BALANCING] Total %d missed sessions detected. Adjusting workload and regenerating path from TODAY (%s)...\n", len(missedSessionsAcrossDays), realToday.Format(TIME_FORMAT))
-		adjustWorkload(missedSessionsAcrossDays, realToday.AddDate(0, 0, -1))
-	} else if lastScheduled.Before(realToday.AddDate(0, 0, 1)) {
-		fmt.Println("[RE-BALANCING] Schedule is behind. Regenerating path to ensure today is planned.")
-		state.LastScheduledDate = realToday.Format(TIME_FORMAT)
+	if adoptSubTopics(&state, rawConfig) {
 		saveState(state)
-		generateSchedule()
 	}
+}
 
-	sessions, err := readDayPlan(realToday)
-	if err != nil {
-		fmt.Printf("[ERROR] Could not load today's schedule. Run '3' (RE-GENERATE) first: %v\n", err)
+// subTopicTotals returns the estimated and the remaining hours of wl's
+// sub-topics.
+func subTopicTotals(wl ChapterWorkload) (total, remaining float64) {
+	for _, t := range wl.SubTopics {
+		total += t.Hours
+		remaining += t.Remaining()
+	}
+	return total, remaining
+}
+
+// syncRemainingTime derives RemainingTime from the unfinished sub-topics of a
+// chapter that has them.
+func syncRemainingTime(wl *ChapterWorkload) {
+	if len(wl.SubTopics) == 0 {
 		return
 	}
+	_, wl.RemainingTime = subTopicTotals(*wl)
+}
 
-	progress, foundProgress := loadProgress(realToday)
-	reader := bufio.NewReader(os.Stdin)
+// cloneSubTopics gives wl its own copy of its sub-topics, so that changes to a
+// copy of a ChapterWorkload do not write through to the original.
+func cloneSubTopics(wl *ChapterWorkload) {
+	if wl.SubTopics != nil {
+		wl.SubTopics = append([]SubTopic(nil), wl.SubTopics...)
+	}
+}
 
-	if foundProgress {
-		sessionIndexToResume := -1
-		for i, s := range sessions {
-			if s.ChapterID == progress.ChapterID {
-				sessionIndexToResume = i
-				break
-			}
+// coverSubTopics credits hours of study to wl's unfinished sub-topics, those
+// named in first before the rest in syllabus order, and returns the names of
+// the sub-topics the hours went to. wl must own its sub-topics.
+func coverSubTopics(wl *ChapterWorkload, hours float64, first []string) []string {
+	var covered []string
+	credit := func(t *SubTopic) {
+		if hours <= 0.001 || t.Remaining() <= 0.001 {
+			return
 		}
-		if sessionIndexToResume != -1 {
-			sessionToResume := sessions[sessionIndexToResume]
-			fmt.Printf("\n" + ColorYellow + "[RESUME ALERT] Unfinished session found for %s - %s (%s elapsed)." + ColorReset + "\n",
-				sessionToResume.Subject, sessionToResume.Chapter, time.Duration(progress.ElapsedSeconds)*time.Second)
-			fmt.Print("Do you want to resume this session? (y/N): ")
-			input, _ := reader.ReadString('\n')
-			input = strings.TrimSpace(strings.ToLower(input))
+		spent := math.Min(hours, t.Remaining())
+		t.DoneHours += spent
+		t.Completed = t.Remaining() <= 0.001
+		hours -= spent
+		covered = append(covered, t.Name)
+	}
+	for i := range wl.SubTopics {
+		if contains(first, wl.SubTopics[i].Name) {
+			credit(&wl.SubTopics[i])
+		}
+	}
+	for i := range wl.SubTopics {
+		credit(&wl.SubTopics[i])
+	}
+	return covered
+}
 
-			if input == "y" {
-				finished, updatedSessions := runStudyTimer(sessions, sessionIndexToResume, progress.ElapsedSeconds, realToday)
-				sessions = updatedSessions
-				if finished && (sessions[sessionIndexToResume].Type == "Study" || sessions[sessionIndexToResume].Type == "Revision") && sessions[sessionIndexToResume].Status == "Completed" {
-					runBreakTimer(BREAK_MINUTES)
-				}
-			} else {
-				fmt.Println("\n[ACTION] Marking interrupted session as MISSED and rescheduling.")
-				sessions[sessionIndexToResume].Status = "Missed"
-				writeDayPlan(realToday, sessions)
-				adjustWorkload([]Session{sessions[sessionIndexToResume]}, realToday)
-				deleteProgress()
+// completeSubTopic marks one sub-topic of wl as covered, starting the revision
+// cycle if it was the last one left.
+func completeSubTopic(wl ChapterWorkload, name string, today time.Time) (ChapterWorkload, error) {
+	cloneSubTopics(&wl)
+	for i := range wl.SubTopics {
+		if wl.SubTopics[i].Name != name {
+			continue
+		}
+		wl.SubTopics[i].Completed = true
+		syncRemainingTime(&wl)
+		if !wl.IsStudyCompleted && wl.RemainingTime <= 0.001 {
+			startRevisionCycle(&wl, today)
+		}
+		return wl, nil
+	}
+	return wl, fmt.Errorf("%s has no sub-topic %q", wl.ID, name)
+}
 
-				sessions, _ = readDayPlan(realToday) 
+// adoptSubTopics copies sub-topics added to the config into a state that was
+// created without them. Study already done on the chapter is credited to its
+// first sub-topics.
+func adoptSubTopics(state *ScheduleState, c Config) bool {
+	changed := false
+	for _, cfg := range c.InitialWorkload {
+		wl, ok := state.Workload[cfg.ID]
+		if !ok || len(wl.SubTopics) > 0 || len(cfg.SubTopics) == 0 {
+			continue
+		}
+		studied := math.Max(0, wl.InitialTotalTime-wl.RemainingTime)
+		wl.SubTopics = append([]SubTopic(nil), cfg.SubTopics...)
+		wl.InitialTotalTime, _ = subTopicTotals(wl)
+		if wl.IsStudyCompleted {
+			for i := range wl.SubTopics {
+				wl.SubTopics[i].Completed = true
 			}
 		} else {
-			fmt.Println("[WARNING] Progress file found but chapter ID mismatch. Deleting progress file.")
-			deleteProgress()
+			coverSubTopics(&wl, studied, nil)
 		}
+		syncRemainingTime(&wl)
+		state.Workload[cfg.ID] = wl
+		changed = true
 	}
+	return changed
+}
 
-	for {
-		fmt.Println("\n-- Today's Schedule --")
-		hasPending := false
+// sessionTitle is the chapter of a session followed by the sub-topics it
+// covers and the kind of work, if known.
+func sessionTitle(s Session) string {
+	title := s.Chapter
+	if len(s.Topics) > 0 {
+		title = fmt.Sprintf("%s [%s]", title, strings.Join(s.Topics, "; "))
+	}
+	if s.Activity != "" && s.Activity != ActivityMock {
+		title += " - " + activityLabel(s.Activity)
+	}
+	return title
+}
 
-		for i, s := range sessions {
-			if s.Type == "Study" || s.Type == "Revision" {
-				hasPending = hasPending || (s.Status == "Pending")
-				status := s.Status
-				if status == "Pending" {
-					status = ColorCyan + status + ColorReset
-				} else if status == "Completed" {
-					status = ColorGreen + status + ColorReset
-				} else if status == "Missed" {
-					status = ColorRed + status + ColorReset
-				}
-				fmt.Printf("[%d] %.1f hrs | %s: %s (%s)\n", i+1, s.Duration, s.Subject, s.Chapter, status)
-			}
-		}
-		if !hasPending {
-			fmt.Println("\n[INFO] All Study/Revision sessions complete for today. Press 'q' to quit.")
+func printSubTopics(wl ChapterWorkload) {
+	fmt.Printf("%s %s: %s (%.1f hrs left)\n", wl.ID, wl.Subject, wl.Chapter, wl.RemainingTime)
+	if len(wl.SubTopics) == 0 {
+		fmt.Println("  -> No sub-topics configured for this chapter.")
+		return
+	}
+	for i, t := range wl.SubTopics {
+		mark := "[ ]"
+		if t.Completed {
+			mark = "[x]"
 		}
+		fmt.Printf("  %d. %s %s (%.1f of %.1f hrs done)\n", i+1, mark, t.Name, t.Hours-t.Remaining(), t.Hours)
+	}
+}
 
-		fmt.Print("\n> Enter session number to START, 'm' to mark all PENDING as MISSED, 's' to see all, or 'q' to quit: ")
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
+// ------------------ Session Activities ------------------
 
-		if input == "q" {
-			break
-		}
+// Session.Activity says what kind of work a session is. Study sessions move
+// through the study activities in order as a chapter progresses; revisions
+// are flashcard reviews, exam-phase revisions previous-year questions.
+const (
+	ActivityTheory     = "theory"
+	ActivityProblems   = "problems"
+	ActivityPYQ        = "pyq"
+	ActivityFlashcards = "flashcards"
+	ActivityMock       = "mock"
+)
 
-		if input == "s" {
-			fmt.Println("\n-- All Sessions (Including Buffer/Rest) --")
-			for i, s := range sessions {
-				fmt.Printf("[%d] %.1f hrs | %s: %s (%s, %s)\n", i+1, s.Duration, s.Subject, s.Chapter, s.Type, s.Status)
-			}
-			continue
-		}
+var studyActivities = []string{ActivityTheory, ActivityProblems, ActivityPYQ}
 
-		if input == "m" && hasPending {
-			missed := []Session{}
-			missedCount := 0
-			modifiedSessions := make([]Session, len(sessions))
-			copy(modifiedSessions, sessions)
-			for i, s := range modifiedSessions {
-				if s.Status == "Pending" && (s.Type == "Study" || s.Type == "Revision") {
-					modifiedSessions[i].Status = "Missed"
-					missed = append(missed, modifiedSessions[i])
-					missedCount++
-				}
+var activityLabels = map[string]string{
+	ActivityTheory:     "Theory Reading",
+	ActivityProblems:   "Problem Practice",
+	ActivityPYQ:        "Previous-Year Questions",
+	ActivityFlashcards: "Flashcard Review",
+	ActivityMock:       "Mock Test",
+}
+
+// DEFAULT_ACTIVITY_MIX splits a chapter's study hours when activity_mix has
+// no entry for the chapter or its subject.
+var DEFAULT_ACTIVITY_MIX = map[string]float64{ActivityTheory: 0.5, ActivityProblems: 0.35, ActivityPYQ: 0.15}
+
+func activityLabel(activity string) string {
+	if label, ok := activityLabels[activity]; ok {
+		return label
+	}
+	return activity
+}
+
+// activityMixFor returns the study activity mix of a chapter: its own
+// activity_mix entry, else its subject's, else DEFAULT_ACTIVITY_MIX.
+func activityMixFor(c Config, wl ChapterWorkload) map[string]float64 {
+	if mix, ok := c.ActivityMix[wl.ID]; ok {
+		return mix
+	}
+	if mix, ok := c.ActivityMix[wl.Subject]; ok {
+		return mix
+	}
+	return DEFAULT_ACTIVITY_MIX
+}
+
+// validateActivityMix describes every activity_mix entry the generator cannot
+// use; those entries fall back to DEFAULT_ACTIVITY_MIX.
+func validateActivityMix(c Config) []string {
+	known := map[string]bool{}
+	for _, wl := range c.InitialWorkload {
+		known[wl.ID] = true
+		known[wl.Subject] = true
+	}
+	var keys []string
+	for key := range c.ActivityMix {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		mix := c.ActivityMix[key]
+		if !known[key] {
+			problems = append(problems, fmt.Sprintf("activity_mix: %q is neither a chapter ID nor a subject", key))
+		}
+		var unknown []string
+		for activity := range mix {
+			if !contains(studyActivities, activity) {
+				unknown = append(unknown, activity)
 			}
-			if missedCount > 0 {
-				writeDayPlan(realToday, modifiedSessions)
-				fmt.Printf("[ACTION] Marked %d pending study/revision sessions as MISSED. These will be rescheduled.\n", missedCount)
-				adjustWorkload(missed, realToday)
-				sessions, _ = readDayPlan(realToday)
+		}
+		sort.Strings(unknown)
+		for _, activity := range unknown {
+			problems = append(problems, fmt.Sprintf("activity_mix[%q]: unknown activity %q (use %s)", key, activity, strings.Join(studyActivities, ", ")))
+		}
+		total := 0.0
+		for _, activity := range studyActivities {
+			if mix[activity] < 0 {
+				problems = append(problems, fmt.Sprintf("activity_mix[%q]: %s share is negative", key, activity))
 			} else {
-				fmt.Println("[INFO] No pending study/revision sessions to mark as missed.")
+				total += mix[activity]
 			}
+		}
+		if total <= 0 {
+			problems = append(problems, fmt.Sprintf("activity_mix[%q]: no positive study activity share", key))
+		}
+	}
+	return problems
+}
+
+// studyActivity picks the activity for a study session of the given length
+// that starts at the chapter's current progress. The mix is laid out over the
+// chapter's hours in the order of studyActivities (read, then practise, then
+// past papers) and the session takes the activity at its midpoint. Invalid
+// shares are ignored.
+func studyActivity(mix map[string]float64, wl ChapterWorkload, duration float64) string {
+	total := 0.0
+	for _, activity := range studyActivities {
+		total += math.Max(0, mix[activity])
+	}
+	if total <= 0 || wl.InitialTotalTime <= 0 {
+		mix, total = DEFAULT_ACTIVITY_MIX, 1
+	}
+	midpoint := (wl.InitialTotalTime - wl.RemainingTime + duration/2) / math.Max(wl.InitialTotalTime, 0.001)
+	reached, last := 0.0, ActivityTheory
+	for _, activity := range studyActivities {
+		share := math.Max(0, mix[activity]) / total
+		if share <= 0 {
 			continue
 		}
+		reached += share
+		last = activity
+		if midpoint < reached {
+			return activity
+		}
+	}
+	return last
+}
 
-		sessionIndex, err := strconv.Atoi(input)
-		if err != nil || sessionIndex < 1 || sessionIndex > len(sessions) {
-			fmt.Println("[ERROR] Invalid input. Please enter a valid session number or command ('m', 's', 'q').")
+// ------------------ Subject Rotation ------------------
+
+const (
+	DEFAULT_SUBJECTS_PER_DAY  = 2
+	DEFAULT_REPEAT_AFTER_DAYS = 2 // i.e. never on two days in a row
+	ROTATION_ISSUE_LIMIT      = 5 // issues printed after a regeneration
+)
+
+// RotationPolicy decides which subjects get study sessions on a day. Zero
+// values mean the defaults: two subjects a day, preferably none studied the
+// day before. Only an explicit repeat_after_days is reported when it has to be
+// broken, since three subjects at two a day cannot always avoid a repeat.
+type RotationPolicy struct {
+	SubjectsPerDay    int                 `json:"subjects_per_day,omitempty"`
+	RepeatAfterDays   int                 `json:"repeat_after_days,omitempty"`   // 1 = a subject may come up every day
+	WeeklyHourTargets map[string]float64  `json:"weekly_hour_targets,omitempty"` // subject -> hrs per Monday-Sunday week
+	FixedWeekdays     map[string][]string `json:"fixed_weekdays,omitempty"`      // weekday -> subjects studied only on it
+}
+
+func (p RotationPolicy) subjectsPerDay() int {
+	if p.SubjectsPerDay > 0 {
+		return p.SubjectsPerDay
+	}
+	return DEFAULT_SUBJECTS_PER_DAY
+}
+
+func (p RotationPolicy) repeatAfterDays() int {
+	if p.RepeatAfterDays > 0 {
+		return p.RepeatAfterDays
+	}
+	return DEFAULT_REPEAT_AFTER_DAYS
+}
+
+// fixedDays maps every subject with fixed weekdays to those weekdays. Names
+// that are not weekdays are left out; validateRotationPolicy reports them.
+func (p RotationPolicy) fixedDays() map[string][]time.Weekday {
+	fixed := map[string][]time.Weekday{}
+	for name, subjects := range p.FixedWeekdays {
+		day, ok := parseWeekday(name)
+		if !ok {
 			continue
 		}
+		for _, subject := range subjects {
+			fixed[subject] = append(fixed[subject], day)
+		}
+	}
+	return fixed
+}
 
-		sessionIdx := sessionIndex - 1
-		session := &sessions[sessionIdx]
+// weeklyStudyDays is the number of weekdays that are not c's weekly rest day.
+// Calendar overrides are left out: they move single dates, not the week.
+func weeklyStudyDays(c Config) int {
+	days := 0
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if day != c.WeeklyRestDay {
+			days++
+		}
+	}
+	return days
+}
 
-		if session.Status != "Pending" {
-			fmt.Printf("[INFO] Session is already %s. Select another.\n", session.Status)
+// validateRotationPolicy lists the ways the configured policy is invalid or
+// cannot be satisfied by the syllabus and the daily hours.
+func validateRotationPolicy(c Config) []string {
+	p := c.Rotation
+	var problems []string
+	var subjects []string
+	for _, wl := range c.InitialWorkload {
+		if !contains(subjects, wl.Subject) {
+			subjects = append(subjects, wl.Subject)
+		}
+	}
+	if p.SubjectsPerDay < 0 || p.RepeatAfterDays < 0 {
+		problems = append(problems, "subjects_per_day and repeat_after_days cannot be negative")
+	}
+	if n := p.subjectsPerDay(); n > len(subjects) {
+		problems = append(problems, fmt.Sprintf("subjects_per_day is %d but the syllabus has only %d subjects", n, len(subjects)))
+	}
+
+	fixed := p.fixedDays()
+	var weekdayNames []string
+	for name := range p.FixedWeekdays {
+		weekdayNames = append(weekdayNames, name)
+	}
+	sort.Strings(weekdayNames)
+	for _, name := range weekdayNames {
+		day, ok := parseWeekday(name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("fixed_weekdays: %q is not a weekday", name))
 			continue
 		}
+		if day == c.WeeklyRestDay {
+			problems = append(problems, fmt.Sprintf("fixed_weekdays: %s is the weekly rest day, so %s is never studied then", day, strings.Join(p.FixedWeekdays[name], ", ")))
+		}
+		if len(p.FixedWeekdays[name]) > p.subjectsPerDay() {
+			problems = append(problems, fmt.Sprintf("fixed_weekdays: %d subjects on %s but only %d subjects per day", len(p.FixedWeekdays[name]), day, p.subjectsPerDay()))
+		}
+		for _, subject := range p.FixedWeekdays[name] {
+			if !contains(subjects, subject) {
+				problems = append(problems, fmt.Sprintf("fixed_weekdays: unknown subject %q", subject))
+			}
+		}
+	}
 
-		finished, updatedSessions := runStudyTimer(sessions, sessionIdx, 0, realToday)
-		sessions = updatedSessions
+	var targetSubjects []string
+	total := 0.0
+	for subject, hours := range p.WeeklyHourTargets {
+		targetSubjects = append(targetSubjects, subject)
+		total += hours
+	}
+	sort.Strings(targetSubjects)
+	for _, subject := range targetSubjects {
+		if !contains(subjects, subject) {
+			problems = append(problems, fmt.Sprintf("weekly_hour_targets: unknown subject %q", subject))
+		}
+		if days, ok := fixed[subject]; ok && p.WeeklyHourTargets[subject] > float64(len(days))*c.DailyStudyHrs {
+			problems = append(problems, fmt.Sprintf("weekly_hour_targets: %s is fixed to %d day(s) a week, too few for %.1f hrs", subject, len(days), p.WeeklyHourTargets[subject]))
+		}
+	}
+	if weekly := float64(weeklyStudyDays(c)) * math.Max(0, c.DailyStudyHrs-float64(c.DailyBufferMins)/60.0); total > weekly+0.001 {
+		problems = append(problems, fmt.Sprintf("weekly_hour_targets add up to %.1f hrs but a week has %.1f study hrs", total, weekly))
+	}
 
-		if finished && (session.Type == "Study" || session.Type == "Revision") && session.Status == "Completed" {
-			runBreakTimer(BREAK_MINUTES)
+	free := 0
+	for _, subject := range subjects {
+		if _, ok := fixed[subject]; !ok {
+			free++
 		}
 	}
+	if need := p.subjectsPerDay() * p.RepeatAfterDays; free < need && p.RepeatAfterDays > 1 {
+		problems = append(problems, fmt.Sprintf("%d subjects a day with repeat_after_days %d needs %d freely rotating subjects, there are %d", p.subjectsPerDay(), p.repeatAfterDays(), need, free))
+	}
+	return problems
+}
 
-	fmt.Println("\n[INFO] Exiting timer. Any unfinished session progress has been saved.")
+// subjectRotation applies the policy while planSchedule walks the days in
+// order, and collects the days on which it had to be relaxed.
+type subjectRotation struct {
+	policy      RotationPolicy
+	start       time.Time
+	fixed       map[string][]time.Weekday
+	lastStudied map[string]time.Time
+	week        time.Time
+	weekHours   map[string]float64
+	Issues      []string
 }
 
-func runFullReport() {
-	rawConfig = loadConfig()
-	fmt.Println("\n--- FULL PROGRESS REPORT ---")
-	state, _ := loadState()
+func startOfWeek(date time.Time) time.Time {
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
 
-	allChapters := calculateQuotas(&state)
+// newSubjectRotation picks up the history the policy needs from the plans
+// before start: when each subject was last studied and this week's hours.
+func newSubjectRotation(policy RotationPolicy, start time.Time, lastSubjects []string) *subjectRotation {
+	r := &subjectRotation{
+		policy:      policy,
+		start:       start,
+		fixed:       policy.fixedDays(),
+		lastStudied: map[string]time.Time{},
+		week:        startOfWeek(start),
+		weekHours:   map[string]float64{},
+	}
+	for _, subject := range lastSubjects {
+		r.lastStudied[subject] = start.AddDate(0, 0, -1)
+	}
+	from := start.AddDate(0, 0, 1-policy.repeatAfterDays())
+	if r.week.Before(from) {
+		from = r.week
+	}
+	for date := from; date.Before(start); date = date.AddDate(0, 0, 1) {
+		sessions, _ := readDayPlan(date)
+		r.record(date, sessions)
+	}
+	return r
+}
 
-	totalWorkload := state.TotalWeightedWorkload
-	totalRemainingHrs := state.TotalRemainingTime
-	netStudyDays := state.NetStudyDays
-	dailyQuota := state.DailyQuotaWT
+// record counts a planned day towards the gaps and weekly hours.
+func (r *subjectRotation) record(date time.Time, sessions []Session) {
+	for _, s := range sessions {
+		if (s.Type != "Study" && s.Type != "Revision") || s.Status == "Missed" {
+			continue
+		}
+		if last, ok := r.lastStudied[s.Subject]; !ok || date.After(last) {
+			r.lastStudied[s.Subject] = date
+		}
+		if !date.Before(r.week) {
+			r.weekHours[s.Subject] += s.Duration
+		}
+	}
+}
 
-	if len(state.Workload) == 0 {
-		fmt.Println("[INFO] No workload initialized. Please run option [3] RE-GENERATE first.")
+func (r *subjectRotation) issue(date time.Time, format string, a ...interface{}) {
+	r.Issues = append(r.Issues, date.Format(TIME_FORMAT)+": "+fmt.Sprintf(format, a...))
+}
+
+// startDay rolls the week over, noting subjects that missed their target.
+// The week planning started in is not judged, as it began before the plan.
+func (r *subjectRotation) startDay(date time.Time) {
+	week := startOfWeek(date)
+	if !week.After(r.week) {
 		return
 	}
-
-	if totalWorkload < 0.001 {
-		today := time.Now().Truncate(24 * time.Hour)
-		if len(getDueRevisions(state, today)) == 0 {
-			fmt.Printf("🎯 Syllabus Target Date: %s (Net Study Days Remaining: %d)\n", rawConfig.SyllabusEndDate, netStudyDays)
-			fmt.Println("⏳ Total Remaining Workload: 0.00 WT (0.0 Study Hrs)")
-			fmt.Println("📅 Required Daily Quota: 0.00 WT (Weighted Time)")
-			fmt.Println("-----------------------------------------------------------------")
-			fmt.Println("🎉 All initial study and scheduled revisions are complete!")
-			fmt.Println("-----------------------------------------------------------------")
-			return
+	if r.week.Before(r.start) {
+		r.week = week
+		r.weekHours = map[string]float64{}
+		return
+	}
+	var subjects []string
+	for subject := range r.policy.WeeklyHourTargets {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	for _, subject := range subjects {
+		if target := r.policy.WeeklyHourTargets[subject]; r.weekHours[subject] < target-0.001 {
+			r.issue(r.week, "week fell short of the %s target (%.1f of %.1f hrs)", subject, r.weekHours[subject], target)
 		}
 	}
+	r.week = week
+	r.weekHours = map[string]float64{}
+}
 
-	fmt.Printf("🎯 Syllabus Target Date: %s (Net Study Days Remaining: %d)\n", rawConfig.SyllabusEndDate, netStudyDays)
-	fmt.Printf("⏳ Total Remaining Workload: %.2f WT (%.1f Study Hrs)\n", totalWorkload, totalRemainingHrs)
-	fmt.Printf("📅 Required Daily Quota: %.2f WT (Weighted Time)\n", dailyQuota)
-	fmt.Println("-----------------------------------------------------------------")
+func (r *subjectRotation) recentlyStudied(subject string, date time.Time) (int, bool) {
+	last, ok := r.lastStudied[subject]
+	if !ok {
+		return 0, false
+	}
+	gap := int(date.Sub(last).Hours() / 24)
+	return gap, gap < r.policy.repeatAfterDays()
+}
 
-	var incompleteStudyChapters, revisionDueChapters, nextRevisionChapters, completedChapters []ChapterWorkload
-	today := time.Now().Truncate(24 * time.Hour)
+func (r *subjectRotation) targetMet(subject string) bool {
+	target, ok := r.policy.WeeklyHourTargets[subject]
+	return ok && r.weekHours[subject] >= target-0.001
+}
 
-	for _, wl := range allChapters {
-		if !wl.IsStudyCompleted && wl.RemainingTime > 0.001 {
-			incompleteStudyChapters = append(incompleteStudyChapters, wl)
-		} else if wl.IsStudyCompleted && wl.RevisionCount < MAX_REVISIONS && wl.NextRevisionDate != "" {
-			revDate, _ := time.Parse(TIME_FORMAT, wl.NextRevisionDate)
-			if !revDate.After(today) {
-				revisionDueChapters = append(revisionDueChapters, wl)
+// targetDeficit is how many hours subject still needs to reach its weekly
+// target; subjects without a target have none.
+func (r *subjectRotation) targetDeficit(subject string) float64 {
+	target, ok := r.policy.WeeklyHourTargets[subject]
+	if !ok {
+		return 0
+	}
+	return math.Max(0, target-r.weekHours[subject])
+}
+
+// pick chooses the subjects to study on date from candidates (the subjects
+// with study left, highest priority first). Subjects fixed to the weekday come
+// first; the rest is drawn from subjects that are neither fixed to other days,
+// studied too recently nor already at their weekly target, those furthest
+// below their target first and at random otherwise. Only if that leaves slots
+// empty is the policy relaxed, in priority order.
+func (r *subjectRotation) pick(date time.Time, candidates []string) []string {
+	n := r.policy.subjectsPerDay()
+	var chosen, eligible, relaxed, fixedElsewhere []string
+	for _, subject := range candidates {
+		if days, ok := r.fixed[subject]; ok {
+			if containsWeekday(days, date.Weekday()) {
+				chosen = append(chosen, subject)
 			} else {
-				nextRevisionChapters = append(nextRevisionChapters, wl)
+				fixedElsewhere = append(fixedElsewhere, subject)
 			}
-		} else if wl.IsStudyCompleted && wl.RevisionCount >= MAX_REVISIONS {
-			completedChapters = append(completedChapters, wl)
+			continue
+		}
+		if _, recent := r.recentlyStudied(subject, date); recent || r.targetMet(subject) {
+			relaxed = append(relaxed, subject)
+		} else {
+			eligible = append(eligible, subject)
 		}
 	}
-
-	sort.Slice(incompleteStudyChapters, func(i, j int) bool { return incompleteStudyChapters[i].PriorityScore > incompleteStudyChapters[j].PriorityScore })
-	sort.Slice(revisionDueChapters, func(i, j int) bool { return revisionDueChapters[i].PriorityScore > revisionDueChapters[j].PriorityScore })
-	sort.Slice(nextRevisionChapters, func(i, j int) bool {
-		dateI, _ := time.Parse(TIME_FORMAT, nextRevisionChapters[i].NextRevisionDate)
-		dateJ, _ := time.Parse(TIME_FORMAT, nextRevisionChapters[j].NextRevisionDate)
-		return dateI.Before(dateJ)
+	if len(chosen) > n {
+		chosen = chosen[:n]
+	}
+	randSource.Shuffle(len(eligible), func(i, j int) { eligible[i], eligible[j] = eligible[j], eligible[i] })
+	sort.SliceStable(eligible, func(i, j int) bool {
+		return r.targetDeficit(eligible[i]) > r.targetDeficit(eligible[j])+0.001
 	})
+	for _, subject := range eligible {
+		if len(chosen) == n {
+			break
+		}
+		chosen = append(chosen, subject)
+	}
+	for _, subject := range relaxed {
+		if len(chosen) == n {
+			break
+		}
+		chosen = append(chosen, subject)
+		if gap, recent := r.recentlyStudied(subject, date); recent {
+			if r.policy.RepeatAfterDays == 0 {
+				continue
+			}
+			r.issue(date, "%s repeated after %d day(s), policy asks for %d", subject, gap, r.policy.repeatAfterDays())
+		} else {
+			r.issue(date, "%s studied beyond its %.1f-hr weekly target", subject, r.policy.WeeklyHourTargets[subject])
+		}
+	}
+	if len(chosen) == 0 && len(fixedElsewhere) > 0 {
+		chosen = append(chosen, fixedElsewhere[0])
+		r.issue(date, "only %s has study left; studied outside its fixed weekdays", fixedElsewhere[0])
+	}
+	return chosen
+}
 
-	fmt.Println("\n📚 PENDING INITIAL STUDY (Sorted by Priority)")
-	if len(incompleteStudyChapters) == 0 {
-		fmt.Println("  -> All initial study complete! Time for revision phase.")
-	} else {
-		for _, wl := range incompleteStudyChapters {
-			fmt.Printf("  - [Prio: %.2f | %.1f hrs left] %s: %s (Diff: %.1f)\n", wl.PriorityScore, wl.RemainingTime, wl.Subject, wl.Chapter, wl.Difficulty)
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// printRotationIssues prints the first ROTATION_ISSUE_LIMIT issues.
+func printRotationIssues(issues []string) {
+	if len(issues) == 0 {
+		return
+	}
+	fmt.Fprintf(logOut, ColorYellow+"[ROTATION] The subject rotation policy was relaxed %d time(s):"+ColorReset+"\n", len(issues))
+	for i, issue := range issues {
+		if i == ROTATION_ISSUE_LIMIT {
+			fmt.Fprintf(logOut, "  ... and %d more.\n", len(issues)-ROTATION_ISSUE_LIMIT)
+			break
+		}
+		fmt.Fprintln(logOut, "  - " + issue)
+	}
+}
+
+// ------------------ Daily Timetable ------------------
+
+const (
+	TIMETABLE_DEFAULT_KEY   = "default" // timetable entry for weekdays without one
+	TIMETABLE_MIN_PART_MINS = 15        // shortest piece a session is split into
+)
+
+// TimeBlock is a stretch of a day in minutes after midnight, End exclusive.
+type TimeBlock struct {
+	Start, End int
+}
+
+func (b TimeBlock) Minutes() int { return b.End - b.Start }
+
+// parseClock reads "HH:MM" (00:00 to 24:00) as minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		if strings.TrimSpace(s) == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("invalid time %q: use HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseTimeBlock reads a block written as "HH:MM-HH:MM".
+func parseTimeBlock(s string) (TimeBlock, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return TimeBlock{}, fmt.Errorf("invalid time block %q: use HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return TimeBlock{}, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return TimeBlock{}, err
+	}
+	if end <= start {
+		return TimeBlock{}, fmt.Errorf("time block %q ends before it starts", s)
+	}
+	return TimeBlock{Start: start, End: end}, nil
+}
+
+// timetableBlocks returns the study blocks of a weekday in order, from its own
+// timetable entry or else the default one. ok is false without a timetable
+// for the day, in which case sessions get no clock times. Invalid and
+// overlapping blocks are left out; validateTimetable reports them.
+func timetableBlocks(c Config, weekday time.Weekday) (blocks []TimeBlock, ok bool) {
+	var specs []string
+	for key, value := range c.Timetable {
+		if day, isDay := parseWeekday(key); isDay && day == weekday {
+			specs, ok = value, true
+		}
+	}
+	if !ok {
+		specs, ok = c.Timetable[TIMETABLE_DEFAULT_KEY]
+	}
+	for _, spec := range specs {
+		if b, err := parseTimeBlock(spec); err == nil {
+			blocks = append(blocks, b)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+	var kept []TimeBlock
+	for _, b := range blocks {
+		if len(kept) == 0 || b.Start >= kept[len(kept)-1].End {
+			kept = append(kept, b)
+		}
+	}
+	return kept, ok
+}
+
+// validateTimetable describes every timetable entry the generator cannot use.
+func validateTimetable(c Config) []string {
+	var keys []string
+	for key := range c.Timetable {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var problems []string
+	for _, key := range keys {
+		if _, ok := parseWeekday(key); !ok && key != TIMETABLE_DEFAULT_KEY {
+			problems = append(problems, fmt.Sprintf("timetable: %q is not a weekday or %q", key, TIMETABLE_DEFAULT_KEY))
+			continue
+		}
+		var blocks []TimeBlock
+		for _, spec := range c.Timetable[key] {
+			b, err := parseTimeBlock(spec)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("timetable[%q]: %v", key, err))
+				continue
+			}
+			blocks = append(blocks, b)
+		}
+		sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+		for i := 1; i < len(blocks); i++ {
+			if blocks[i].Start < blocks[i-1].End {
+				problems = append(problems, fmt.Sprintf("timetable[%q]: %s-%s overlaps the block before it and is ignored",
+					key, formatClock(blocks[i].Start), formatClock(blocks[i].End)))
+			}
+		}
+	}
+	return problems
+}
+
+// timetableStudyHours is how many study hours the timetable leaves on date
+// from the minute `after` on, net of the breaks between sessions and the
+// daily buffer. ok is false when the timetable does not cover the day.
+func timetableStudyHours(date time.Time, after int) (float64, bool) {
+	blocks, ok := timetableBlocks(rawConfig, date.Weekday())
+	if !ok {
+		return 0, false
+	}
+	minutes := 0
+	for _, b := range blocks {
+		minutes += b.End - int(math.Max(float64(b.Start), math.Min(float64(after), float64(b.End))))
+	}
+	sessionMins := math.Max(rawConfig.MaxSessionHrs*60, 1)
+	studyShare := sessionMins / (sessionMins + BREAK_MINUTES)
+	return math.Max(0, float64(minutes)/60*studyShare-float64(rawConfig.DailyBufferMins)/60), true
+}
+
+func breakSession(start int) Session {
+	return Session{
+		Subject:  "Break",
+		Chapter:  fmt.Sprintf("%d min break", BREAK_MINUTES),
+		Duration: BREAK_MINUTES / 60.0,
+		Type:     "Break",
+		Status:   "Pending",
+		Start:    formatClock(start),
+		End:      formatClock(start + BREAK_MINUTES),
+	}
+}
+
+// scheduleTimes gives the pending sessions of a day plan clock times inside
+// the day's timetable blocks, each in the first gap with room for it and the
+// day sorted by time afterwards, with a break between study
+// sessions that follow each other in one block. A session that no longer fits
+// in one piece is split over the remaining gaps. Worked sessions keep their
+// times and block them out, as does the part of today that is already over.
+// Breaks from an earlier plan are replaced. It returns the timed plan and the
+// hours that did not fit, which are left without times.
+func scheduleTimes(date time.Time, sessions []Session, now time.Time) ([]Session, float64) {
+	blocks, ok := timetableBlocks(rawConfig, date.Weekday())
+	if !ok {
+		return sessions, 0
+	}
+	var busy []TimeBlock
+	if y, m, d := now.Date(); date.Equal(time.Date(y, m, d, 0, 0, 0, 0, date.Location())) {
+		busy = append(busy, TimeBlock{Start: 0, End: now.Hour()*60 + now.Minute()})
+	}
+	for _, s := range sessions {
+		if s.Status != "Pending" && s.Start != "" {
+			start, err1 := parseClock(s.Start)
+			end, err2 := parseClock(s.End)
+			if err1 == nil && err2 == nil {
+				busy = append(busy, TimeBlock{Start: start, End: end})
+			}
+		}
+	}
+	// free is what is left of the blocks once busy times are cut out.
+	var free []TimeBlock
+	for _, b := range blocks {
+		parts := []TimeBlock{b}
+		for _, x := range busy {
+			var next []TimeBlock
+			for _, p := range parts {
+				if x.End <= p.Start || x.Start >= p.End {
+					next = append(next, p)
+					continue
+				}
+				if x.Start > p.Start {
+					next = append(next, TimeBlock{Start: p.Start, End: x.Start})
+				}
+				if x.End < p.End {
+					next = append(next, TimeBlock{Start: x.End, End: p.End})
+				}
+			}
+			parts = next
+		}
+		free = append(free, parts...)
+	}
+
+	// Each gap fills from its start; a study session right after another one
+	// in the same gap gets a break first.
+	cursor := make([]int, len(free))
+	lastStudy := make([]bool, len(free))
+	for i := range free {
+		cursor[i] = free[i].Start
+	}
+	var timed []Session
+	unplaced := 0.0
+	for _, s := range sessions {
+		if s.Type == "Break" && s.Status == "Pending" {
+			continue
+		}
+		if s.Status != "Pending" || s.Type == "Rest" {
+			timed = append(timed, s)
+			continue
+		}
+		s.Start, s.End = "", ""
+		isStudy := s.Type == "Study" || s.Type == "Revision" || s.Type == "Mock"
+		need := int(math.Round(s.Duration * 60))
+
+		room := func(i int) (start, pause, minutes int) {
+			start = cursor[i]
+			if isStudy && lastStudy[i] {
+				pause = BREAK_MINUTES
+			}
+			return start, pause, free[i].End - start - pause
+		}
+		place := func(i, minutes int, part Session) {
+			start, pause, _ := room(i)
+			if pause > 0 {
+				timed = append(timed, breakSession(start))
+			}
+			part.Start, part.End = formatClock(start+pause), formatClock(start+pause+minutes)
+			timed = append(timed, part)
+			cursor[i], lastStudy[i] = start+pause+minutes, isStudy
+		}
+
+		placed := false
+		for i := range free {
+			if _, _, minutes := room(i); minutes >= need {
+				place(i, need, s)
+				placed = true
+				break
+			}
+		}
+		// A session too long for any gap left is split over the gaps.
+		for i := 0; i < len(free) && !placed && need > 0; i++ {
+			_, _, minutes := room(i)
+			if minutes < TIMETABLE_MIN_PART_MINS {
+				continue
+			}
+			part := s
+			minutes = int(math.Min(float64(minutes), float64(need)))
+			part.Duration = float64(minutes) / 60
+			place(i, minutes, part)
+			need -= minutes
+		}
+		if !placed && need > 0 {
+			rest := s
+			rest.Duration = float64(need) / 60
+			unplaced += rest.Duration
+			timed = append(timed, rest)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool {
+		if timed[i].Start == "" || timed[j].Start == "" {
+			return timed[i].Start != "" && timed[j].Start == ""
+		}
+		return timed[i].Start < timed[j].Start
+	})
+	return timed, unplaced
+}
+
+// sessionTime is a session's clock time, "HH:MM-HH:MM ", or "" if it has none.
+func sessionTime(s Session) string {
+	if s.Start == "" {
+		return ""
+	}
+	return s.Start + "-" + s.End + " "
+}
+
+// dueSession picks the session to propose at now: the pending study or
+// revision session whose time slot contains now, else the next one to start,
+// else (when it has no times) the first pending one. It returns -1 when
+// nothing is pending.
+func dueSession(sessions []Session, now time.Time) int {
+	minute := now.Hour()*60 + now.Minute()
+	next, nextStart, first := -1, 24*60+1, -1
+	for i, s := range sessions {
+		if s.Status != "Pending" || (s.Type != "Study" && s.Type != "Revision") {
+			continue
+		}
+		if first == -1 {
+			first = i
+		}
+		start, err1 := parseClock(s.Start)
+		end, err2 := parseClock(s.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if start <= minute && minute < end {
+			return i
+		}
+		if start > minute && start < nextStart {
+			next, nextStart = i, start
+		}
+	}
+	if next != -1 {
+		return next
+	}
+	return first
+}
+
+// ------------------ Calendar Overrides ------------------
+
+// Kinds of calendar override. Each kind has a default share of
+// daily_study_hrs for the day; an override's Hours replaces it.
+const (
+	CalendarRest   = "rest"   // holiday or extra rest day
+	CalendarSick   = "sick"   // sick day
+	CalendarHalf   = "half"   // half day
+	CalendarExam   = "exam"   // exam at school: short revision only
+	CalendarTravel = "travel" // travel day
+)
+
+var calendarKindShares = map[string]float64{
+	CalendarRest:   0,
+	CalendarSick:   0,
+	CalendarHalf:   0.5,
+	CalendarExam:   0.25,
+	CalendarTravel: 0.25,
+}
+
+// CalendarOverride changes the study hours of one date, or of the dates from
+// Date to To. Where overrides overlap the last one in the config wins, and an
+// override also replaces the weekly rest day.
+type CalendarOverride struct {
+	Date  string   `json:"date"`
+	To    string   `json:"to,omitempty"` // last day of a range, inclusive
+	Kind  string   `json:"kind"`
+	Hours *float64 `json:"hours,omitempty"` // study hours that day; default from Kind
+	Note  string   `json:"note,omitempty"`
+}
+
+// Covers reports whether the override applies on date.
+func (o CalendarOverride) Covers(date time.Time) bool {
+	day := date.Format(TIME_FORMAT)
+	to := o.To
+	if to == "" {
+		to = o.Date
+	}
+	return day >= o.Date && day <= to
+}
+
+// StudyHours is the daily study time the override leaves.
+func (o CalendarOverride) StudyHours(dailyStudyHrs float64) float64 {
+	if o.Hours != nil {
+		return math.Max(0, *o.Hours)
+	}
+	return dailyStudyHrs * calendarKindShares[o.Kind]
+}
+
+func (o CalendarOverride) String() string {
+	when := o.Date
+	if o.To != "" && o.To != o.Date {
+		when += " to " + o.To
+	}
+	s := fmt.Sprintf("%s: %s, %.1f study hrs", when, o.Kind, o.StudyHours(rawConfig.DailyStudyHrs))
+	if o.Note != "" {
+		s += " (" + o.Note + ")"
+	}
+	return s
+}
+
+// calendarOverrideOn returns the override in effect on date, if any.
+// Entries of an unknown kind are ignored; validateCalendar reports them.
+func calendarOverrideOn(date time.Time) (CalendarOverride, bool) {
+	for i := len(rawConfig.Calendar) - 1; i >= 0; i-- {
+		if _, known := calendarKindShares[rawConfig.Calendar[i].Kind]; known && rawConfig.Calendar[i].Covers(date) {
+			return rawConfig.Calendar[i], true
+		}
+	}
+	return CalendarOverride{}, false
+}
+
+// studyHoursOn is the daily study time on date before the buffer: from the
+// calendar if it has an override for the day, else none on the weekly rest
+// day and daily_study_hrs otherwise.
+func studyHoursOn(date time.Time) float64 {
+	return studyHoursWith(date, rawConfig.DailyStudyHrs)
+}
+
+// studyHoursWith is studyHoursOn as it would be with dailyHrs as
+// daily_study_hrs.
+func studyHoursWith(date time.Time, dailyHrs float64) float64 {
+	if o, ok := calendarOverrideOn(date); ok {
+		return o.StudyHours(dailyHrs)
+	}
+	if date.Weekday() == rawConfig.WeeklyRestDay {
+		return 0
+	}
+	return dailyHrs
+}
+
+func isStudyDay(date time.Time) bool {
+	return studyHoursOn(date) > 0.001
+}
+
+// validateCalendar describes every calendar entry the scheduler cannot use;
+// entries with a bad date or kind never match any day.
+func validateCalendar(c Config) []string {
+	var problems []string
+	for i, o := range c.Calendar {
+		from, err := time.Parse(TIME_FORMAT, o.Date)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("calendar[%d]: invalid date %q", i, o.Date))
+			continue
+		}
+		if o.To != "" {
+			if to, err := time.Parse(TIME_FORMAT, o.To); err != nil || to.Before(from) {
+				problems = append(problems, fmt.Sprintf("calendar[%d]: %q is not a date on or after %s", i, o.To, o.Date))
+			}
+		}
+		if _, ok := calendarKindShares[o.Kind]; !ok {
+			problems = append(problems, fmt.Sprintf("calendar[%d]: unknown kind %q (use rest, sick, half, exam or travel)", i, o.Kind))
+		}
+		if o.Hours != nil && *o.Hours < 0 {
+			problems = append(problems, fmt.Sprintf("calendar[%d]: hours cannot be negative", i))
+		}
+	}
+	return problems
+}
+
+// upcomingOverrides lists the calendar entries that still have days on or
+// after from, in date order.
+func upcomingOverrides(c Config, from time.Time) []CalendarOverride {
+	day := from.Format(TIME_FORMAT)
+	upcoming := []CalendarOverride{}
+	for _, o := range c.Calendar {
+		last := o.To
+		if last == "" {
+			last = o.Date
+		}
+		if last >= day {
+			upcoming = append(upcoming, o)
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].Date < upcoming[j].Date })
+	return upcoming
+}
+
+// ------------------ Feasibility ------------------
+
+// FEASIBILITY_STEP_HRS is the granularity of suggested extra daily hours.
+const FEASIBILITY_STEP_HRS = 0.25
+
+// SubjectShortfall is the study time of one subject that does not fit before
+// the syllabus end date, with the chapters it belongs to.
+type SubjectShortfall struct {
+	Subject  string   `json:"subject"`
+	Hours    float64  `json:"hours"`
+	Chapters []string `json:"chapters"` // chapter IDs, lowest priority last
+}
+
+// FeasibilityOption is one concrete change that would close a shortfall.
+// Kind is extra_hours, drop_rest_day or move_end_date.
+type FeasibilityOption struct {
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Sufficient  bool   `json:"sufficient"` // closes the whole shortfall on its own
+}
+
+// Feasibility compares the study time still needed with the time left until
+// the syllabus end date. Revision hours only count revisions already due by
+// then, so the check is a lower bound on the real load.
+type Feasibility struct {
+	From                string              `json:"from"`
+	SyllabusEndDate     string              `json:"syllabus_end_date"`
+	StudyDays           int                 `json:"study_days"`
+	RestDays            int                 `json:"rest_days"`
+	CapacityHours       float64             `json:"capacity_hours"`
+	StudyHours          float64             `json:"study_hours"`
+	RevisionHours       float64             `json:"revision_hours"`
+	Feasible            bool                `json:"feasible"`
+	ProjectedCompletion string              `json:"projected_completion"` // empty if there is no study capacity at all
+	ShortfallHours      float64             `json:"shortfall_hours"`
+	Shortfall           []SubjectShortfall  `json:"shortfall"`
+	Options             []FeasibilityOption `json:"options"`
+}
+
+// dailyStudyCapacity is the study time a day offers: none on a rest day,
+// otherwise the day's hours (see studyHoursOn) less the buffer.
+func dailyStudyCapacity(date time.Time) float64 {
+	return studyCapacity(date, studyHoursOn(date))
+}
+
+// studyCapacity is the study time a day with the given hours offers: the
+// hours less the buffer, capped by the day's timetable if there is one.
+func studyCapacity(date time.Time, hours float64) float64 {
+	if hours <= 0.001 {
+		return 0
+	}
+	capacity := math.Max(0, hours-float64(rawConfig.DailyBufferMins)/60.0)
+	if hours, ok := timetableStudyHours(date, 0); ok {
+		capacity = math.Min(capacity, hours)
+	}
+	return capacity
+}
+
+// analyzeFeasibility checks whether the remaining study and due revisions fit
+// into the days from `from` to the syllabus end date. Chapters are filled in
+// priority order, so the shortfall lands on the chapters the generator would
+// leave unfinished.
+func analyzeFeasibility(state ScheduleState, from time.Time) Feasibility {
+	syllabusEndDate, _ := time.Parse(TIME_FORMAT, rawConfig.SyllabusEndDate)
+	f := Feasibility{
+		From:            from.Format(TIME_FORMAT),
+		SyllabusEndDate: rawConfig.SyllabusEndDate,
+		Shortfall:       []SubjectShortfall{},
+		Options:         []FeasibilityOption{},
+	}
+
+	for d := from; !d.After(syllabusEndDate); d = d.AddDate(0, 0, 1) {
+		if capacity := dailyStudyCapacity(d); capacity > 0 {
+			f.StudyDays++
+			f.CapacityHours += capacity
+		} else {
+			f.RestDays++
+		}
+	}
+
+	for _, id := range sortedChapterIDs(state.Workload) {
+		wl := state.Workload[id]
+		if wl.IsStudyCompleted && hasRevisionsLeft(wl) {
+			revDate, err := time.Parse(TIME_FORMAT, wl.NextRevisionDate)
+			if err == nil && !revDate.After(syllabusEndDate) {
+				f.RevisionHours += REVISION_TIME_HRS
+			}
+		}
+	}
+
+	available := f.CapacityHours - f.RevisionHours
+	shortBySubject := map[string]*SubjectShortfall{}
+	var subjects []string
+	for _, wl := range prioritizeChapters(calculateQuotas(&state)) {
+		f.StudyHours += wl.RemainingTime
+		fits := math.Max(0, math.Min(available, wl.RemainingTime))
+		available -= fits
+		if short := wl.RemainingTime - fits; short > 0.001 {
+			s, ok := shortBySubject[wl.Subject]
+			if !ok {
+				s = &SubjectShortfall{Subject: wl.Subject}
+				shortBySubject[wl.Subject] = s
+				subjects = append(subjects, wl.Subject)
+			}
+			s.Hours += short
+			s.Chapters = append(s.Chapters, wl.ID)
+			f.ShortfallHours += short
+		}
+	}
+	for _, subject := range subjects {
+		f.Shortfall = append(f.Shortfall, *shortBySubject[subject])
+	}
+	sort.SliceStable(f.Shortfall, func(i, j int) bool { return f.Shortfall[i].Hours > f.Shortfall[j].Hours })
+
+	f.Feasible = f.ShortfallHours <= 0.001
+	f.ProjectedCompletion = projectCompletion(from, f.StudyHours+f.RevisionHours)
+	if !f.Feasible {
+		f.Options = feasibilityOptions(f)
+	}
+	return f
+}
+
+// projectCompletion walks the calendar from `from` until the configured days
+// have supplied the required hours. It gives up after ten years.
+func projectCompletion(from time.Time, required float64) string {
+	limit := from.AddDate(10, 0, 0)
+	for d := from; d.Before(limit); d = d.AddDate(0, 0, 1) {
+		required -= dailyStudyCapacity(d)
+		if required <= 0.001 {
+			return d.Format(TIME_FORMAT)
+		}
+	}
+	return ""
+}
+
+func feasibilityOptions(f Feasibility) []FeasibilityOption {
+	var options []FeasibilityOption
+	from, _ := time.Parse(TIME_FORMAT, f.From)
+	end, _ := time.Parse(TIME_FORMAT, f.SyllabusEndDate)
+	// A timetable caps every day, so more daily hours may gain nothing.
+	if f.StudyDays > 0 {
+		extra := math.Ceil(f.ShortfallHours/float64(f.StudyDays)/FEASIBILITY_STEP_HRS) * FEASIBILITY_STEP_HRS
+		gained := 0.0
+		for d := from; !d.After(end); d = d.AddDate(0, 0, 1) {
+			gained += studyCapacity(d, studyHoursWith(d, rawConfig.DailyStudyHrs+extra)) - dailyStudyCapacity(d)
+		}
+		if gained > 0.001 {
+			options = append(options, FeasibilityOption{
+				Kind:        "extra_hours",
+				Description: fmt.Sprintf("Study %.2f more hrs/day (daily_study_hrs %.2f -> %.2f)", extra, rawConfig.DailyStudyHrs, rawConfig.DailyStudyHrs+extra),
+				Sufficient:  gained >= f.ShortfallHours-0.001,
+			})
+		}
+	}
+	// Only weekly rest days count here; calendar rest days are fixed.
+	weeklyRestDays := 0
+	gained := 0.0
+	for d := from; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if _, overridden := calendarOverrideOn(d); d.Weekday() == rawConfig.WeeklyRestDay && !overridden {
+			weeklyRestDays++
+			gained += studyCapacity(d, rawConfig.DailyStudyHrs)
+		}
+	}
+	if weeklyRestDays > 0 {
+		options = append(options, FeasibilityOption{
+			Kind:        "drop_rest_day",
+			Description: fmt.Sprintf("Study on the %d remaining %ss (+%.1f hrs)", weeklyRestDays, rawConfig.WeeklyRestDay, gained),
+			Sufficient:  gained >= f.ShortfallHours-0.001,
+		})
+	}
+	if f.ProjectedCompletion != "" {
+		projected, _ := time.Parse(TIME_FORMAT, f.ProjectedCompletion)
+		end, _ := time.Parse(TIME_FORMAT, f.SyllabusEndDate)
+		description := fmt.Sprintf("Move syllabus_end_date to %s (%d days later)", f.ProjectedCompletion, int(projected.Sub(end).Hours()/24))
+		if rawConfig.ExamDate != "" && f.ProjectedCompletion > rawConfig.ExamDate {
+			description += ", after the exam date " + rawConfig.ExamDate
+		}
+		options = append(options, FeasibilityOption{Kind: "move_end_date", Description: description, Sufficient: true})
+	}
+	return options
+}
+
+// printFeasibility prints the analysis; the overload warning is the part that
+// matters, so an on-track result is a single line.
+func printFeasibility(w io.Writer, f Feasibility) {
+	required := f.StudyHours + f.RevisionHours
+	if f.Feasible {
+		fmt.Fprintf(w, ColorGreen+"[FEASIBILITY] On track: %.1f of %.1f available study hrs needed by %s (projected completion %s)."+ColorReset+"\n",
+			required, f.CapacityHours, f.SyllabusEndDate, f.ProjectedCompletion)
+		return
+	}
+	fmt.Fprintf(w, ColorRed+"[OVERLOAD] %.1f study hrs do not fit before %s: %.1f needed, %.1f available over %d study days."+ColorReset+"\n",
+		f.ShortfallHours, f.SyllabusEndDate, required, f.CapacityHours, f.StudyDays)
+	if f.ProjectedCompletion != "" {
+		fmt.Fprintf(w, "  Projected completion at the configured hours: %s\n", f.ProjectedCompletion)
+	} else {
+		fmt.Fprintln(w, "  Projected completion: never (no study hours are configured)")
+	}
+	fmt.Fprintln(w, "  Shortfall by subject:")
+	for _, s := range f.Shortfall {
+		fmt.Fprintf(w, "    - %s: %.1f hrs (%s)\n", s.Subject, s.Hours, strings.Join(s.Chapters, ", "))
+	}
+	fmt.Fprintln(w, "  Options:")
+	for _, o := range f.Options {
+		note := ""
+		if !o.Sufficient {
+			note = " - not enough on its own"
+		}
+		fmt.Fprintf(w, "    * %s%s\n", o.Description, note)
+	}
+}
+
+func markMissedSessions() {
+	if n, err := planStore.MigrateAll(); err != nil {
+		fmt.Fprintln(logOut, "[WARN] Could not migrate legacy plan files:", err)
+	} else if n > 0 {
+		fmt.Fprintf(logOut, "[INFO] Migrated %d legacy plan files to schema v%d.\n", n, PLAN_SCHEMA_VERSION)
+	}
+
+	dates, err := planStore.Dates()
+	if err != nil {
+		return
+	}
+
+	today := currentDay()
+
+	for _, planDate := range dates {
+		if !planDate.Before(today) {
+			continue
+		}
+
+		sessions, err := planStore.Load(planDate)
+		if err != nil {
+			continue
+		}
+
+		updated := false
+		for i := range sessions {
+			if sessions[i].Status == "Pending" {
+				sessions[i].Status = "Missed"
+				if sessions[i].Type == "Study" || sessions[i].Type == "Revision" {
+					logSessionEvent(EventMiss, planDate, sessions[i], 0)
+				}
+				updated = true
+			}
+		}
+
+		if updated {
+			writeDayPlan(planDate, sessions)
+		}
+	}
+	fmt.Fprintln(logOut, "[INFO] Marked past pending sessions as Missed.")
+}
+
+
+// updatePerformance scans past schedule files into a per-day ledger and
+// recomputes performance_state.json from it. Each date's tally is replaced,
+// never added to, so running it any number of times gives the same result.
+// With PerformanceWindowDays set, the totals only cover that many recent days.
+func updatePerformance() {
+	perfPath := PERFORMANCE_FILE
+	perf := PerformanceState{}
+	_ = loadJSON(perfPath, &perf)
+	if perf.DailyLedger == nil {
+		perf.DailyLedger = map[string]DayTally{}
+	}
+
+	dates, err := planStore.Dates()
+	if err != nil {
+		fmt.Fprintln(logOut, "[WARN] Could not read schedule directory for performance update:", err)
+		return
+	}
+
+	today := currentDay()
+
+	for _, planDate := range dates {
+		if !planDate.Before(today) {
+			continue
+		}
+
+		sessions, err := readDayPlan(planDate)
+		if err != nil {
+			continue
+		}
+
+		tally := DayTally{}
+		for _, s := range sessions {
+			if s.Type != "Study" && s.Type != "Revision" {
+				continue
+			}
+			tally.Total++
+			switch s.Status {
+			case "Completed":
+				tally.Completed++
+			case "Missed":
+				tally.Missed++
+			}
+		}
+		perf.DailyLedger[planDate.Format(TIME_FORMAT)] = tally
+	}
+
+	perf.recomputeTotals(today, rawConfig.PerformanceWindowDays)
+
+	if err := saveJSON(perfPath, &perf); err != nil {
+		fmt.Fprintln(logOut, "[WARN] Could not save performance state:", err)
+	} else {
+		window := "all time"
+		if rawConfig.PerformanceWindowDays > 0 {
+			window = fmt.Sprintf("last %d days", rawConfig.PerformanceWindowDays)
+		}
+		fmt.Fprintf(logOut, "[STATS] Performance updated. Consistency: %.1f%% (%d/%d done, %s)\n",
+			perf.ConsistencyFactor*100.0, perf.CompletedSessions, perf.TotalSessions, window)
+	}
+}
+
+// SchedulePlan is a regeneration computed in memory. Nothing is written until
+// commitSchedule, so it can be previewed and thrown away.
+type SchedulePlan struct {
+	State   ScheduleState // the state to save on commit
+	Days    []PlannedDay  // only the days whose plan changes
+	Changes []DayPlanDiff
+	Missed  []Session // sessions to journal as rescheduled on commit
+	Audit   time.Time // the day the missed sessions belong to
+
+	// Chapters whose study did not fit before the syllabus end date, with
+	// RemainingTime holding the hours left unplanned.
+	Unscheduled []ChapterWorkload
+
+	// Days on which the subject rotation policy had to be relaxed.
+	RotationIssues []string
+	// TimetableIssues are the days whose sessions did not all fit into the
+	// timetable.
+	TimetableIssues []string
+}
+
+type PlannedDay struct {
+	Date     time.Time
+	Sessions []Session
+}
+
+func generateSchedule() {
+	fmt.Fprintln(logOut, "--- Starting Schedule Generation ---")
+	markMissedSessions()
+	state, _ := loadState()
+	if plan := planSchedule(state); plan != nil {
+		commitSchedule(plan)
+	}
+}
+
+// commitSchedule writes a planned regeneration: the changed day plans, the
+// state, the change record and refreshed performance statistics.
+func commitSchedule(plan *SchedulePlan) {
+	for _, session := range plan.Missed {
+		logSessionEvent(EventReschedule, plan.Audit, session, 0)
+	}
+	for _, day := range plan.Days {
+		writeDayPlan(day.Date, day.Sessions)
+	}
+	saveState(plan.State)
+	fmt.Fprintln(logOut, "\n--- Schedule Generation Complete ---")
+	fmt.Fprintf(logOut, "Syllabus plans saved in '%s/' until %s.\n", SCHEDULE_DIR, rawConfig.SyllabusEndDate)
+	if first, last, ok := examPhaseWindow(); ok {
+		fmt.Fprintf(logOut, "Exam phase (revision cycles, mock tests, taper) planned from %s to %s, before the exam on %s.\n",
+			first.Format(TIME_FORMAT), last.Format(TIME_FORMAT), rawConfig.ExamDate)
+	}
+	recordPlanChanges(plan.Changes)
+	updatePerformance()
+	printRotationIssues(plan.RotationIssues)
+	for _, issue := range plan.TimetableIssues {
+		fmt.Fprintln(logOut, ColorYellow + "[TIMETABLE] " + issue + ColorReset)
+	}
+	if len(plan.Unscheduled) > 0 {
+		hours := 0.0
+		var ids []string
+		for _, wl := range plan.Unscheduled {
+			hours += wl.RemainingTime
+			ids = append(ids, wl.ID)
+		}
+		fmt.Fprintf(logOut, ColorYellow+"[WARN] The new plan leaves %.1f study hrs of %d chapters unscheduled by %s: %s"+ColorReset+"\n",
+			hours, len(ids), rawConfig.SyllabusEndDate, strings.Join(ids, ", "))
+	}
+	printFeasibility(logOut, analyzeFeasibility(plan.State, currentDay()))
+}
+
+// scheduleTimes times one planned day and notes it if sessions did not fit.
+func (plan *SchedulePlan) scheduleTimes(date time.Time, sessions []Session) []Session {
+	timed, unplaced := scheduleTimes(date, sessions, clock.Now())
+	if unplaced > 0.001 {
+		plan.TimetableIssues = append(plan.TimetableIssues,
+			fmt.Sprintf("%s: %.2f hrs of sessions do not fit into the timetable and have no time.", date.Format(TIME_FORMAT), unplaced))
+	}
+	return timed
+}
+
+// planSchedule lays out every day from the state's LastScheduledDate (or
+// today, if that is earlier) to the syllabus end date without writing
+// anything. It returns nil when there is nothing left to plan.
+func planSchedule(state ScheduleState) *SchedulePlan {
+	realToday := currentDay()
+	stateDate, _ := time.Parse(TIME_FORMAT, state.LastScheduledDate)
+	syllabusEndDate, _ := time.Parse(TIME_FORMAT, rawConfig.SyllabusEndDate)
+
+	if stateDate.Before(realToday) {
+		state.LastScheduledDate = realToday.Format(TIME_FORMAT)
+		fmt.Fprintf(logOut, "[FIX] Schedule path reset detected. Starting generation from today: %s\n", realToday.Format(TIME_FORMAT))
+	}
+
+	// --- Adaptive scaling ---
+	perf := PerformanceState{}
+	_ = loadJSON(PERFORMANCE_FILE, &perf)
+	consistency := perf.ConsistencyFactor
+	if consistency <= 0 || math.IsNaN(consistency) {
+		consistency = 1.0
+	}
+	// Focus only counts once there is data; until then it mirrors consistency.
+	focus := consistency
+	if perf.FocusSessions > 0 && !math.IsNaN(perf.AverageFocusScore) {
+		focus = perf.AverageFocusScore
+	}
+	minScale := 0.8
+	maxScale := 1.15
+	scale := minScale + (maxScale-minScale)*(0.7*consistency+0.3*focus)
+	adaptedDailyStudyHrsGlobal := rawConfig.DailyStudyHrs * scale
+	adaptedMaxSessionHrsGlobal := rawConfig.MaxSessionHrs * (0.9 + 0.2*focus)
+	if adaptedDailyStudyHrsGlobal <= 0 {
+		adaptedDailyStudyHrsGlobal = rawConfig.DailyStudyHrs
+	}
+	if adaptedMaxSessionHrsGlobal <= 0 {
+		adaptedMaxSessionHrsGlobal = rawConfig.MaxSessionHrs
+	}
+
+	allChapters := calculateQuotas(&state)
+	allChapters = prioritizeChapters(allChapters)
+	currentDate, _ := time.Parse(TIME_FORMAT, state.LastScheduledDate)
+
+	if state.TotalRemainingTime <= 0.001 && len(getDueRevisions(state, currentDate)) == 0 {
+		if _, examPhaseEnd, ok := examPhaseWindow(); currentDate.After(syllabusEndDate) && (!ok || currentDate.After(examPhaseEnd)) {
+			fmt.Fprintln(logOut, "[SUCCESS] All chapters are studied and all revisions are up-to-date. No new schedule generated.")
+			return nil
+		}
+	}
+
+	fmt.Fprintf(logOut, "[INFO] Required Daily Quota (WT): %.2f | Regenerating from %s\n", state.DailyQuotaWT, currentDate.Format(TIME_FORMAT))
+
+	var activeStudyChapters []*ChapterWorkload
+	planned := map[string]*ChapterWorkload{}
+	for i := range allChapters {
+		cloneSubTopics(&allChapters[i])
+		planned[allChapters[i].ID] = &allChapters[i]
+		if !allChapters[i].IsStudyCompleted && allChapters[i].RemainingTime > 0.001 {
+			activeStudyChapters = append(activeStudyChapters, &allChapters[i])
+		}
+	}
+
+	// Prerequisites are judged on the planning copies, so a chapter becomes
+	// available once the plan has finished studying what it depends on.
+	graph := prerequisiteGraph(rawConfig)
+	lookupPlanned := func(id string) (ChapterWorkload, bool) {
+		if ch, ok := planned[id]; ok {
+			return *ch, true
+		}
+		return ChapterWorkload{}, false
+	}
+	isBlocked := func(ch *ChapterWorkload) bool {
+		return len(unmetPrerequisites(*ch, graph, lookupPlanned)) > 0
+	}
+
+	plan := &SchedulePlan{}
+	planStart := currentDate
+	for _, problem := range validateRotationPolicy(rawConfig) {
+		fmt.Fprintln(logOut, ColorYellow + "[ROTATION] " + problem + ColorReset)
+	}
+	for _, problem := range validateActivityMix(rawConfig) {
+		fmt.Fprintln(logOut, ColorYellow + "[CONFIG] " + problem + ColorReset)
+	}
+	for _, problem := range validateTimetable(rawConfig) {
+		fmt.Fprintln(logOut, ColorYellow + "[TIMETABLE] " + problem + ColorReset)
+	}
+	for _, problem := range validateCalendar(rawConfig) {
+		fmt.Fprintln(logOut, ColorYellow + "[CALENDAR] " + problem + ColorReset)
+	}
+	rotation := newSubjectRotation(rawConfig.Rotation, currentDate, state.LastSubjects)
+
+	for currentDate.Before(syllabusEndDate.AddDate(0, 0, 1)) {
+		rotation.startDay(currentDate)
+		dailySessions := []Session{}
+		dailyProgressWT := 0.0
+
+		// Base hours for the day, scaled down on reduced calendar days
+		dayHrs := studyHoursOn(currentDate)
+		dayShare := 1.0
+		if rawConfig.DailyStudyHrs > 0 {
+			dayShare = math.Min(1, dayHrs/rawConfig.DailyStudyHrs)
+		}
+		dailyTotalStudyHrs := adaptedDailyStudyHrsGlobal*dayShare - (float64(rawConfig.DailyBufferMins) / 60.0)
+		if dailyTotalStudyHrs < 0.25 {
+			dailyTotalStudyHrs = math.Min(0.25, dayHrs-(float64(rawConfig.DailyBufferMins)/60.0))
+		}
+		if hours, ok := timetableStudyHours(currentDate, 0); ok {
+			dailyTotalStudyHrs = math.Min(dailyTotalStudyHrs, hours)
+		}
+
+		maxSessionsPerDay := int(math.Max(1.0, math.Floor(dailyTotalStudyHrs/adaptedMaxSessionHrsGlobal)))
+		sessionCount := 0
+		hoursAssigned := 0.0
+		todaySubjects := map[string]bool{}
+
+		// Sessions already completed or missed on this day stay as they are
+		// and use up their share of the day; only the rest is re-planned.
+		oldSessions, _ := readDayPlan(currentDate)
+		for _, s := range oldSessions {
+			if s.Status == "Completed" || s.Status == "Missed" {
+				dailySessions = append(dailySessions, s)
+				hoursAssigned += s.Duration
+				if s.Type == "Study" || s.Type == "Revision" {
+					sessionCount++
+					todaySubjects[s.Subject] = true
+				}
+			}
+		}
+		// Today only the rest of the timetable is still available.
+		if now := clock.Now(); currentDate.Equal(realToday) {
+			if hours, ok := timetableStudyHours(currentDate, now.Hour()*60+now.Minute()); ok {
+				dailyTotalStudyHrs = math.Min(dailyTotalStudyHrs, hoursAssigned+hours)
+			}
+		}
+
+		if len(dailySessions) > 0 && !isStudyDay(currentDate) {
+			// A rest day that was used anyway is left untouched.
+		} else if !isStudyDay(currentDate) {
+			dailySessions = append(dailySessions, restDaySession(currentDate))
+		} else {
+			// Handle due revisions first
+			dueRevisions := getDueRevisions(state, currentDate)
+			sort.SliceStable(dueRevisions, func(i, j int) bool {
+				return dueRevisions[i].PriorityScore > dueRevisions[j].PriorityScore
+			})
+			for len(dueRevisions) > 0 && hoursAssigned < dailyTotalStudyHrs && sessionCount < maxSessionsPerDay {
+				revChapter := dueRevisions[0]
+				revDuration := math.Min(REVISION_TIME_HRS, dailyTotalStudyHrs-hoursAssigned)
+				if revDuration <= 0.001 {
+					break
+				}
+				dailySessions = append(dailySessions, Session{
+					Subject:   revChapter.Subject,
+					Chapter:   fmt.Sprintf("%s (Revision #%d)", revChapter.Chapter, revChapter.RevisionCount+1),
+					Duration:  revDuration,
+					ChapterID: revChapter.ID,
+					Type:      "Revision",
+					Status:    "Pending",
+					Activity:  ActivityFlashcards,
+				})
+				hoursAssigned += revDuration
+				todaySubjects[revChapter.Subject] = true
+				dueRevisions = dueRevisions[1:]
+				sessionCount++
+			}
+
+			// Filter active chapters
+			var currentActive []*ChapterWorkload
+			for _, ch := range activeStudyChapters {
+				if !ch.IsStudyCompleted && ch.RemainingTime > 0.001 {
+					currentActive = append(currentActive, ch)
+				}
+			}
+			activeStudyChapters = currentActive
+			sort.SliceStable(activeStudyChapters, func(i, j int) bool {
+				return activeStudyChapters[i].PriorityScore > activeStudyChapters[j].PriorityScore
+			})
+
+			// Pick the day's subjects as the rotation policy allows
+			candidates := []string{}
+			for _, ch := range activeStudyChapters {
+				if !isBlocked(ch) && !contains(candidates, ch.Subject) {
+					candidates = append(candidates, ch.Subject)
+				}
+			}
+			allSubjects := rotation.pick(currentDate, candidates)
+			studiedToday := map[string]bool{}
+
+			// Assign study sessions for selected subjects
+			for dailyProgressWT < state.DailyQuotaWT && hoursAssigned < dailyTotalStudyHrs && len(activeStudyChapters) > 0 && sessionCount < maxSessionsPerDay {
+				// Each picked subject gets a session before any gets a second
+				foundChapterIndex := -1
+				maxP := -1e18
+				fresh := false
+				for i, ch := range activeStudyChapters {
+					if !contains(allSubjects, ch.Subject) || isBlocked(ch) {
+						continue
+					}
+					isFresh := !studiedToday[ch.Subject]
+					if (isFresh && !fresh) || (isFresh == fresh && ch.PriorityScore > maxP) {
+						maxP, fresh, foundChapterIndex = ch.PriorityScore, isFresh, i
+					}
+				}
+				if foundChapterIndex == -1 {
+					break
+				}
+				currentChapter := activeStudyChapters[foundChapterIndex]
+				sessionDuration := math.Min(adaptedMaxSessionHrsGlobal, currentChapter.RemainingTime)
+				if sessionDuration > (dailyTotalStudyHrs - hoursAssigned) {
+					sessionDuration = dailyTotalStudyHrs - hoursAssigned
+				}
+				if sessionDuration <= 0.001 {
+					activeStudyChapters = append(activeStudyChapters[:foundChapterIndex], activeStudyChapters[foundChapterIndex+1:]...)
+					continue
+				}
+
+				dailySessions = append(dailySessions, Session{
+					Subject:   currentChapter.Subject,
+					Chapter:   currentChapter.Chapter,
+					Duration:  sessionDuration,
+					ChapterID: currentChapter.ID,
+					Type:      "Study",
+					Status:    "Pending",
+					Activity:  studyActivity(activityMixFor(rawConfig, *currentChapter), *currentChapter, sessionDuration),
+					Topics:    coverSubTopics(currentChapter, sessionDuration, nil),
+				})
+
+				sessionWT := sessionDuration * (1 + currentChapter.Difficulty/5.0) * (currentChapter.Weightage * 2.0)
+				dailyProgressWT += sessionWT
+				hoursAssigned += sessionDuration
+				todaySubjects[currentChapter.Subject] = true
+				studiedToday[currentChapter.Subject] = true
+				// Only the planning copy is reduced: RemainingTime in the state
+				// drops when a session is actually completed.
+				currentChapter.RemainingTime -= sessionDuration
+				sessionCount++
+
+				if currentChapter.RemainingTime <= 0.001 {
+					startRevisionCycle(currentChapter, currentDate)
+					activeStudyChapters = append(activeStudyChapters[:foundChapterIndex], activeStudyChapters[foundChapterIndex+1:]...)
+				}
+			}
+
+			// Add buffer
+			dailySessions = append(dailySessions, bufferSession())
+		}
+
+		// Update LastSubjects for next day
+		state.LastSubjects = []string{}
+		for s := range todaySubjects {
+			state.LastSubjects = append(state.LastSubjects, s)
+		}
+		rotation.record(currentDate, dailySessions)
+		dailySessions = plan.scheduleTimes(currentDate, dailySessions)
+
+		if change := diffDayPlan(currentDate, oldSessions, dailySessions); !change.Empty() {
+			plan.Changes = append(plan.Changes, change)
+			plan.Days = append(plan.Days, PlannedDay{Date: currentDate, Sessions: dailySessions})
+		}
+		currentDate = currentDate.AddDate(0, 0, 1)
+		state.LastScheduledDate = currentDate.Format(TIME_FORMAT)
+	}
+
+	// Starting after the syllabus end means those days were planned earlier.
+	for _, ch := range activeStudyChapters {
+		if ch.RemainingTime > 0.001 && !planStart.After(syllabusEndDate) {
+			plan.Unscheduled = append(plan.Unscheduled, *ch)
+		}
+	}
+	plan.RotationIssues = rotation.Issues
+	// The planning copies show which chapters will have been studied by the
+	// end of the syllabus phase; only those are revised in the exam phase.
+	studied := map[string]bool{}
+	for id, wl := range state.Workload {
+		studied[id] = wl.IsStudyCompleted
+	}
+	for _, ch := range allChapters {
+		studied[ch.ID] = ch.IsStudyCompleted
+	}
+	planExamPhase(&state, plan, currentDate, studied)
+	plan.State = state
+	return plan
+}
+
+// restDaySession fills a day without study; a calendar override names the
+// day by its note or kind instead of the weekly rest day activity.
+func restDaySession(date time.Time) Session {
+	activity := rawConfig.RestDayActivity
+	if o, ok := calendarOverrideOn(date); ok {
+		activity = o.Note
+		if activity == "" {
+			activity = strings.ToUpper(o.Kind[:1]) + o.Kind[1:] + " day"
+		}
+	}
+	return Session{
+		Subject:  "Rest",
+		Chapter:  activity,
+		Duration: rawConfig.DailyStudyHrs,
+		Type:     "Rest",
+		Status:   "Pending",
+	}
+}
+
+func bufferSession() Session {
+	return Session{
+		Subject:  "Buffer",
+		Chapter:  "Recovery/Review",
+		Duration: float64(rawConfig.DailyBufferMins) / 60.0,
+		Type:     "Buffer",
+		Status:   "Pending",
+	}
+}
+
+// ------------------ Exam Phase ------------------
+
+const (
+	EXAM_REVISION_HRS    = 1.0 // one exam-phase revision session
+	EXAM_MOCK_TEST_HRS   = 3.0 // one full-length mock test
+	EXAM_MOCK_EVERY_DAYS = 3   // a mock test on every third study day
+	EXAM_TAPER_DAYS      = 3   // lighter days right before the exam
+	EXAM_TAPER_FACTOR    = 0.5 // share of the daily hours kept while tapering
+	EXAM_REVISION_SUFFIX = " (Exam Revision)"
+)
+
+// examPhaseWindow returns the first and last day of the exam phase: from the
+// day after the syllabus end date to the day before the exam. ok is false when
+// the config leaves no such window.
+func examPhaseWindow() (first, last time.Time, ok bool) {
+	syllabusEndDate, err := time.Parse(TIME_FORMAT, rawConfig.SyllabusEndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	examDate, err := time.Parse(TIME_FORMAT, rawConfig.ExamDate)
+	if err != nil || !examDate.After(syllabusEndDate.AddDate(0, 0, 1)) {
+		return time.Time{}, time.Time{}, false
+	}
+	return syllabusEndDate.AddDate(0, 0, 1), examDate.AddDate(0, 0, -1), true
+}
+
+// isExamRevision tells exam-phase revisions apart from spaced-repetition ones.
+func isExamRevision(s Session) bool {
+	return s.Type == "Revision" && strings.HasSuffix(s.Chapter, EXAM_REVISION_SUFFIX)
+}
+
+// examRevisionWeight is how often a chapter comes up in the exam-phase
+// revision cycles relative to the others.
+func examRevisionWeight(wl ChapterWorkload) float64 {
+	return wl.Weightage * (1 + wl.Difficulty/5.0)
+}
+
+// nextExamRevision picks the studied chapter furthest behind its share of
+// revisions, skipping chapters already revised that day.
+func nextExamRevision(workload map[string]ChapterWorkload, studied map[string]bool, revised map[string]int, today map[string]bool) (ChapterWorkload, bool) {
+	var best ChapterWorkload
+	bestScore := -1.0
+	for _, id := range sortedChapterIDs(workload) {
+		if today[id] || !studied[id] {
+			continue
+		}
+		wl := workload[id]
+		if score := examRevisionWeight(wl) / float64(1+revised[id]); score > bestScore {
+			best, bestScore = wl, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// planExamPhase lays out the exam phase from `from` (or its first day, if
+// later) into plan: every chapter is revised in cycles, in proportion to its
+// weightage and difficulty, with a full mock test every EXAM_MOCK_EVERY_DAYS
+// study days and shorter revision-only days over the last EXAM_TAPER_DAYS.
+// As in the syllabus phase, worked sessions are kept and count towards the day.
+// Chapters not in studied are never revised: their study did not fit.
+func planExamPhase(state *ScheduleState, plan *SchedulePlan, from time.Time, studied map[string]bool) {
+	first, last, ok := examPhaseWindow()
+	if !ok || from.After(last) {
+		return
+	}
+	taperFrom := last.AddDate(0, 0, 1-EXAM_TAPER_DAYS)
+
+	// Days already behind us still count, so that regenerating in the middle
+	// of the phase continues the revision cycle and the mock test rhythm.
+	revised := map[string]int{}
+	studyDay := 0
+	for date := first; !date.After(last); date = date.AddDate(0, 0, 1) {
+		oldSessions, _ := readDayPlan(date)
+		if date.Before(from) {
+			for _, s := range oldSessions {
+				if isExamRevision(s) {
+					revised[s.ChapterID]++
+				}
+			}
+			if dailyStudyCapacity(date) > 0 {
+				studyDay++
+			}
+			continue
+		}
+
+		dailySessions := []Session{}
+		hoursAssigned := 0.0
+		revisedToday := map[string]bool{}
+		hasMock := false
+		for _, s := range oldSessions {
+			if s.Status == "Completed" || s.Status == "Missed" {
+				dailySessions = append(dailySessions, s)
+				hoursAssigned += s.Duration
+				if isExamRevision(s) {
+					revised[s.ChapterID]++
+					revisedToday[s.ChapterID] = true
+				}
+				hasMock = hasMock || s.Type == "Mock"
+			}
+		}
+
+		capacity := dailyStudyCapacity(date)
+		if capacity <= 0 {
+			if len(dailySessions) == 0 {
+				dailySessions = append(dailySessions, restDaySession(date))
+			}
+		} else {
+			if !date.Before(taperFrom) {
+				capacity *= EXAM_TAPER_FACTOR
+			} else if studyDay%EXAM_MOCK_EVERY_DAYS == 0 && !hasMock && capacity-hoursAssigned > 0.001 {
+				duration := math.Min(EXAM_MOCK_TEST_HRS, capacity-hoursAssigned)
+				dailySessions = append(dailySessions, Session{
+					Subject:  "Mock Test",
+					Chapter:  "Full-length paper (all subjects)",
+					Duration: duration,
+					Type:     "Mock",
+					Status:   "Pending",
+					Activity: ActivityMock,
+				})
+				hoursAssigned += duration
+			}
+			for capacity-hoursAssigned > 0.001 {
+				wl, found := nextExamRevision(state.Workload, studied, revised, revisedToday)
+				if !found {
+					break
+				}
+				duration := math.Min(EXAM_REVISION_HRS, capacity-hoursAssigned)
+				dailySessions = append(dailySessions, Session{
+					Subject:   wl.Subject,
+					Chapter:   wl.Chapter + EXAM_REVISION_SUFFIX,
+					Duration:  duration,
+					ChapterID: wl.ID,
+					Type:      "Revision",
+					Status:    "Pending",
+					Activity:  ActivityPYQ,
+				})
+				hoursAssigned += duration
+				revised[wl.ID]++
+				revisedToday[wl.ID] = true
+			}
+			dailySessions = append(dailySessions, bufferSession())
+			studyDay++
+		}
+
+		dailySessions = plan.scheduleTimes(date, dailySessions)
+		if change := diffDayPlan(date, oldSessions, dailySessions); !change.Empty() {
+			plan.Changes = append(plan.Changes, change)
+			plan.Days = append(plan.Days, PlannedDay{Date: date, Sessions: dailySessions})
+		}
+		state.LastScheduledDate = date.AddDate(0, 0, 1).Format(TIME_FORMAT)
+	}
+}
+
+// ------------------ Mock Tests ------------------
+
+const (
+	MOCK_TESTS_FILE      = "data/mock_tests.jsonl"
+	MOCK_TARGET_ACCURACY = 0.7  // chapters scoring below this count as weak
+	MOCK_ERROR_PENALTY   = 0.25 // accuracy lost per error when no marks are given
+	MOCK_PRIORITY_WEIGHT = 2.0  // revision priority boost at full weakness
+)
+
+// MockTestResult is one line of MOCK_TESTS_FILE.
+type MockTestResult struct {
+	Date     string             `json:"date"`
+	Name     string             `json:"name,omitempty"`
+	Subjects []MockSubjectScore `json:"subjects"`
+}
+
+type MockSubjectScore struct {
+	Subject  string             `json:"subject"`
+	Marks    float64            `json:"marks"`
+	MaxMarks float64            `json:"max_marks"`
+	Chapters []MockChapterScore `json:"chapters,omitempty"`
+}
+
+// MockChapterScore attributes part of a mock to one chapter: the marks scored
+// on its questions out of MaxMarks, and/or the number of errors made.
+type MockChapterScore struct {
+	ChapterID string  `json:"chapter_id"`
+	Marks     float64 `json:"marks"`
+	MaxMarks  float64 `json:"max_marks"`
+	Errors    int     `json:"errors"`
+}
+
+// Accuracy is the share of marks scored, or, without marks, one minus
+// MOCK_ERROR_PENALTY per error.
+func (s MockChapterScore) Accuracy() float64 {
+	if s.MaxMarks > 0 {
+		return math.Min(1, math.Max(0, s.Marks/s.MaxMarks))
+	}
+	return math.Max(0, 1-float64(s.Errors)*MOCK_ERROR_PENALTY)
+}
+
+func (r MockTestResult) Total() (marks, maxMarks float64) {
+	for _, s := range r.Subjects {
+		marks += s.Marks
+		maxMarks += s.MaxMarks
+	}
+	return marks, maxMarks
+}
+
+// applyMockScore feeds one chapter's mock result back into its workload. The
+// difficulty moves one adjustment step per ten points of accuracy away from
+// MOCK_TARGET_ACCURACY, MockWeakness keeps the mean of how far below target
+// the chapter has scored, and a weak chapter that is already studied gets a
+// revision the next day (reopening one if its revisions were used up).
+func applyMockScore(wl ChapterWorkload, score MockChapterScore, date time.Time) ChapterWorkload {
+	accuracy := score.Accuracy()
+	delta := rawConfig.DifficultyAdjustmentRate * (MOCK_TARGET_ACCURACY - accuracy) * 10
+	wl.Difficulty = math.Min(5.0, math.Max(1.0, wl.Difficulty+delta))
+
+	weakness := math.Max(0, (MOCK_TARGET_ACCURACY-accuracy)/MOCK_TARGET_ACCURACY)
+	n := float64(wl.MockScores)
+	wl.MockWeakness = (wl.MockWeakness*n + weakness) / (n + 1)
+	wl.MockScores++
+
+	if accuracy < MOCK_TARGET_ACCURACY && wl.IsStudyCompleted {
+		if limit := revisionModelFor(rawConfig).MaxRevisions(); limit > 0 && wl.RevisionCount >= limit {
+			wl.RevisionCount = limit - 1
+		}
+		due := date.AddDate(0, 0, 1).Format(TIME_FORMAT)
+		if wl.NextRevisionDate == "" || due < wl.NextRevisionDate {
+			wl.NextRevisionDate = due
+		}
+	}
+	return wl
+}
+
+// recordMockTest stores a mock result, journals every chapter score and
+// applies them to the schedule state.
+func recordMockTest(result MockTestResult) error {
+	date, err := time.Parse(TIME_FORMAT, result.Date)
+	if err != nil {
+		return fmt.Errorf("invalid mock test date %q", result.Date)
+	}
+	state, _ := loadState()
+	for _, subject := range result.Subjects {
+		if _, ok := syllabusSubject(state.Workload, subject.Subject); !ok {
+			return fmt.Errorf("unknown subject %q", subject.Subject)
+		}
+		for _, score := range subject.Chapters {
+			if _, ok := state.Workload[score.ChapterID]; !ok {
+				return fmt.Errorf("unknown chapter %q", score.ChapterID)
+			}
+		}
+	}
+
+	line, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := appendLine(MOCK_TESTS_FILE, line); err != nil {
+		return err
+	}
+	for _, subject := range result.Subjects {
+		for _, score := range subject.Chapters {
+			wl := state.Workload[score.ChapterID]
+			event := newSessionEvent(EventMock, date, Session{Subject: wl.Subject, Chapter: wl.Chapter, ChapterID: wl.ID, Type: "Mock"}, 0)
+			score := score
+			event.Mock = &score
+			if err := appendEvent(event); err != nil {
+				fmt.Fprintln(logOut, "[WARN] Could not write to event log:", err)
+			}
+			state.Workload[wl.ID] = applyMockScore(wl, score, date)
+		}
+	}
+	return saveState(state)
+}
+
+// loadMockTests returns every recorded mock test, oldest first.
+func loadMockTests() ([]MockTestResult, error) {
+	data, err := os.ReadFile(MOCK_TESTS_FILE)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var results []MockTestResult
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var r MockTestResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return results, fmt.Errorf("%s line %d: %v", MOCK_TESTS_FILE, i+1, err)
+		}
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Date < results[j].Date })
+	return results, nil
+}
+
+// weakChapters lists chapters with mock weakness, weakest first.
+func weakChapters(state ScheduleState) []ChapterWorkload {
+	var weak []ChapterWorkload
+	for _, id := range sortedChapterIDs(state.Workload) {
+		if wl := state.Workload[id]; wl.MockWeakness > 0.001 {
+			weak = append(weak, wl)
+		}
+	}
+	sort.SliceStable(weak, func(i, j int) bool { return weak[i].MockWeakness > weak[j].MockWeakness })
+	return weak
+}
+
+// parseMarks parses "scored/max".
+func parseMarks(value string) (marks, maxMarks float64, err error) {
+	scored, max, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, fmt.Errorf("marks must look like scored/max, got %q", value)
+	}
+	if marks, err = strconv.ParseFloat(strings.TrimSpace(scored), 64); err == nil {
+		maxMarks, err = strconv.ParseFloat(strings.TrimSpace(max), 64)
+	}
+	if err != nil || maxMarks <= 0 || marks > maxMarks {
+		return 0, 0, fmt.Errorf("invalid marks %q", value)
+	}
+	return marks, maxMarks, nil
+}
+
+// parseSubjectScore parses "Subject=scored/max".
+func parseSubjectScore(spec string) (MockSubjectScore, error) {
+	subject, marks, found := strings.Cut(spec, "=")
+	if !found || strings.TrimSpace(subject) == "" {
+		return MockSubjectScore{}, fmt.Errorf("subject score must look like Physics=120/180, got %q", spec)
+	}
+	s := MockSubjectScore{Subject: strings.TrimSpace(subject)}
+	var err error
+	s.Marks, s.MaxMarks, err = parseMarks(marks)
+	return s, err
+}
+
+// parseChapterScore parses "ID=scored/max", "ID=scored/max:errors" or
+// "ID=:errors".
+func parseChapterScore(spec string) (MockChapterScore, error) {
+	id, rest, found := strings.Cut(spec, "=")
+	if !found || strings.TrimSpace(id) == "" {
+		return MockChapterScore{}, fmt.Errorf("chapter score must look like PH002=8/16 or PH002=8/16:3, got %q", spec)
+	}
+	score := MockChapterScore{ChapterID: strings.ToUpper(strings.TrimSpace(id))}
+	marks, errCount, hasErrors := strings.Cut(rest, ":")
+	if strings.TrimSpace(marks) != "" {
+		var err error
+		if score.Marks, score.MaxMarks, err = parseMarks(marks); err != nil {
+			return score, err
+		}
+	}
+	if hasErrors {
+		n, err := strconv.Atoi(strings.TrimSpace(errCount))
+		if err != nil || n < 0 {
+			return score, fmt.Errorf("invalid error count in %q", spec)
+		}
+		score.Errors = n
+	}
+	if score.MaxMarks == 0 && !hasErrors {
+		return score, fmt.Errorf("chapter score %q has neither marks nor errors", spec)
+	}
+	return score, nil
+}
+
+// syllabusSubject returns the workload's spelling of subject, matched
+// without regard to case, and whether the syllabus has it at all.
+func syllabusSubject(workload map[string]ChapterWorkload, subject string) (string, bool) {
+	for _, id := range sortedChapterIDs(workload) {
+		if strings.EqualFold(workload[id].Subject, subject) {
+			return workload[id].Subject, true
+		}
+	}
+	return subject, false
+}
+
+// addChapterScores checks the subject totals against the syllabus and files
+// chapter scores under their subject, adding subjects that were not given a
+// total.
+func addChapterScores(result *MockTestResult, scores []MockChapterScore, workload map[string]ChapterWorkload) error {
+	for i, s := range result.Subjects {
+		name, ok := syllabusSubject(workload, s.Subject)
+		if !ok {
+			return fmt.Errorf("unknown subject %q", s.Subject)
+		}
+		result.Subjects[i].Subject = name
+	}
+	for _, score := range scores {
+		wl, ok := workload[score.ChapterID]
+		if !ok {
+			return fmt.Errorf("unknown chapter %q", score.ChapterID)
+		}
+		i := 0
+		for i < len(result.Subjects) && !strings.EqualFold(result.Subjects[i].Subject, wl.Subject) {
+			i++
+		}
+		if i == len(result.Subjects) {
+			result.Subjects = append(result.Subjects, MockSubjectScore{Subject: wl.Subject})
+		}
+		result.Subjects[i].Chapters = append(result.Subjects[i].Chapters, score)
+	}
+	return nil
+}
+
+func printMockTests(results []MockTestResult, weak []ChapterWorkload) {
+	if len(results) == 0 {
+		fmt.Println("[INFO] No mock tests recorded yet. Log one with 'mock log'.")
+		return
+	}
+	fmt.Printf("--- Mock Tests (%d) ---\n", len(results))
+	for _, r := range results {
+		marks, maxMarks := r.Total()
+		percent := 0.0
+		if maxMarks > 0 {
+			percent = marks / maxMarks * 100
+		}
+		fmt.Printf("%s  %-20s  Total %.0f/%.0f (%.1f%%)\n", r.Date, r.Name, marks, maxMarks, percent)
+		for _, s := range r.Subjects {
+			if s.MaxMarks > 0 {
+				fmt.Printf("    %-10s %.0f/%.0f (%.1f%%)\n", s.Subject, s.Marks, s.MaxMarks, s.Marks/s.MaxMarks*100)
+			} else {
+				fmt.Printf("    %-10s no total\n", s.Subject)
+			}
+			for _, c := range s.Chapters {
+				color := ColorGreen
+				if c.Accuracy() < MOCK_TARGET_ACCURACY {
+					color = ColorRed
+				}
+				detail := fmt.Sprintf("%d errors", c.Errors)
+				if c.MaxMarks > 0 {
+					detail = fmt.Sprintf("%.0f/%.0f, %s", c.Marks, c.MaxMarks, detail)
+				}
+				fmt.Printf("      %s%s accuracy %.0f%% (%s)%s\n", color, c.ChapterID, c.Accuracy()*100, detail, ColorReset)
+			}
+		}
+	}
+	if len(weak) > 0 {
+		fmt.Println("\nWeakest chapters (revised sooner and more often):")
+		for _, wl := range weak {
+			fmt.Printf("  - %s %s: %s (weakness %.2f, difficulty %.1f)\n", wl.ID, wl.Subject, wl.Chapter, wl.MockWeakness, wl.Difficulty)
+		}
+	}
+}
+
+// promptMockTest records a mock test from the interactive menu.
+func promptMockTest(reader *bufio.Reader) {
+	fmt.Println(ColorYellow + "\n--- Log Mock Test ---" + ColorReset)
+	result := MockTestResult{Date: readDate(reader, "Mock test date", currentDay().Format(TIME_FORMAT))}
+	fmt.Print("Name (optional): ")
+	name, _ := reader.ReadString('\n')
+	result.Name = strings.TrimSpace(name)
+
+	state, _ := loadState()
+	var subjects []string
+	for _, wl := range rawConfig.InitialWorkload {
+		if !contains(subjects, wl.Subject) {
+			subjects = append(subjects, wl.Subject)
+		}
+	}
+	for _, subject := range subjects {
+		fmt.Printf("%s marks (scored/max, Enter to skip): ", subject)
+		input, _ := reader.ReadString('\n')
+		if input = strings.TrimSpace(input); input != "" {
+			score, err := parseSubjectScore(subject + "=" + input)
+			if err != nil {
+				fmt.Println("[ERROR]", err, "- skipping", subject)
+				continue
+			}
+			result.Subjects = append(result.Subjects, score)
+		}
+	}
+	fmt.Print("Chapter scores (ID=scored/max[:errors] or ID=:errors, comma-separated, Enter for none): ")
+	input, _ := reader.ReadString('\n')
+	var scores []MockChapterScore
+	for _, spec := range strings.Split(input, ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		score, err := parseChapterScore(spec)
+		if err != nil {
+			fmt.Println("[ERROR]", err)
+			return
+		}
+		scores = append(scores, score)
+	}
+	if err := addChapterScores(&result, scores, state.Workload); err != nil {
+		fmt.Println("[ERROR]", err)
+		return
+	}
+	if len(result.Subjects) == 0 {
+		fmt.Println("[INFO] Nothing entered. No mock test recorded.")
+		return
+	}
+	if err := recordMockTest(result); err != nil {
+		fmt.Println("[ERROR] Could not record mock test:", err)
+		return
+	}
+	fmt.Println(ColorGreen + "[MOCK] Mock test recorded." + ColorReset + " Re-generate the schedule to move weak chapters' revisions forward.")
+}
+
+func processMissedSessionsForDate(date time.Time) ([]Session, error) {
+	sessions, err := readDayPlan(date)
+	if err != nil {
+		return nil, err
+	}
+	missedSessions := []Session{}
+	updated := false
+	modifiedSessions := make([]Session, len(sessions))
+	copy(modifiedSessions, sessions)
+	for i, s := range modifiedSessions {
+		if s.Status == "Pending" && (s.Type == "Study" || s.Type == "Revision") {
+			modifiedSessions[i].Status = "Missed"
+			missedSessions = append(missedSessions, modifiedSessions[i])
+			logSessionEvent(EventMiss, date, modifiedSessions[i], 0)
+			updated = true
+		}
+	}
+	if updated {
+		writeDayPlan(date, modifiedSessions)
+	}
+	return missedSessions, nil
+}
+
+func adjustWorkload(missedSessions []Session, auditDate time.Time) {
+	fmt.Fprintln(logOut, "\n[ADJUSTMENT] Recalculating workload due to missed sessions...")
+	markMissedSessions()
+	if plan := planAdjustment(missedSessions, auditDate); plan != nil {
+		commitSchedule(plan)
+		fmt.Fprintln(logOut, "[ADJUSTMENT] Schedule successfully updated and re-balanced.")
+	}
+}
+
+// planAdjustment applies missed sessions to the loaded state in memory and
+// plans the schedule again from the day after auditDate. Nothing is written.
+func planAdjustment(missedSessions []Session, auditDate time.Time) *SchedulePlan {
+	state, _ := loadState()
+	if len(state.Workload) == 0 {
+		fmt.Fprintln(logOut, "[WARNING] No active workload in state. Skipping adjustment.")
+		return nil
+	}
+	applied := []Session{}
+	for _, session := range missedSessions {
+		chID := session.ChapterID
+		if chID != "" {
+			if workload, ok := state.Workload[chID]; ok {
+				workload = applyMissedSession(workload, session, auditDate)
+				if session.Type == "Revision" {
+					fmt.Fprintf(logOut, "  -> Missed Revision for %s. Resetting due date.\n", workload.Chapter)
+				} else {
+					fmt.Fprintf(logOut, "  -> %.1f hrs of %s carried over for re-planning.\n", session.Duration, workload.Chapter)
+				}
+				state.Workload[chID] = workload
+				applied = append(applied, session)
+			}
+		}
+	}
+
+	restartDate := auditDate.AddDate(0, 0, 1)
+	state.LastScheduledDate = restartDate.Format(TIME_FORMAT)
+	fmt.Fprintf(logOut, "[ADJUSTMENT] Re-generating schedule from %s with adjusted workload...\n", restartDate.Format(TIME_FORMAT))
+	plan := planSchedule(state)
+	if plan == nil {
+		// Nothing left to plan, but the workload change still has to be saved.
+		plan = &SchedulePlan{State: state}
+	}
+	plan.Missed = applied
+	plan.Audit = auditDate
+	return plan
+}
+
+// applyCompletedSession credits a finished study or revision session to its
+// chapter. elapsedSeconds below the planned length means an early finish, in
+// which case only the time actually spent is deducted.
+func applyCompletedSession(workload ChapterWorkload, session Session, elapsedSeconds int, today time.Time, grade int, rating *SessionRating) ChapterWorkload {
+	workload = updateChapterPerformance(workload, true, rating)
+	if isExamRevision(session) {
+		// Like a missed one, an exam-phase revision leaves the
+		// spaced-repetition schedule alone.
+		return workload
+	}
+	if session.Type == "Revision" {
+		recordRevision(&workload, grade, today)
+		return workload
+	}
+
+	timeSpent := session.Duration
+	if elapsedSeconds < int(session.Duration*3600) {
+		timeSpent = float64(elapsedSeconds) / 3600.0
+	}
+	workload.RemainingTime = math.Max(0, workload.RemainingTime-timeSpent)
+	if len(workload.SubTopics) > 0 {
+		cloneSubTopics(&workload)
+		coverSubTopics(&workload, timeSpent, session.Topics)
+		syncRemainingTime(&workload)
+	}
+
+	if workload.RemainingTime <= 0.001 {
+		startRevisionCycle(&workload, today)
+	}
+	return workload
+}
+
+// applyMissedSession records a missed session against its chapter. Study hours
+// are only deducted on completion, so a missed study session's hours are still
+// in RemainingTime; a missed revision is made due again the next day. Missed
+// exam-phase revisions leave the spaced-repetition schedule alone: the next
+// revision cycle covers the chapter again.
+func applyMissedSession(workload ChapterWorkload, session Session, auditDate time.Time) ChapterWorkload {
+	workload = updateChapterPerformance(workload, false, nil)
+	if session.Type == "Revision" && !isExamRevision(session) {
+
+		workload.NextRevisionDate = auditDate.AddDate(0, 0, 1).Format(TIME_FORMAT)
+		workload.RevisionCount--
+		workload.RevisionCount = int(math.Max(0, float64(workload.RevisionCount)))
+	}
+	return workload
+}
+
+// promptSessionRating asks for the post-session self-assessment on the timer's
+// input channel. Skipping the understanding question skips the whole rating.
+func promptSessionRating(cmdChan <-chan command) *SessionRating {
+	fmt.Println("\n-- Quick Self-Assessment --")
+	understanding, ok := promptScore(cmdChan, "Understanding of the material (1-5, Enter to skip): ")
+	if !ok {
+		return nil
+	}
+	focus, ok := promptScore(cmdChan, "Focus during the session (1-5): ")
+	if !ok {
+		focus = 3
+	}
+	fmt.Print("Notes (optional): ")
+	notes := (<-cmdChan).raw
+	return &SessionRating{Understanding: understanding, Focus: focus, Notes: notes}
+}
+
+func promptScore(cmdChan <-chan command, prompt string) (int, bool) {
+	fmt.Print(prompt)
+	for {
+		input := (<-cmdChan).action
+		if input == "" {
+			return 0, false
+		}
+		score, err := strconv.Atoi(input)
+		if err == nil && score >= 1 && score <= 5 {
+			return score, true
+		}
+		fmt.Print("Please enter a whole number from 1 to 5: ")
+	}
+}
+
+// ------------------ Timer ------------------
+
+// TimerState is where a Timer is in its life cycle:
+//
+//	idle -> running <-> paused -> finished | missed
+type TimerState string
+
+const (
+	TimerIdle     TimerState = "idle"
+	TimerRunning  TimerState = "running"
+	TimerPaused   TimerState = "paused"
+	TimerFinished TimerState = "finished"
+	TimerMissed   TimerState = "missed"
+)
+
+// TimerCommand is an input to a Timer. Front ends translate their own input
+// (typed lines, key presses, HTTP requests) into these.
+type TimerCommand string
+
+const (
+	TimerStart       TimerCommand = "start"
+	TimerPause       TimerCommand = "pause"
+	TimerResume      TimerCommand = "resume"
+	TimerFinish      TimerCommand = "finish"
+	TimerMiss        TimerCommand = "miss"
+	TimerToggleMusic TimerCommand = "toggle_music" // counted for the focus score
+	TimerTick        TimerCommand = "tick"
+)
+
+// Clock is the source of the current time, replaceable in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// TimerEvent is sent to every observer after a command has been applied.
+type TimerEvent struct {
+	Command   TimerCommand
+	From      TimerState
+	State     TimerState
+	Elapsed   int // seconds, capped at the total
+	Remaining int // seconds
+}
+
+type TimerObserver func(TimerEvent)
+
+// Timer is the study/break countdown as a state machine. It does no I/O of its
+// own: commands come in through Apply or Run, rendering and persistence happen
+// in observers.
+type Timer struct {
+	clock        Clock
+	total        int
+	initial      int // elapsed seconds carried over from an earlier run
+	state        TimerState
+	banked       int // elapsed seconds up to the last pause
+	runningSince time.Time
+	pausedAt     time.Time
+	focus        FocusMetrics
+	observers    []TimerObserver
+}
+
+func NewTimer(totalSeconds, initialElapsed int, clock Clock) *Timer {
+	return &Timer{
+		clock:   clock,
+		total:   totalSeconds,
+		initial: initialElapsed,
+		state:   TimerIdle,
+		banked:  initialElapsed,
+		focus:   FocusMetrics{PlannedSeconds: totalSeconds},
+	}
+}
+
+func (t *Timer) Observe(o TimerObserver) {
+	t.observers = append(t.observers, o)
+}
+
+func (t *Timer) State() TimerState { return t.state }
+
+func (t *Timer) Done() bool { return t.state == TimerFinished || t.state == TimerMissed }
+
+// Elapsed returns the seconds counted so far, never more than the total.
+func (t *Timer) Elapsed() int {
+	elapsed := t.banked
+	if t.state == TimerRunning {
+		elapsed += int(t.clock.Now().Sub(t.runningSince).Seconds())
+	}
+	return min(elapsed, t.total)
+}
+
+func (t *Timer) Remaining() int { return t.total - t.Elapsed() }
+
+// Focus returns the focus metrics; they are final once the timer is finished.
+func (t *Timer) Focus() FocusMetrics { return t.focus }
+
+// Apply runs one command and notifies the observers. It reports false, and
+// changes nothing, when the command is not valid in the current state.
+func (t *Timer) Apply(cmd TimerCommand) bool {
+	from := t.state
+	now := t.clock.Now()
+	switch {
+	case cmd == TimerStart && from == TimerIdle:
+		t.state = TimerRunning
+		t.runningSince = now
+	case cmd == TimerPause && from == TimerRunning:
+		t.banked = t.Elapsed()
+		t.state = TimerPaused
+		t.pausedAt = now
+		t.focus.PauseCount++
+	case cmd == TimerResume && from == TimerPaused:
+		t.focus.PausedSeconds += int(now.Sub(t.pausedAt).Seconds())
+		t.state = TimerRunning
+		t.runningSince = now
+	case cmd == TimerFinish && (from == TimerRunning || from == TimerPaused):
+		t.stop(TimerFinished)
+	case cmd == TimerMiss && !t.Done():
+		t.stop(TimerMissed)
+	case cmd == TimerToggleMusic && (from == TimerRunning || from == TimerPaused):
+		t.focus.MusicToggles++
+	case cmd == TimerTick && (from == TimerRunning || from == TimerPaused):
+		if from == TimerRunning && t.Elapsed() >= t.total {
+			t.stop(TimerFinished)
+		}
+	default:
+		return false
+	}
+
+	event := TimerEvent{Command: cmd, From: from, State: t.state, Elapsed: t.Elapsed(), Remaining: t.Remaining()}
+	for _, o := range t.observers {
+		o(event)
+	}
+	return true
+}
+
+// stop freezes the clock and settles the focus metrics. Only the part timed in
+// this run counts; a resumed session's earlier part was never observed.
+func (t *Timer) stop(final TimerState) {
+	if t.state == TimerPaused {
+		t.focus.PausedSeconds += int(t.clock.Now().Sub(t.pausedAt).Seconds())
+	}
+	t.banked = t.Elapsed()
+	t.state = final
+	t.focus.ElapsedSeconds = t.banked - t.initial
+	t.focus.PlannedSeconds = t.total - t.initial
+	t.focus.EarlyFinish = t.banked < t.total
+	t.focus.Score = computeFocusScore(t.focus)
+}
+
+// Run starts the timer if needed and feeds it commands and ticks until it is
+// finished or missed, returning the final state.
+func (t *Timer) Run(commands <-chan TimerCommand, ticks <-chan time.Time) TimerState {
+	if t.state == TimerIdle {
+		t.Apply(TimerStart)
+	}
+	for !t.Done() {
+		select {
+		case cmd, ok := <-commands:
+			if !ok {
+				commands = nil
+				continue
+			}
+			t.Apply(cmd)
+		case <-ticks:
+			t.Apply(TimerTick)
+		}
+	}
+	return t.state
+}
+
+// sessionRecorder is the observer every front end attaches to a study timer:
+// it journals start/pause/resume and keeps the progress file current so an
+// interrupted session can be resumed.
+func sessionRecorder(today time.Time, session Session) TimerObserver {
+	lastSaved := 0
+	save := func(elapsed int) {
+		if session.ChapterID != "" {
+			saveProgress(session.ChapterID, elapsed)
+		}
+		lastSaved = elapsed
+	}
+	return func(ev TimerEvent) {
+		switch ev.Command {
+		case TimerStart:
+			kind := EventStart
+			if ev.Elapsed > 0 {
+				kind = EventResume
+			}
+			logSessionEvent(kind, today, session, ev.Elapsed)
+			lastSaved = ev.Elapsed
+		case TimerPause:
+			logSessionEvent(EventPause, today, session, ev.Elapsed)
+			save(ev.Elapsed)
+		case TimerResume:
+			logSessionEvent(EventResume, today, session, ev.Elapsed)
+		case TimerTick:
+			if ev.State == TimerRunning && ev.Elapsed-lastSaved >= int(PROGRESS_SAVE_INTERVAL.Seconds()) {
+				save(ev.Elapsed)
+			}
+		}
+	}
+}
+
+// feedTimer translates typed lines into timer commands via keys. The returned
+// stop function must be called once the timer is done; after it returns the
+// caller owns cmdChan again (for the post-session questions).
+func feedTimer(cmdChan <-chan command, keys map[string]TimerCommand, help string) (<-chan TimerCommand, func()) {
+	commands := make(chan TimerCommand)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-done:
+				return
+			case cmd := <-cmdChan:
+				tc, ok := keys[cmd.action]
+				if !ok {
+					if cmd.action != "" {
+						fmt.Printf("Invalid command. Options: %s. ", help)
+					}
+					continue
+				}
+				select {
+				case commands <- tc:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return commands, func() {
+		close(done)
+		<-exited
+	}
+}
+
+var studyTimerKeys = map[string]TimerCommand{
+	"p": TimerPause,
+	"r": TimerResume,
+	"f": TimerFinish,
+	"m": TimerMiss,
+	"o": TimerToggleMusic,
+}
+
+var breakTimerKeys = map[string]TimerCommand{
+	"p": TimerPause,
+	"r": TimerResume,
+	"q": TimerFinish,
+}
+
+func inputReader(cmdChan chan<- command) {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		input, _ := reader.ReadString('\n')
+		raw := strings.TrimSpace(input)
+		cmdChan <- command{action: strings.ToLower(raw), raw: raw}
+	}
+}
+
+func runStudyTimer(sessions []Session, sessionIndex int, initialElapsed int, today time.Time) (bool, []Session) {
+	session := &sessions[sessionIndex]
+	totalSeconds := int(session.Duration * 3600)
+	timer := NewTimer(totalSeconds, initialElapsed, clock)
+
+	if initialElapsed == 0 {
+		fmt.Printf("\n[START] Starting %s session for %.1f hrs (Total: %d seconds). Press 'p' to pause.\n", session.Type, session.Duration, totalSeconds)
+	} else {
+		fmt.Printf("\n[RESUME] Resuming %s session. %s/%s complete. Press 'p' to pause.\n", session.Type, time.Duration(initialElapsed)*time.Second, time.Duration(totalSeconds)*time.Second)
+	}
+	fmt.Printf("[Timer] %s\n", sessionTitle(*session))
+
+	musicOn := true
+	startMusic()
+
+	timer.Observe(sessionRecorder(today, *session))
+	timer.Observe(func(ev TimerEvent) {
+		switch ev.Command {
+		case TimerToggleMusic:
+			if musicOn {
+				stopMusic()
+				musicOn = false
+				fmt.Println("\n[ACTION] Music OFF. Press 'o' to turn music back on. (Timer continues)")
+			} else {
+				startMusic()
+				musicOn = true
+				fmt.Println("\n[ACTION] Music On. Press 'o' to turn music back off. (Timer continues)")
+			}
+		case TimerPause:
+			if musicOn {
+				pauseMusic()
+			}
+			fmt.Print("\n[ACTION] Paused. Enter 'r' to resume, 'f' to finish early, or 'm' to mark missed. ")
+		case TimerResume:
+			if musicOn {
+				resumeMusic()
+			}
+			fmt.Printf("\033[2K\r[TIMER] Remaining: %s | Status: RUNNING  ", time.Duration(ev.Remaining)*time.Second)
+		case TimerFinish:
+			fmt.Println("\n[ACTION] Session finished early/forced completion.")
+		case TimerMiss:
+			fmt.Println("\n[ACTION] Session marked as MISSED. This will be rescheduled.")
+		case TimerStart, TimerTick:
+			fmt.Printf("\033[2K\r[TIMER] Remaining: %s | Status: %s", time.Duration(ev.Remaining)*time.Second, strings.ToUpper(string(ev.State)))
+		}
+	})
+
+	cmdChan := make(chan command)
+	go inputReader(cmdChan)
+	commands, stopFeed := feedTimer(cmdChan, studyTimerKeys, "p, r, f, m, o")
+	ticker := time.NewTicker(time.Second)
+	final := timer.Run(commands, ticker.C)
+	ticker.Stop()
+	stopFeed()
+	if musicOn {
+		stopMusic()
+	}
+
+	if final == TimerMissed {
+		session.Status = "Missed"
+		logSessionEvent(EventMiss, today, *session, timer.Elapsed())
+		deleteProgress()
+		writeDayPlan(today, sessions)
+		adjustWorkload([]Session{*session}, today)
+		return true, sessions
+	}
+
+	if timer.Remaining() == 0 {
+		fmt.Println("\n\n" + ColorGreen + "[COMPLETED] Session finished! Great job. 🔔" + ColorReset)
+	}
+
+	rating := promptSessionRating(cmdChan)
+	grade := RECALL_GRADE_DEFAULT
+	if session.Type == "Revision" {
+		fmt.Print("\n> How well did you recall this chapter? (0 = blank .. 5 = perfect): ")
+		for {
+			g, ok := parseRecallGrade((<-cmdChan).action)
+			if ok {
+				grade = g
+				break
+			}
+			fmt.Print("Please enter a whole number from 0 to 5: ")
+		}
+	}
+
+	focus := timer.Focus()
+	completeSession(sessions, sessionIndex, today, timer.Elapsed(), grade, rating, &focus)
+	return true, sessions
+}
+
+// completeSession records a finished session: the plan entry, the event
+// journal, focus statistics (when the timer observed them) and the chapter's
+// workload. Shared by the study timer and the non-interactive CLI.
+func completeSession(sessions []Session, idx int, today time.Time, elapsedSeconds int, grade int, rating *SessionRating, focus *FocusMetrics) {
+	session := &sessions[idx]
+	session.Status = "Completed"
+	totalSeconds := int(session.Duration * 3600)
+	elapsedSeconds = min(elapsedSeconds, totalSeconds)
+
+	if focus != nil {
+		recordFocus(today, *session, *focus)
+	}
+
+	kind := EventComplete
+	switch {
+	case elapsedSeconds < totalSeconds:
+		kind = EventEarlyFinish
+	case session.Type == "Revision":
+		kind = EventRevision
+	}
+	event := newSessionEvent(kind, today, *session, elapsedSeconds)
+	if session.Type == "Revision" {
+		event.RecallGrade = &grade
+	}
+	event.Rating = rating
+	event.Focus = focus
+	if err := appendEvent(event); err != nil {
+		fmt.Fprintln(logOut, "\n[WARN] Could not write to event log:", err)
+	}
+
+	if session.ChapterID != "" {
+		state, _ := loadState()
+		if workload, ok := state.Workload[session.ChapterID]; ok {
+			workload = applyCompletedSession(workload, *session, elapsedSeconds, today, grade, rating)
+			state.Workload[session.ChapterID] = workload
+			saveState(state)
+		}
+	}
+	deleteProgress()
+	writeDayPlan(today, sessions)
+}
+
+// Rebalance is what marking sessions of one day as missed would do: the day's
+// plan with those sessions marked and the re-planned schedule. Nothing is
+// written until Commit.
+type Rebalance struct {
+	Date     time.Time
+	Before   []Session // the day's plan as it is now
+	Sessions []Session // the day's plan with the sessions marked Missed
+	Missed   []Session
+	Plan     *SchedulePlan
+}
+
+// planMissSessions computes the effect of missing the given pending
+// study/revision sessions of a day. It returns nil if none of them qualify.
+func planMissSessions(sessions []Session, indices []int, today time.Time) *Rebalance {
+	r := &Rebalance{Date: today, Before: sessions, Sessions: append([]Session(nil), sessions...)}
+	for _, i := range indices {
+		s := &r.Sessions[i]
+		if s.Status != "Pending" || (s.Type != "Study" && s.Type != "Revision") {
+			continue
+		}
+		s.Status = "Missed"
+		r.Missed = append(r.Missed, *s)
+	}
+	if len(r.Missed) == 0 {
+		return nil
+	}
+	r.Plan = planAdjustment(r.Missed, today)
+	return r
+}
+
+// Commit writes the re-balance and returns the day's plan as re-read.
+func (r *Rebalance) Commit() []Session {
+	for _, s := range r.Missed {
+		logSessionEvent(EventMiss, r.Date, s, 0)
+	}
+	writeDayPlan(r.Date, r.Sessions)
+	markMissedSessions()
+	if r.Plan != nil {
+		commitSchedule(r.Plan)
+		fmt.Fprintln(logOut, "[ADJUSTMENT] Schedule successfully updated and re-balanced.")
+	}
+	if reloaded, err := readDayPlan(r.Date); err == nil {
+		return reloaded
+	}
+	return r.Sessions
+}
+
+// Changes lists the day's own status changes followed by the re-planned days.
+func (r *Rebalance) Changes() []DayPlanDiff {
+	changes := []DayPlanDiff{diffDayPlan(r.Date, r.Before, r.Sessions)}
+	if r.Plan != nil {
+		changes = append(changes, r.Plan.Changes...)
+	}
+	return changes
+}
+
+// missSessions marks the given pending study/revision sessions of a day as
+// missed, re-balances the schedule and returns the re-read plan.
+func missSessions(sessions []Session, indices []int, today time.Time) []Session {
+	r := planMissSessions(sessions, indices, today)
+	if r == nil {
+		return sessions
+	}
+	return r.Commit()
+}
+
+func runBreakTimer(durationMins int) {
+	timer := NewTimer(durationMins*60, 0, clock)
+	timer.Observe(func(ev TimerEvent) {
+		switch ev.Command {
+		case TimerFinish:
+			fmt.Println("\n[ACTION] Break skipped.")
+		case TimerPause:
+			fmt.Print("\n[ACTION] Break Paused. Enter 'r' to resume. ")
+		case TimerResume:
+			fmt.Print("\n[ACTION] Break Resumed. ")
+		case TimerTick:
+			fmt.Printf("\033[2K\r[TIMER] Break Remaining: %s | Status: %s ", time.Duration(ev.Remaining)*time.Second, strings.ToUpper(string(ev.State)))
+		}
+	})
+
+	cmdChan := make(chan command)
+	go inputReader(cmdChan)
+	commands, stopFeed := feedTimer(cmdChan, breakTimerKeys, "q, p, r")
+	fmt.Printf("\n" + ColorCyan + "[BREAK] Starting %d minute break. Press 'q' to skip, 'p' to pause. ☕️" + ColorReset + "\n", durationMins)
+	ticker := time.NewTicker(time.Second)
+	timer.Run(commands, ticker.C)
+	ticker.Stop()
+	stopFeed()
+	if timer.Remaining() == 0 {
+		fmt.Println("\n\n" + ColorGreen + "[BREAK] Break finished! Time to select your next session." + ColorReset)
+	}
+}
+
+// prepareToday audits days since the last run for missed sessions, re-balances
+// or regenerates as needed and returns today's plan. Shared by the timer CLI
+// and the TUI.
+func prepareToday(realToday time.Time) ([]Session, error) {
+	state, _ := loadState()
+
+	lastScheduled, _ := time.Parse(TIME_FORMAT, state.LastScheduledDate)
+	missedSessionsAcrossDays := []Session{}
+
+	for d := lastScheduled; d.Before(realToday); d = d.AddDate(0, 0, 1) {
+		missed, err := processMissedSessionsForDate(d)
+		if err == nil && len(missed) > 0 {
+			fmt.Fprintf(logOut, "[AUDIT] Found %d missed sessions on %s. Adjusting workload.\n", len(missed), d.Format(TIME_FORMAT))
+			missedSessionsAcrossDays = append(missedSessionsAcrossDays, missed...)
+		}
+	}
+
+	if len(missedSessionsAcrossDays) > 0 {
+		fmt.Fprintf(logOut, "[RE-BALANCING] Total %d missed sessions detected. Adjusting workload and regenerating path from TODAY (%s)...\n", len(missedSessionsAcrossDays), realToday.Format(TIME_FORMAT))
+		adjustWorkload(missedSessionsAcrossDays, realToday.AddDate(0, 0, -1))
+	} else if lastScheduled.Before(realToday.AddDate(0, 0, 1)) {
+		fmt.Fprintln(logOut, "[RE-BALANCING] Schedule is behind. Regenerating path to ensure today is planned.")
+		state.LastScheduledDate = realToday.Format(TIME_FORMAT)
+		saveState(state)
+		generateSchedule()
+	}
+
+	return readDayPlan(realToday)
+}
+
+func runTimerCLI() {
+	rawConfig = loadConfig()
+	realToday := currentDay()
+	fmt.Printf("\n--- Timer CLI for %s ---\n", realToday.Format(TIME_FORMAT))
+
+	sessions, err := prepareToday(realToday)
+	if err != nil {
+		fmt.Printf("[ERROR] Could not load today's schedule. Run '3' (RE-GENERATE) first: %v\n", err)
+		return
+	}
+
+	progress, foundProgress := loadProgress(realToday)
+	reader := bufio.NewReader(os.Stdin)
+
+	if foundProgress {
+		sessionIndexToResume := -1
+		for i, s := range sessions {
+			if s.ChapterID == progress.ChapterID {
+				sessionIndexToResume = i
+				break
+			}
+		}
+		if sessionIndexToResume != -1 {
+			sessionToResume := sessions[sessionIndexToResume]
+			fmt.Printf("\n" + ColorYellow + "[RESUME ALERT] Unfinished session found for %s - %s (%s elapsed)." + ColorReset + "\n",
+				sessionToResume.Subject, sessionToResume.Chapter, time.Duration(progress.ElapsedSeconds)*time.Second)
+			fmt.Print("Do you want to resume this session? (y/N): ")
+			input, _ := reader.ReadString('\n')
+			input = strings.TrimSpace(strings.ToLower(input))
+
+			if input == "y" {
+				finished, updatedSessions := runStudyTimer(sessions, sessionIndexToResume, progress.ElapsedSeconds, realToday)
+				sessions = updatedSessions
+				if finished && (sessions[sessionIndexToResume].Type == "Study" || sessions[sessionIndexToResume].Type == "Revision") && sessions[sessionIndexToResume].Status == "Completed" {
+					runBreakTimer(BREAK_MINUTES)
+				}
+			} else {
+				fmt.Println("\n[ACTION] Marking interrupted session as MISSED and rescheduling.")
+				sessions[sessionIndexToResume].Status = "Missed"
+				logSessionEvent(EventMiss, realToday, sessions[sessionIndexToResume], progress.ElapsedSeconds)
+				writeDayPlan(realToday, sessions)
+				adjustWorkload([]Session{sessions[sessionIndexToResume]}, realToday)
+				deleteProgress()
+
+				sessions, _ = readDayPlan(realToday) 
+			}
+		} else {
+			fmt.Println("[WARNING] Progress file found but chapter ID mismatch. Deleting progress file.")
+			deleteProgress()
+		}
+	}
+
+	for {
+		fmt.Println("\n-- Today's Schedule --")
+		hasPending := false
+
+		for i, s := range sessions {
+			if s.Type == "Study" || s.Type == "Revision" {
+				hasPending = hasPending || (s.Status == "Pending")
+				status := s.Status
+				if status == "Pending" {
+					status = ColorCyan + status + ColorReset
+				} else if status == "Completed" {
+					status = ColorGreen + status + ColorReset
+				} else if status == "Missed" {
+					status = ColorRed + status + ColorReset
+				}
+				fmt.Printf("[%d] %s%.1f hrs | %s: %s (%s)\n", i+1, sessionTime(s), s.Duration, s.Subject, sessionTitle(s), status)
+			}
+		}
+		if !hasPending {
+			fmt.Println("\n[INFO] All Study/Revision sessions complete for today. Press 'q' to quit.")
+		}
+		due := dueSession(sessions, clock.Now())
+		if due != -1 {
+			s := sessions[due]
+			fmt.Printf("\n"+ColorCyan+"[NOW] Due: [%d] %s%s: %s. Press Enter to start it."+ColorReset+"\n", due+1, sessionTime(s), s.Subject, sessionTitle(s))
+		}
+
+		fmt.Print("\n> Enter session number to START, 'm' to mark all PENDING as MISSED, 's' to see all, or 'q' to quit: ")
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(strings.ToLower(input))
+		if input == "" && due != -1 {
+			input = strconv.Itoa(due + 1)
+		}
+
+		if input == "q" {
+			break
+		}
+
+		if input == "s" {
+			fmt.Println("\n-- All Sessions (Including Buffer/Rest) --")
+			for i, s := range sessions {
+				fmt.Printf("[%d] %s%.1f hrs | %s: %s (%s, %s)\n", i+1, sessionTime(s), s.Duration, s.Subject, sessionTitle(s), s.Type, s.Status)
+			}
+			continue
+		}
+
+		if input == "m" && hasPending {
+			pending := []int{}
+			for i, s := range sessions {
+				if s.Status == "Pending" && (s.Type == "Study" || s.Type == "Revision") {
+					pending = append(pending, i)
+				}
+			}
+			if r := planMissSessions(sessions, pending, realToday); r != nil {
+				r.Preview()
+				if confirm(reader, fmt.Sprintf("\n> Mark %d pending study/revision sessions as MISSED and apply this re-balance?", len(r.Missed))) {
+					sessions = r.Commit()
+				} else {
+					fmt.Println("[INFO] Nothing changed.")
+				}
+			} else {
+				fmt.Println("[INFO] No pending study/revision sessions to mark as missed.")
+			}
+			continue
+		}
+
+		sessionIndex, err := strconv.Atoi(input)
+		if err != nil || sessionIndex < 1 || sessionIndex > len(sessions) {
+			fmt.Println("[ERROR] Invalid input. Please enter a valid session number or command ('m', 's', 'q').")
+			continue
+		}
+
+		sessionIdx := sessionIndex - 1
+		session := &sessions[sessionIdx]
+
+		if session.Status != "Pending" {
+			fmt.Printf("[INFO] Session is already %s. Select another.\n", session.Status)
+			continue
+		}
+
+		finished, updatedSessions := runStudyTimer(sessions, sessionIdx, 0, realToday)
+		sessions = updatedSessions
+
+		if finished && (session.Type == "Study" || session.Type == "Revision") && session.Status == "Completed" {
+			runBreakTimer(BREAK_MINUTES)
+		}
+	}
+
+	fmt.Println("\n[INFO] Exiting timer. Any unfinished session progress has been saved.")
+}
+
+// REPORT_SCHEMA_VERSION versions the machine-readable (json/csv) output of
+// report, plan and stats. Fields are only ever added within a version.
+const REPORT_SCHEMA_VERSION = 1
+
+// Output formats accepted by --format.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// ReportData is the json form of the full report. Chapter lists carry the
+// ChapterWorkload objects from the schedule state unchanged:
+//
+//	pending_study        chapters with initial study left, by priority
+//	revisions_due        chapters whose next revision is today or earlier
+//	upcoming_revisions   chapters with a future revision, soonest first
+//	finished             chapters with study done and no revision left
+//
+// feasibility is the Feasibility of finishing by the syllabus end date.
+type ReportData struct {
+	SchemaVersion         int               `json:"schema_version"`
+	GeneratedAt           string            `json:"generated_at"` // RFC 3339
+	SyllabusEndDate       string            `json:"syllabus_end_date"`
+	ExamDate              string            `json:"exam_date"`
+	NetStudyDays          int               `json:"net_study_days"`
+	TotalWeightedWorkload float64           `json:"total_weighted_workload"`
+	TotalRemainingHours   float64           `json:"total_remaining_hours"`
+	DailyQuotaWT          float64           `json:"daily_quota_wt"`
+	ChaptersTotal         int               `json:"chapters_total"`
+	ChaptersCompleted     int               `json:"chapters_completed"`
+	CompletionPercent     float64           `json:"completion_percent"`
+	PendingStudy          []ChapterWorkload `json:"pending_study"`
+	RevisionsDue          []ChapterWorkload `json:"revisions_due"`
+	UpcomingRevisions     []ChapterWorkload `json:"upcoming_revisions"`
+	Finished              []ChapterWorkload `json:"finished"`
+	Feasibility           Feasibility       `json:"feasibility"`
+	Blocked               []BlockedChapter  `json:"blocked"` // pending chapters waiting on prerequisites
+	Calendar              []CalendarOverride `json:"calendar"` // overrides with days still to come
+}
+
+// DayPlanData is the json form of one day plan; sessions are in plan order.
+type DayPlanData struct {
+	SchemaVersion int       `json:"schema_version"`
+	Date          string    `json:"date"`
+	Weekday       string    `json:"weekday"`
+	Sessions      []Session `json:"sessions"`
+}
+
+// PerformanceData is the json form of performance_state.json.
+type PerformanceData struct {
+	SchemaVersion int `json:"schema_version"`
+	PerformanceState
+}
+
+func validFormat(format string) bool {
+	return format == FormatText || format == FormatJSON || format == FormatCSV
+}
+
+func writeJSONOutput(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func buildReport(state *ScheduleState, today time.Time) ReportData {
+	feasibility := analyzeFeasibility(*state, today)
+	allChapters := calculateQuotas(state)
+	report := ReportData{
+		SchemaVersion:         REPORT_SCHEMA_VERSION,
+		GeneratedAt:           clock.Now().Format(time.RFC3339),
+		SyllabusEndDate:       rawConfig.SyllabusEndDate,
+		ExamDate:              rawConfig.ExamDate,
+		NetStudyDays:          state.NetStudyDays,
+		TotalWeightedWorkload: state.TotalWeightedWorkload,
+		TotalRemainingHours:   state.TotalRemainingTime,
+		DailyQuotaWT:          state.DailyQuotaWT,
+		ChaptersTotal:         len(allChapters),
+		PendingStudy:          []ChapterWorkload{},
+		RevisionsDue:          []ChapterWorkload{},
+		UpcomingRevisions:     []ChapterWorkload{},
+		Finished:              []ChapterWorkload{},
+		Feasibility:           feasibility,
+		Blocked:               blockedChapters(*state),
+		Calendar:              upcomingOverrides(rawConfig, today),
+	}
+
+	for _, wl := range allChapters {
+		if wl.IsStudyCompleted {
+			report.ChaptersCompleted++
+		}
+		if !wl.IsStudyCompleted && wl.RemainingTime > 0.001 {
+			report.PendingStudy = append(report.PendingStudy, wl)
+		} else if wl.IsStudyCompleted && hasRevisionsLeft(wl) {
+			revDate, _ := time.Parse(TIME_FORMAT, wl.NextRevisionDate)
+			if !revDate.After(today) {
+				report.RevisionsDue = append(report.RevisionsDue, wl)
+			} else {
+				report.UpcomingRevisions = append(report.UpcomingRevisions, wl)
+			}
+		} else if wl.IsStudyCompleted {
+			report.Finished = append(report.Finished, wl)
+		}
+	}
+
+	sort.Slice(report.PendingStudy, func(i, j int) bool { return report.PendingStudy[i].PriorityScore > report.PendingStudy[j].PriorityScore })
+	sort.Slice(report.RevisionsDue, func(i, j int) bool { return report.RevisionsDue[i].PriorityScore > report.RevisionsDue[j].PriorityScore })
+	sort.Slice(report.UpcomingRevisions, func(i, j int) bool {
+		dateI, _ := time.Parse(TIME_FORMAT, report.UpcomingRevisions[i].NextRevisionDate)
+		dateJ, _ := time.Parse(TIME_FORMAT, report.UpcomingRevisions[j].NextRevisionDate)
+		return dateI.Before(dateJ)
+	})
+	sort.Slice(report.Finished, func(i, j int) bool { return report.Finished[i].ID < report.Finished[j].ID })
+
+	report.CompletionPercent = 100.0
+	if report.ChaptersTotal > 0 {
+		report.CompletionPercent = float64(report.ChaptersCompleted) / float64(report.ChaptersTotal) * 100
+	}
+	return report
+}
+
+func runFullReport() {
+	if err := runReport(FormatText); err != nil {
+		fmt.Fprintln(os.Stderr, "[ERROR]", err)
+	}
+}
+
+func runReport(format string) error {
+	rawConfig = loadConfig()
+	if format == FormatText {
+		fmt.Println("\n--- FULL PROGRESS REPORT ---")
+	}
+	state, _ := loadState()
+	today := currentDay()
+	report := buildReport(&state, today)
+
+	switch format {
+	case FormatJSON:
+		return writeJSONOutput(report)
+	case FormatCSV:
+		return writeReportCSV(report)
+	}
+
+	if len(state.Workload) == 0 {
+		fmt.Println("[INFO] No workload initialized. Please run option [3] RE-GENERATE first.")
+		return nil
+	}
+
+	if report.TotalWeightedWorkload < 0.001 && len(report.RevisionsDue) == 0 {
+		fmt.Printf("🎯 Syllabus Target Date: %s (Net Study Days Remaining: %d)\n", rawConfig.SyllabusEndDate, report.NetStudyDays)
+		fmt.Println("⏳ Total Remaining Workload: 0.00 WT (0.0 Study Hrs)")
+		fmt.Println("📅 Required Daily Quota: 0.00 WT (Weighted Time)")
+		fmt.Println("-----------------------------------------------------------------")
+		fmt.Println("🎉 All initial study and scheduled revisions are complete!")
+		fmt.Println("-----------------------------------------------------------------")
+		return nil
+	}
+
+	fmt.Printf("🎯 Syllabus Target Date: %s (Net Study Days Remaining: %d)\n", rawConfig.SyllabusEndDate, report.NetStudyDays)
+	if first, last, ok := examPhaseWindow(); ok {
+		fmt.Printf("🏁 Exam Date: %s (exam phase %s to %s: revision cycles, mock tests, taper)\n", rawConfig.ExamDate, first.Format(TIME_FORMAT), last.Format(TIME_FORMAT))
+	}
+	fmt.Printf("⏳ Total Remaining Workload: %.2f WT (%.1f Study Hrs)\n", report.TotalWeightedWorkload, report.TotalRemainingHours)
+	fmt.Printf("📅 Required Daily Quota: %.2f WT (Weighted Time)\n", report.DailyQuotaWT)
+	fmt.Println("-----------------------------------------------------------------")
+
+	fmt.Println("\n⚖️  FEASIBILITY")
+	printFeasibility(os.Stdout, report.Feasibility)
+	for _, problem := range validateRotationPolicy(rawConfig) {
+		fmt.Println(ColorYellow + "[ROTATION] " + problem + ColorReset)
+	}
+	for _, problem := range validateActivityMix(rawConfig) {
+		fmt.Println(ColorYellow + "[CONFIG] " + problem + ColorReset)
+	}
+	for _, problem := range validateTimetable(rawConfig) {
+		fmt.Println(ColorYellow + "[TIMETABLE] " + problem + ColorReset)
+	}
+	for _, problem := range validateCalendar(rawConfig) {
+		fmt.Println(ColorYellow + "[CALENDAR] " + problem + ColorReset)
+	}
+
+	if len(report.Calendar) > 0 {
+		fmt.Println("\n📆 CALENDAR OVERRIDES")
+		for _, o := range report.Calendar {
+			fmt.Printf("  - %s\n", o)
+		}
+	}
+
+	fmt.Println("\n📚 PENDING INITIAL STUDY (Sorted by Priority)")
+	if len(report.PendingStudy) == 0 {
+		fmt.Println("  -> All initial study complete! Time for revision phase.")
+	} else {
+		for _, wl := range report.PendingStudy {
+			topics := ""
+			if len(wl.SubTopics) > 0 {
+				done := 0
+				for _, t := range wl.SubTopics {
+					if t.Completed {
+						done++
+					}
+				}
+				topics = fmt.Sprintf(" | %d/%d topics", done, len(wl.SubTopics))
+			}
+			fmt.Printf("  - [Prio: %.2f | %.1f hrs left%s] %s: %s (Diff: %.1f)\n", wl.PriorityScore, wl.RemainingTime, topics, wl.Subject, wl.Chapter, wl.Difficulty)
+		}
+	}
+
+	if len(report.Blocked) > 0 {
+		fmt.Println("\n⛔ BLOCKED BY PREREQUISITES (not started until these are studied)")
+		for _, b := range report.Blocked {
+			var waiting []string
+			for _, id := range b.WaitingFor {
+				p := state.Workload[id]
+				waiting = append(waiting, fmt.Sprintf("%s %s (%.1f hrs left)", id, p.Chapter, p.RemainingTime))
+			}
+			fmt.Printf("  - %s: %s waits for %s\n", b.Subject, b.Chapter, strings.Join(waiting, ", "))
+		}
+	}
+
+	fmt.Println("\n🔄 REVISIONS DUE TODAY")
+	if len(report.RevisionsDue) == 0 {
+		fmt.Println("  -> No revisions are currently due for today.")
+	} else {
+		for _, wl := range report.RevisionsDue {
+			fmt.Printf("  - [DUE | %s] %s: %s (Priority: %.2f)\n", revisionLabel(wl), wl.Subject, wl.Chapter, wl.PriorityScore)
+		}
+	}
+
+	fmt.Println("\n📅 UPCOMING REVISIONS")
+	if len(report.UpcomingRevisions) == 0 {
+		fmt.Println("  -> No upcoming revisions scheduled.")
+	} else {
+		for i, wl := range report.UpcomingRevisions {
+			if i >= 3 {
+				break
+			}
+			fmt.Printf("  - [Next: %s | %s] %s: %s\n", wl.NextRevisionDate, revisionLabel(wl), wl.Subject, wl.Chapter)
+		}
+		if len(report.UpcomingRevisions) > 3 {
+			fmt.Printf("  ... and %d more upcoming revisions.\n", len(report.UpcomingRevisions)-3)
+		}
+	}
+
+	if weak := weakChapters(state); len(weak) > 0 {
+		fmt.Println("\n🧪 WEAKEST IN MOCK TESTS")
+		for i, wl := range weak {
+			if i >= 3 {
+				fmt.Printf("  ... and %d more (see 'mock list').\n", len(weak)-3)
+				break
+			}
+			fmt.Printf("  - [Weakness %.2f | Diff: %.1f] %s: %s\n", wl.MockWeakness, wl.Difficulty, wl.Subject, wl.Chapter)
+		}
+	}
+
+	fmt.Println("\n-----------------------------------------------------------------")
+	fmt.Printf(ColorGreen+"✅ Overall Chapter Completion: %.1f%% (%d of %d chapters)"+ColorReset+"\n", report.CompletionPercent, report.ChaptersCompleted, report.ChaptersTotal)
+	fmt.Println("-----------------------------------------------------------------")
+	return nil
+}
+
+// writeReportCSV writes one row per chapter; the section column is one of the
+// ReportData list names.
+func writeReportCSV(report ReportData) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"section", "id", "subject", "chapter", "remaining_hours", "difficulty", "weightage", "priority_score", "revision_count", "next_revision_date"})
+	sections := []struct {
+		name     string
+		chapters []ChapterWorkload
+	}{
+		{"pending_study", report.PendingStudy},
+		{"revisions_due", report.RevisionsDue},
+		{"upcoming_revisions", report.UpcomingRevisions},
+		{"finished", report.Finished},
+	}
+	for _, section := range sections {
+		for _, wl := range section.chapters {
+			w.Write([]string{
+				section.name, wl.ID, wl.Subject, wl.Chapter,
+				formatCSVFloat(wl.RemainingTime), formatCSVFloat(wl.Difficulty), formatCSVFloat(wl.Weightage), formatCSVFloat(wl.PriorityScore),
+				strconv.Itoa(wl.RevisionCount), wl.NextRevisionDate,
+			})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func writeDayPlanOutput(date time.Time, sessions []Session, format string) error {
+	switch format {
+	case FormatJSON:
+		return writeJSONOutput(DayPlanData{
+			SchemaVersion: REPORT_SCHEMA_VERSION,
+			Date:          date.Format(TIME_FORMAT),
+			Weekday:       date.Weekday().String(),
+			Sessions:      sessions,
+		})
+	case FormatCSV:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"index", "date", "subject", "chapter", "duration", "chapter_id", "type", "status", "topics", "activity", "start", "end"})
+		for i, s := range sessions {
+			w.Write([]string{strconv.Itoa(i + 1), date.Format(TIME_FORMAT), s.Subject, s.Chapter, formatCSVFloat(s.Duration), s.ChapterID, s.Type, s.Status, strings.Join(s.Topics, "; "), s.Activity, s.Start, s.End})
+		}
+		w.Flush()
+		return w.Error()
+	}
+	printDayPlan(date, sessions)
+	return nil
+}
+
+func writePerformanceOutput(perf PerformanceState, format string) error {
+	switch format {
+	case FormatJSON:
+		return writeJSONOutput(PerformanceData{SchemaVersion: REPORT_SCHEMA_VERSION, PerformanceState: perf})
+	case FormatCSV:
+		// scope is "overall", "subject" or "day"; key names the subject or date.
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"scope", "key", "metric", "value"})
+		w.Write([]string{"overall", "", "total_sessions", strconv.Itoa(perf.TotalSessions)})
+		w.Write([]string{"overall", "", "completed_sessions", strconv.Itoa(perf.CompletedSessions)})
+		w.Write([]string{"overall", "", "missed_sessions", strconv.Itoa(perf.MissedSessions)})
+		w.Write([]string{"overall", "", "consistency_factor", formatCSVFloat(perf.ConsistencyFactor)})
+		w.Write([]string{"overall", "", "average_focus_score", formatCSVFloat(perf.AverageFocusScore)})
+		for _, scope := range []struct {
+			name string
+			aggs map[string]FocusAggregate
+		}{{"subject", perf.SubjectFocus}, {"day", perf.DailyFocus}} {
+			keys := make([]string, 0, len(scope.aggs))
+			for k := range scope.aggs {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				w.Write([]string{scope.name, k, "focus_average", formatCSVFloat(scope.aggs[k].Average)})
+				w.Write([]string{scope.name, k, "focus_sessions", strconv.Itoa(scope.aggs[k].Sessions)})
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+	printPerformance(perf)
+	return nil
+}
+
+func printPerformance(perf PerformanceState) {
+	fmt.Println("\n--- PERFORMANCE REPORT ---")
+	fmt.Printf("Total Sessions Tracked : %d\n", perf.TotalSessions)
+	fmt.Printf("Completed Sessions     : %d\n", perf.CompletedSessions)
+	fmt.Printf("Missed Sessions        : %d\n", perf.MissedSessions)
+	fmt.Printf("Consistency Factor     : %.1f%%\n", perf.ConsistencyFactor*100.0)
+	fmt.Printf("Average Focus (score)  : %.2f (%d sessions)\n", perf.AverageFocusScore, perf.FocusSessions)
+	if len(perf.SubjectFocus) > 0 {
+		fmt.Println("Focus by Subject:")
+		subjects := make([]string, 0, len(perf.SubjectFocus))
+		for subject := range perf.SubjectFocus {
+			subjects = append(subjects, subject)
+		}
+		sort.Strings(subjects)
+		for _, subject := range subjects {
+			agg := perf.SubjectFocus[subject]
+			fmt.Printf("  - %-10s : %.2f (%d sessions)\n", subject, agg.Average, agg.Sessions)
+		}
+	}
+	if agg, ok := perf.DailyFocus[currentDay().Format(TIME_FORMAT)]; ok {
+		fmt.Printf("Today's Focus          : %.2f (%d sessions)\n", agg.Average, agg.Sessions)
+	}
+	fmt.Println()
+}
+
+func readFloat(reader *bufio.Reader, prompt string, defaultValue float64) float64 {
+	fmt.Printf("%s (Current: %.1f): ", prompt, defaultValue)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return defaultValue
+	}
+	val, err := strconv.ParseFloat(input, 64)
+	if err != nil {
+		fmt.Println("[ERROR] Invalid number format. Using current value.")
+		return defaultValue
+	}
+	return val
+}
+
+func readInt(reader *bufio.Reader, prompt string, defaultValue int) int {
+	fmt.Printf("%s (Current: %d): ", prompt, defaultValue)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return defaultValue
+	}
+	val, err := strconv.Atoi(input)
+	if err != nil {
+		fmt.Println("[ERROR] Invalid integer format. Using current value.")
+		return defaultValue
+	}
+	return val
+}
+func runDownloader() error {
+
+	if !isCommandAvailable("yt-dlp") || !isCommandAvailable("ffmpeg") {
+		return fmt.Errorf("external dependencies missing. Please install 'yt-dlp' and 'ffmpeg' using pacman")
+	}
+	OUTPUT_DIR :="study_music"
+
+	if err := os.MkdirAll(OUTPUT_DIR, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", OUTPUT_DIR, err)
+	}
+
+	fmt.Println(ColorYellow + "\n--- Music Downloader ---" + ColorReset)
+	fmt.Printf("Downloads will be saved to the './%s' folder.\n", OUTPUT_DIR)
+
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Print("\nEnter YouTube URL: ")
+
+	url, _ := reader.ReadString('\n')
+	url = strings.TrimSpace(url)
+
+	if url == "" {
+		fmt.Println("No URL entered. Returning to Main Menu...")
+		return nil
+	}
+
+	return downloadMusic(url)
+}
+
+// downloadMusic fetches url with yt-dlp and stores it as MP3 in study_music/.
+func downloadMusic(url string) error {
+	if !isCommandAvailable("yt-dlp") || !isCommandAvailable("ffmpeg") {
+		return fmt.Errorf("external dependencies missing. Please install 'yt-dlp' and 'ffmpeg' using pacman")
+	}
+	OUTPUT_DIR := "study_music"
+	if err := os.MkdirAll(OUTPUT_DIR, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", OUTPUT_DIR, err)
+	}
+
+	fmt.Printf("Processing URL: %s\n", url)
+	fmt.Printf("Downloading and converting audio to MP3 (192K quality)...\n")
+
+	cmd := exec.Command("yt-dlp",
+		"-x", 
+		"--audio-format", "mp3",
+		"--audio-quality", "192K",
+
+		"--output", fmt.Sprintf("%s/%%(title)s.%%(ext)s", OUTPUT_DIR),
+		url,
+	)
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("\n❌ Download/Conversion failed for %s. Check yt-dlp output above.\n", url)
+		return fmt.Errorf("yt-dlp failed: %w", err)
+	}
+
+	fmt.Printf("\n✅ Success! MP3 file saved in the '%s' directory.\n\n", OUTPUT_DIR)
+
+	return nil
+}
+
+func isCommandAvailable(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func readDate(reader *bufio.Reader, prompt string, defaultValue string) string {
+	fmt.Printf("%s (Format YYYY-MM-DD, Current: %s): ", prompt, defaultValue)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return defaultValue
+	}
+	_, err := time.Parse(TIME_FORMAT, input)
+	if err != nil {
+		fmt.Println("[ERROR] Invalid date format. Using current value.")
+		return defaultValue
+	}
+	return input
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	dayNames := map[string]time.Weekday{
+		"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
+		"wednesday": time.Wednesday, "thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	}
+	day, ok := dayNames[strings.TrimSpace(strings.ToLower(name))]
+	return day, ok
+}
+
+func readWeekday(reader *bufio.Reader, prompt string, defaultValue time.Weekday) time.Weekday {
+	fmt.Printf("%s (Current: %s): ", prompt, defaultValue)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(strings.ToLower(input))
+	if input == "" {
+		return defaultValue
+	}
+	if day, ok := parseWeekday(input); ok {
+		return day
+	}
+	fmt.Println("[ERROR] Invalid day. Enter full day name (e.g., monday). Using current value.")
+	return defaultValue
+}
+
+func promptConfig(currentConfig Config) Config {
+	reader := bufio.NewReader(os.Stdin)
+	newConfig := currentConfig
+	configChanged := false
+
+	fmt.Println("\n--- Configure Scheduler Parameters ---")
+
+	newSyllabusEndDate := readDate(reader, "Syllabus Completion Target Date", newConfig.SyllabusEndDate)
+	if newSyllabusEndDate != newConfig.SyllabusEndDate { configChanged = true }
+	newConfig.SyllabusEndDate = newSyllabusEndDate
+
+	newExamDate := readDate(reader, "Final Exam Date (exam-phase revision and mock tests run up to it)", newConfig.ExamDate)
+	if newExamDate != newConfig.ExamDate { configChanged = true }
+	newConfig.ExamDate = newExamDate
+
+	newDailyStudyHrs := readFloat(reader, "Total Daily Study Hours (Excluding Buffer/Breaks)", newConfig.DailyStudyHrs)
+	if newDailyStudyHrs != newConfig.DailyStudyHrs { configChanged = true }
+	newConfig.DailyStudyHrs = newDailyStudyHrs
+
+	newMaxSessionHrs := readFloat(reader, "Maximum Hours per Single Session", newConfig.MaxSessionHrs)
+	if newMaxSessionHrs != newConfig.MaxSessionHrs { configChanged = true }
+	newConfig.MaxSessionHrs = newMaxSessionHrs
+
+	newDailyBufferMins := readInt(reader, "Daily Buffer/Review Time (in minutes)", newConfig.DailyBufferMins)
+	if newDailyBufferMins != newConfig.DailyBufferMins { configChanged = true }
+	newConfig.DailyBufferMins = newDailyBufferMins
+
+	newWeeklyRestDay := readWeekday(reader, "Weekly Rest Day (e.g., sunday)", newConfig.WeeklyRestDay)
+	if newWeeklyRestDay != newConfig.WeeklyRestDay { configChanged = true }
+	newConfig.WeeklyRestDay = newWeeklyRestDay
+
+	newRestDayActivity := newConfig.RestDayActivity
+	fmt.Printf("Rest Day Activity (Current: %s): ", newConfig.RestDayActivity)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input != "" && input != newConfig.RestDayActivity {
+		newRestDayActivity = input
+		configChanged = true
+	}
+	newConfig.RestDayActivity = newRestDayActivity
+
+	newSubjectsPerDay := readInt(reader, "Subjects per Day", newConfig.Rotation.subjectsPerDay())
+	if newSubjectsPerDay != newConfig.Rotation.subjectsPerDay() {
+		newConfig.Rotation.SubjectsPerDay = newSubjectsPerDay
+		configChanged = true
+	}
+
+	newRepeatAfterDays := readInt(reader, "Days Before a Subject Repeats (1 = any day)", newConfig.Rotation.repeatAfterDays())
+	if newRepeatAfterDays != newConfig.Rotation.repeatAfterDays() {
+		newConfig.Rotation.RepeatAfterDays = newRepeatAfterDays
+		configChanged = true
+	}
+	for _, problem := range validateRotationPolicy(newConfig) {
+		fmt.Println(ColorYellow + "[ROTATION] " + problem + ColorReset)
+	}
+
+	if configChanged {
+		rawConfig = newConfig
+		saveConfig(rawConfig)
+		deleteScheduleState() 
+		fmt.Printf("\n[INFO] Configuration updated and saved. Max Session Hrs is now: %.1f hrs.\n", rawConfig.MaxSessionHrs)
+		fmt.Println("Please run [3] RE-GENERATE to calculate a new study path using these settings.")
+	} else {
+		fmt.Println("\n[INFO] No configuration changes detected. Schedule state retained.")
+	}
+	return newConfig
+}
+
+func runMainMenu() {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		rawConfig = loadConfig()
+		adoptConfigSubTopics()
+		fmt.Println("\n--- Adaptive NEET Scheduler Menu ---")
+		fmt.Println(ColorGreen + "[1] Start TIMER CLI (Daily Study)" + ColorReset)
+		fmt.Println(ColorBlue + "[2] View FULL REPORT(Syllabus Status)" + ColorReset)
+		fmt.Println(ColorYellow + "[3] RE-GENERATE Schedule (Initialize or Re-balance)" + ColorReset)
+		fmt.Println("[4] CHANGE CONFIGURATION (Dates, Times, etc.)")
+		fmt.Println("[5] Music Download")
+		fmt.Println("[7] Full-screen TIMER (TUI)")
+		fmt.Println("[8] Log MOCK TEST result")
+		fmt.Println("[q] Quit")
+		fmt.Print("\n> Enter your choice: ")
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(strings.ToLower(input))
+		switch input {
+		case "1":
+			runTimerCLI()
+		case "2", "report":
+			runFullReport()
+		case "3", "generate":
+			fmt.Println("\n[ACTION] Running Schedule Generation...")
+			previewAndGenerate(reader)
+		case "4", "config":
+			promptConfig(rawConfig)
+		case "5":
+
+			if err := runDownloader(); err != nil {
+				fmt.Fprintf(os.Stderr, "\n[CRITICAL ERROR] Downloader failed: %v\n", err)
+			}
+		case "6" :
+			perf := PerformanceState{}
+			if err := loadJSON(PERFORMANCE_FILE, &perf); err != nil {
+				fmt.Println("[INFO] No performance data yet. Run some sessions first.")
+			} else {
+				printPerformance(perf)
+			}
+		case "7", "tui":
+			if err := runTUI(); err != nil {
+				fmt.Println("[ERROR]", err)
+			}
+		case "8", "mock":
+			promptMockTest(reader)
+		case "q":
+			stopMusic()
+			fmt.Println("\nExiting application. Goodbye! 👋")
+			return
+		default:
+			fmt.Println("[ERROR] Invalid choice. Please enter '1', '2', '3', '4', or 'q'.")
+		}
+	}
+}
+
+// ------------------ Full-Screen TUI ------------------
+
+const (
+	TUI_BAR_WIDTH       = 40
+	TUI_UPCOMING_LIMIT  = 5
+	keyUp               = -1
+	keyDown             = -2
+	tuiModeBrowse       = "browse"
+	tuiModeUnderstanding = "understanding"
+	tuiModeFocus        = "focus"
+	tuiModeGrade        = "grade"
+)
+
+// tuiModel is everything the TUI renders. It drives the same plan, timer and
+// workload functions as runTimerCLI; only input and output differ.
+type tuiModel struct {
+	today    time.Time
+	sessions []Session
+	cursor   int
+	active   *activeSession
+	musicOn  bool
+	mode     string
+	message  string
+	upcoming []ChapterWorkload // revisions shown; reloaded after each action
+
+	// Collected between the end of a session and completeSession.
+	doneElapsed int
+	doneFocus   FocusMetrics
+	rating      *SessionRating
+}
+
+// enterRawMode switches the terminal to unbuffered, no-echo input and returns a
+// function restoring the previous settings. Reads time out every 100ms so the
+// key reader can notice when the TUI exits.
+func enterRawMode() (func(), error) {
+	saved, err := sttyOutput("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stty not available: %w", err)
+	}
+	if _, err := sttyOutput("-icanon", "-echo", "min", "0", "time", "1"); err != nil {
+		return nil, err
+	}
+	return func() { sttyOutput(strings.TrimSpace(saved)) }, nil
+}
+
+func sttyOutput(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readKeys delivers key presses until done is closed. Arrow keys arrive as
+// keyUp/keyDown, everything else as its byte value.
+func readKeys(keys chan<- int, done <-chan struct{}) {
+	buf := make([]byte, 16)
+	var pending []byte
+	send := func(key int) bool {
+		select {
+		case keys <- key:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		n, _ := os.Stdin.Read(buf)
+		pending = append(pending, buf[:n]...)
+		for len(pending) > 0 {
+			if pending[0] == 27 && len(pending) >= 3 && pending[1] == '[' {
+				key := 0
+				switch pending[2] {
+				case 'A':
+					key = keyUp
+				case 'B':
+					key = keyDown
+				}
+				if key != 0 && !send(key) {
+					return
+				}
+				pending = pending[3:]
+				continue
+			}
+			if pending[0] == 27 && len(pending) < 3 && n > 0 {
+				break // wait for the rest of the escape sequence
+			}
+			if !send(int(pending[0])) {
+				return
+			}
+			pending = pending[1:]
+		}
+	}
+}
+
+func runTUI() error {
+	rawConfig = loadConfig()
+	m := &tuiModel{today: currentDay(), mode: tuiModeBrowse}
+	sessions, err := prepareToday(m.today)
+	if err != nil {
+		return fmt.Errorf("could not load today's schedule: %w", err)
+	}
+	m.sessions = sessions
+	m.cursor = m.firstPending()
+	m.loadUpcoming()
+
+	restore, err := enterRawMode()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	fmt.Print("\033[?25l")       // hide cursor
+	defer fmt.Print("\033[?25h") // show cursor
+
+	keys := make(chan int)
+	done := make(chan struct{})
+	defer close(done)
+	go readKeys(keys, done)
+
+	// Ctrl+C or a kill quits like 'q', so the deferred terminal restore runs.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	m.render()
+	for {
+		select {
+		case key := <-keys:
+			if !m.handleKey(key) {
+				m.quit()
+				return nil
+			}
+		case <-signals:
+			m.quit()
+			return nil
+		case <-ticker.C:
+			if m.active != nil && m.mode == tuiModeBrowse {
+				m.active.timer.Apply(TimerTick)
+				if m.active.timer.Done() {
+					m.endSession()
+				}
+			}
+		}
+		m.render()
+	}
+}
+
+// quit pauses the active session, which saves its progress, and clears the
+// screen. A session whose timer has already stopped is waiting for its rating,
+// so it is completed with whatever was rated so far.
+func (m *tuiModel) quit() {
+	if m.active != nil && m.mode != tuiModeBrowse {
+		if m.mode == tuiModeFocus {
+			m.rating.Focus = 3 // the default of 'complete --focus'
+		}
+		m.finish(RECALL_GRADE_DEFAULT)
+	}
+	if m.active != nil {
+		m.active.timer.Apply(TimerPause)
+	}
+	if m.musicOn {
+		stopMusic()
+	}
+	fmt.Print("\033[H\033[2J")
+	fmt.Println("[INFO] Exiting TUI. Any unfinished session progress has been saved.")
+}
+
+func (m *tuiModel) firstPending() int {
+	for i, s := range m.sessions {
+		if s.Status == "Pending" && (s.Type == "Study" || s.Type == "Revision") {
+			return i
+		}
+	}
+	return 0
+}
+
+// handleKey applies one key press and reports whether the TUI keeps running.
+func (m *tuiModel) handleKey(key int) bool {
+	switch m.mode {
+	case tuiModeUnderstanding, tuiModeFocus, tuiModeGrade:
+		if key == 'q' {
+			return false
+		}
+		m.handleRatingKey(key)
+		return true
+	}
+
+	switch key {
+	case 'q':
+		return false
+	case keyUp, 'k':
+		if m.active == nil && m.cursor > 0 {
+			m.cursor--
+		}
+	case keyDown, 'j':
+		if m.active == nil && m.cursor < len(m.sessions)-1 {
+			m.cursor++
+		}
+	case '\n', '\r', ' ':
+		m.startSelected()
+	case 'p':
+		if m.active == nil {
+			break
+		}
+		if m.active.timer.Apply(TimerPause) {
+			if m.musicOn {
+				pauseMusic()
+			}
+		} else if m.active.timer.Apply(TimerResume) {
+			if m.musicOn {
+				resumeMusic()
+			}
+		}
+	case 'f':
+		if m.active != nil {
+			m.endSession()
+		}
+	case 'm':
+		if m.active != nil {
+			idx := m.active.SessionIndex - 1
+			m.active.timer.Apply(TimerMiss)
+			m.active = nil
+			m.stopSessionMusic()
+			deleteProgress()
+			m.sessions = missSessions(m.sessions, []int{idx}, m.today)
+			m.loadUpcoming()
+			m.message = "Session marked as MISSED and rescheduled."
+		}
+	case 'o':
+		if m.active != nil {
+			m.active.timer.Apply(TimerToggleMusic)
+		}
+		if m.musicOn {
+			stopMusic()
+			m.musicOn = false
+		} else {
+			startMusic()
+			m.musicOn = true
+		}
+	}
+	return true
+}
+
+func (m *tuiModel) startSelected() {
+	if m.active != nil || m.cursor >= len(m.sessions) {
+		return
+	}
+	s := m.sessions[m.cursor]
+	if s.Type != "Study" && s.Type != "Revision" {
+		m.message = "Only study and revision sessions can be timed."
+		return
+	}
+	if s.Status != "Pending" {
+		m.message = fmt.Sprintf("Session is already %s.", s.Status)
+		return
+	}
+	m.active = newActiveSession(m.today, m.sessions, m.cursor)
+	if !m.musicOn {
+		startMusic()
+		m.musicOn = true
+	}
+	m.message = ""
+}
+
+func (m *tuiModel) stopSessionMusic() {
+	if m.musicOn {
+		stopMusic()
+		m.musicOn = false
+	}
+}
+
+// endSession stops the clock and moves on to the self-assessment keys.
+func (m *tuiModel) endSession() {
+	m.doneElapsed, m.doneFocus = m.active.stop()
+	m.stopSessionMusic()
+	m.rating = nil
+	m.mode = tuiModeUnderstanding
+}
+
+func (m *tuiModel) handleRatingKey(key int) {
+	score := key - '0'
+	switch m.mode {
+	case tuiModeUnderstanding:
+		if key == 's' || key == '\n' || key == '\r' {
+			m.afterRating()
+		} else if score >= 1 && score <= 5 {
+			m.rating = &SessionRating{Understanding: score}
+			m.mode = tuiModeFocus
+		}
+	case tuiModeFocus:
+		if score >= 1 && score <= 5 {
+			m.rating.Focus = score
+			m.afterRating()
+		}
+	case tuiModeGrade:
+		if score >= 0 && score <= 5 {
+			m.finish(score)
+		}
+	}
+}
+
+func (m *tuiModel) afterRating() {
+	if m.active.Session.Type == "Revision" {
+		m.mode = tuiModeGrade
+		return
+	}
+	m.finish(RECALL_GRADE_DEFAULT)
+}
+
+func (m *tuiModel) finish(grade int) {
+	idx := m.active.SessionIndex - 1
+	completeSession(m.sessions, idx, m.today, m.doneElapsed, grade, m.rating, &m.doneFocus)
+	m.loadUpcoming()
+	m.active = nil
+	m.mode = tuiModeBrowse
+	m.cursor = m.firstPending()
+	m.message = fmt.Sprintf("Session completed. Take a %d minute break.", BREAK_MINUTES)
+}
+
+func progressBar(fraction float64, width int) string {
+	fraction = math.Max(0, math.Min(1, fraction))
+	filled := int(math.Round(fraction * float64(width)))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+func (m *tuiModel) render() {
+	var sb strings.Builder
+	sb.WriteString("\033[H\033[2J")
+	music := ColorRed + "OFF" + ColorReset
+	if m.musicOn {
+		music = ColorGreen + "ON" + ColorReset
+	}
+	sb.WriteString(fmt.Sprintf(ColorCyan+"ADAPTIVE NEET SCHEDULER — %s (%s)"+ColorReset+"    Music: %s\n", m.today.Format(TIME_FORMAT), m.today.Weekday(), music))
+
+	doneHrs, leftHrs := 0.0, 0.0
+	for i, s := range m.sessions {
+		if s.Type != "Study" && s.Type != "Revision" {
+			continue
+		}
+		switch {
+		case s.Status == "Completed":
+			doneHrs += s.Duration
+		case s.Status == "Pending" && m.active != nil && m.active.SessionIndex == i+1:
+			spent := float64(m.active.timer.Elapsed()) / 3600.0
+			doneHrs += spent
+			leftHrs += math.Max(0, s.Duration-spent)
+		case s.Status == "Pending":
+			leftHrs += s.Duration
+		}
+	}
+	sb.WriteString(fmt.Sprintf("Today: %.1f hrs done | %.1f hrs remaining\n", doneHrs, leftHrs))
+	sb.WriteString(strings.Repeat("-", 72) + "\n")
+
+	for i, s := range m.sessions {
+		pointer := "  "
+		if i == m.cursor && m.active == nil {
+			pointer = "> "
+		}
+		status := s.Status
+		switch status {
+		case "Pending":
+			status = ColorCyan + status + ColorReset
+		case "Completed":
+			status = ColorGreen + status + ColorReset
+		case "Missed":
+			status = ColorRed + status + ColorReset
+		}
+		if m.active != nil && m.active.SessionIndex == i+1 {
+			pointer = "▶ "
+			status = ColorYellow + strings.ToUpper(string(m.active.timer.State())) + ColorReset
+		}
+		sb.WriteString(fmt.Sprintf("%s[%d] %s%.2fh %-8s %s: %s (%s)\n", pointer, i+1, sessionTime(s), s.Duration, s.Type, s.Subject, sessionTitle(s), status))
+	}
+	sb.WriteString(strings.Repeat("-", 72) + "\n")
+
+	if m.active != nil {
+		elapsed := m.active.timer.Elapsed()
+		fraction := float64(elapsed) / float64(max(1, m.active.TotalSeconds))
+		remaining := time.Duration(m.active.TotalSeconds-elapsed) * time.Second
+		sb.WriteString(fmt.Sprintf("ACTIVE: %s: %s [%s]\n", m.active.Session.Subject, m.active.Session.Chapter, strings.ToUpper(string(m.active.timer.State()))))
+		sb.WriteString(fmt.Sprintf("%s %3.0f%%  %s left\n", progressBar(fraction, TUI_BAR_WIDTH), fraction*100, remaining))
+	} else {
+		sb.WriteString("No active session.\n")
+	}
+	sb.WriteString(strings.Repeat("-", 72) + "\n")
+
+	sb.WriteString("Upcoming revisions:\n")
+	if len(m.upcoming) == 0 {
+		sb.WriteString("  -> None scheduled.\n")
+	}
+	for _, wl := range m.upcoming {
+		sb.WriteString(fmt.Sprintf("  %s  %s: %s\n", wl.NextRevisionDate, wl.Subject, wl.Chapter))
+	}
+	sb.WriteString(strings.Repeat("-", 72) + "\n")
+
+	switch m.mode {
+	case tuiModeUnderstanding:
+		sb.WriteString(ColorYellow + "Understanding of the material? 1-5 (s to skip rating)" + ColorReset + "\n")
+	case tuiModeFocus:
+		sb.WriteString(ColorYellow + "Focus during the session? 1-5" + ColorReset + "\n")
+	case tuiModeGrade:
+		sb.WriteString(ColorYellow + "How well did you recall this chapter? 0 = blank .. 5 = perfect" + ColorReset + "\n")
+	default:
+		sb.WriteString("↑/↓ or j/k select · Enter start · p pause/resume · f finish · m miss · o music · q quit\n")
+	}
+	if m.message != "" {
+		sb.WriteString(m.message + "\n")
+	}
+	fmt.Print(sb.String())
+}
+
+// loadUpcoming reads the next revisions from the state. render runs every
+// second, so it is called only at start-up and after a session ends.
+func (m *tuiModel) loadUpcoming() {
+	state, _ := loadState()
+	var upcoming []ChapterWorkload
+	for _, wl := range state.Workload {
+		if wl.IsStudyCompleted && hasRevisionsLeft(wl) {
+			upcoming = append(upcoming, wl)
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].NextRevisionDate < upcoming[j].NextRevisionDate })
+	if len(upcoming) > TUI_UPCOMING_LIMIT {
+		upcoming = upcoming[:TUI_UPCOMING_LIMIT]
+	}
+	m.upcoming = upcoming
+}
+
+// ------------------ Simulation ------------------
+
+const (
+	SIM_DEFAULT_COMPLETION = 0.85
+	SIM_DEFAULT_SEED       = 1
+)
+
+// manualClock is a Clock that only moves when told to.
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time { return c.now }
+
+func (c *manualClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+type SimulationResult struct {
+	StartDate         string  `json:"start_date"`
+	EndDate           string  `json:"end_date"`
+	Days              int     `json:"days"`
+	Seed              int64   `json:"seed"`
+	CompletionRate    float64 `json:"completion_rate"`
+	SessionsCompleted int     `json:"sessions_completed"`
+	SessionsMissed    int     `json:"sessions_missed"`
+	SyllabusEndDate   string  `json:"syllabus_end_date"`
+	SyllabusFinished  bool    `json:"syllabus_finished"`
+	FinishDate        string  `json:"finish_date,omitempty"`
+	RemainingHours    float64 `json:"remaining_hours"`
+	ChaptersLeft      int     `json:"chapters_left"`
+}
+
+// OnTime reports whether initial study of every chapter was done by the
+// configured syllabus end date.
+func (r SimulationResult) OnTime() bool {
+	return r.SyllabusFinished && r.FinishDate <= r.SyllabusEndDate
+}
+
+// runSimulation plays the schedule forward day by day on a copy of the data
+// files: each pending study or revision session is completed with probability
+// completionRate and missed otherwise, and the normal audit/re-balance path
+// runs every morning. The real files are never touched. Passing days <= 0
+// simulates through the syllabus end date.
+func runSimulation(days int, completionRate float64, seed int64, fresh bool) (SimulationResult, error) {
+	result := SimulationResult{Seed: seed, CompletionRate: completionRate, SyllabusEndDate: rawConfig.SyllabusEndDate}
+
+	sandbox, err := os.MkdirTemp("", "neet-simulate-")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(sandbox)
+	files := []string{CONFIG_FILE}
+	if !fresh {
+		files = append(files, STATE_FILE, PERFORMANCE_FILE)
+		if dates, err := planStore.Dates(); err == nil {
+			for _, d := range dates {
+				files = append(files, planStore.Path(d))
+			}
+		}
+	}
+	for _, f := range files {
+		if err := copyFile(f, filepath.Join(sandbox, f)); err != nil && !os.IsNotExist(err) {
+			return result, err
+		}
+	}
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		return result, err
+	}
+	if err := os.Chdir(sandbox); err != nil {
+		return result, err
+	}
+	defer os.Chdir(origDir)
+
+	// The scheduler narrates everything it does; only the summary matters here.
+	realLog := logOut
+	logOut = io.Discard
+	defer func() { logOut = realLog }()
+
+	realClock := clock
+	sim := &manualClock{now: currentDay()}
+	clock = sim
+	defer func() { clock = realClock }()
+	seedRandom(seed)
+	draws := rand.New(rand.NewSource(seed))
+
+	start := currentDay()
+	if days <= 0 {
+		end, err := time.Parse(TIME_FORMAT, rawConfig.SyllabusEndDate)
+		if err != nil {
+			return result, fmt.Errorf("invalid syllabus_end_date: %w", err)
+		}
+		days = int(end.Sub(start).Hours()/24) + 1
+	}
+	result.StartDate = start.Format(TIME_FORMAT)
+
+	for day := 0; day < days; day++ {
+		today := currentDay()
+		sessions, err := prepareToday(today)
+		if err == nil {
+			missed := []int{}
+			for i, s := range sessions {
+				if s.Status != "Pending" || (s.Type != "Study" && s.Type != "Revision") {
+					continue
+				}
+				if draws.Float64() < completionRate {
+					completeSession(sessions, i, today, int(s.Duration*3600), RECALL_GRADE_DEFAULT, nil, nil)
+					result.SessionsCompleted++
+				} else {
+					missed = append(missed, i)
+					result.SessionsMissed++
+				}
+			}
+			missSessions(sessions, missed, today)
+		}
+
+		if !result.SyllabusFinished {
+			state, _ := loadState()
+			if allChaptersStudied(state) {
+				result.SyllabusFinished = true
+				result.FinishDate = today.Format(TIME_FORMAT)
+			}
+		}
+		result.EndDate = today.Format(TIME_FORMAT)
+		result.Days++
+		sim.Advance(24 * time.Hour)
+	}
+
+	state, _ := loadState()
+	for _, wl := range state.Workload {
+		if !wl.IsStudyCompleted {
+			result.ChaptersLeft++
+			result.RemainingHours += wl.RemainingTime
+		}
+	}
+	return result, nil
+}
+
+func allChaptersStudied(state ScheduleState) bool {
+	for _, wl := range state.Workload {
+		if !wl.IsStudyCompleted {
+			return false
+		}
+	}
+	return len(state.Workload) > 0
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+func printSimulation(r SimulationResult) {
+	fmt.Printf("\n--- Simulation (seed %d, %.0f%% of sessions completed) ---\n", r.Seed, r.CompletionRate*100)
+	fmt.Printf("Simulated %s .. %s (%d days): %d sessions completed, %d missed.\n",
+		r.StartDate, r.EndDate, r.Days, r.SessionsCompleted, r.SessionsMissed)
+	end, _ := time.Parse(TIME_FORMAT, r.SyllabusEndDate)
+	switch {
+	case r.OnTime():
+		done, _ := time.Parse(TIME_FORMAT, r.FinishDate)
+		fmt.Printf(ColorGreen+"[SUCCESS] Syllabus finished on %s, %d days before the syllabus end date (%s)."+ColorReset+"\n",
+			r.FinishDate, int(end.Sub(done).Hours()/24), r.SyllabusEndDate)
+	case r.SyllabusFinished:
+		done, _ := time.Parse(TIME_FORMAT, r.FinishDate)
+		fmt.Printf(ColorYellow+"[WARNING] Syllabus finished on %s, %d days AFTER the syllabus end date (%s)."+ColorReset+"\n",
+			r.FinishDate, int(done.Sub(end).Hours()/24), r.SyllabusEndDate)
+	default:
+		fmt.Printf(ColorRed+"[WARNING] Syllabus NOT finished by %s: %.1f hrs of initial study left across %d chapters."+ColorReset+"\n",
+			r.EndDate, r.RemainingHours, r.ChaptersLeft)
+	}
+}
+
+// ------------------ Command Line Interface ------------------
+
+// Exit codes returned by runCommand.
+const (
+	ExitOK    = 0
+	ExitError = 1 // the command ran but failed
+	ExitUsage = 2 // bad subcommand, flag or argument
+)
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: scheduler [command] [flags]
+
+Without a command the interactive menu starts.
+
+Commands:
+  generate [--dry-run]          Regenerate the schedule from the current state
+                                (--dry-run: only show the per-day changes)
+  report [--format F]           Print the full progress report
+  stats [--format F]            Print performance statistics
+  today [--date YYYY-MM-DD] [--format F]
+                                List the sessions planned for a day
+  start [n]                     Run the study timer for session n of today
+                                (default: the session due now by the timetable)
+  complete <n> [flags]          Mark session n of today as completed
+      --minutes M                 minutes actually studied (default: full session)
+      --grade G                   recall grade 0-5 for revisions (default 4)
+      --understanding U --focus F --notes TEXT   self-assessment (1-5)
+  miss <n> [--dry-run]          Mark session n of today as missed and re-balance
+  tui                           Full-screen timer for today (single-key controls)
+  config get [key]              Print the config, or one key of it
+  config set <key> <value>      Change one config key (JSON name, e.g. daily_study_hrs)
+  plan show <date> [--format F] Print the plan for a date (YYYY-MM-DD, today, tomorrow)
+  music download <url>          Download a track into study_music/
+  serve [--addr HOST:PORT]      Serve the JSON API on localhost (default `+DEFAULT_API_ADDR+`)
+      --allow-remote              allow a non-loopback --addr (the API has no authentication)
+  simulate [flags]              Fast-forward a copy of the schedule and report whether
+                                the syllabus finishes by syllabus_end_date
+      --days N                    days to simulate (default: through syllabus_end_date)
+      --complete P                chance each session is completed (default 0.85)
+      --seed S                    random seed (default 1); --fresh starts from scratch
+      --format F                  text (default) or json
+  mock log [flags]              Record a mock test result
+      --subject S=M/MAX           marks per subject, e.g. Physics=120/180 (repeatable)
+      --chapter ID=M/MAX[:E]      marks and errors on one chapter's questions, or ID=:E
+                                  for errors only, e.g. PH002=8/16:3 (repeatable)
+      --date D --name TEXT        test date (default today) and label
+  mock list [--format F]        List recorded mock tests and the weakest chapters
+  topic list <chapter-id>       Show a chapter's sub-topics and their progress
+  topic done <chapter-id> <n|name>
+                                Mark a sub-topic as covered
+  calendar list                 Show the date-specific overrides
+  calendar add <date> <kind>    Override a day: rest, sick, half, exam or travel
+      --to D                      last day of a range (inclusive)
+      --hours H --note TEXT       study hours that day and a label for the plan
+  calendar remove <date>        Drop the override starting on date
+  help                          Show this message
+
+--format is text (default), json or csv. The json/csv schemas are versioned
+by schema_version; see ReportData, DayPlanData, PerformanceData and
+MockTestsData.`)
+}
+
+// runCommand dispatches a non-interactive subcommand and returns the process
+// exit code.
+func runCommand(args []string) int {
+	// Loading the config can already print, so the format is looked up
+	// before any command parses its flags.
+	if format := formatArg(args); format != "" && format != FormatText {
+		logOut = os.Stderr
+	}
+	rawConfig = loadConfig()
+	adoptConfigSubTopics()
+	name, rest := args[0], args[1:]
+	switch name {
+	case "generate":
+		return cmdGenerate(rest)
+	case "report":
+		return cmdReport(rest)
+	case "stats":
+		return cmdStats(rest)
+	case "today":
+		return cmdToday(rest)
+	case "start":
+		return cmdStart(rest)
+	case "complete":
+		return cmdComplete(rest)
+	case "miss":
+		return cmdMiss(rest)
+	case "config":
+		return cmdConfig(rest)
+	case "plan":
+		return cmdPlan(rest)
+	case "music":
+		return cmdMusic(rest)
+	case "serve":
+		return cmdServe(rest)
+	case "simulate":
+		return cmdSimulate(rest)
+	case "mock":
+		return cmdMock(rest)
+	case "topic":
+		return cmdTopic(rest)
+	case "calendar":
+		return cmdCalendar(rest)
+	case "tui":
+		if err := runTUI(); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return ExitError
+		}
+		return ExitOK
+	case "help", "-h", "--help":
+		printUsage()
+		return ExitOK
+	}
+	fmt.Fprintf(os.Stderr, "[ERROR] Unknown command %q.\n\n", name)
+	printUsage()
+	return ExitUsage
+}
+
+func usageError(format string, a ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "[ERROR] "+format+"\n", a...)
+	return ExitUsage
+}
+
+// parseDayArg accepts YYYY-MM-DD, "today" or "tomorrow".
+func parseDayArg(value string) (time.Time, error) {
+	today := currentDay()
+	switch strings.ToLower(value) {
+	case "", "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+	return time.Parse(TIME_FORMAT, value)
+}
+
+// todaySession loads today's plan and validates a 1-based session number.
+func todaySession(arg string) ([]Session, int, time.Time, int) {
+	today := currentDay()
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, 0, today, usageError("Session number must be an integer, got %q.", arg)
+	}
+	sessions, err := readDayPlan(today)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return nil, 0, today, ExitError
+	}
+	if n < 1 || n > len(sessions) {
+		return nil, 0, today, usageError("Session %d does not exist; today has %d sessions.", n, len(sessions))
+	}
+	idx := n - 1
+	if sessions[idx].Type != "Study" && sessions[idx].Type != "Revision" {
+		return nil, 0, today, usageError("Session %d is a %s block, not a study or revision session.", n, sessions[idx].Type)
+	}
+	if sessions[idx].Status != "Pending" {
+		fmt.Fprintf(os.Stderr, "[ERROR] Session %d is already %s.\n", n, sessions[idx].Status)
+		return nil, 0, today, ExitError
+	}
+	return sessions, idx, today, ExitOK
+}
+
+func printDayPlan(date time.Time, sessions []Session) {
+	fmt.Printf("Plan for %s (%s)\n", date.Format(TIME_FORMAT), date.Weekday())
+	for i, s := range sessions {
+		id := s.ChapterID
+		if id == "" {
+			id = "-"
+		}
+		fmt.Printf("[%d] %s%.2f hrs | %-8s | %-9s | %-5s | %s: %s\n", i+1, sessionTime(s), s.Duration, s.Type, s.Status, id, s.Subject, sessionTitle(s))
+	}
+}
+
+// formatFlag registers the shared --format flag on a subcommand.
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", FormatText, "output format: text, json or csv")
+}
+
+// formatArg returns the value of a --format flag anywhere in args, or "".
+func formatArg(args []string) string {
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if value, ok := strings.CutPrefix(name, "format="); ok {
+			return value
+		}
+		if name == "format" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func checkFormat(format string) int {
+	if !validFormat(format) {
+		return usageError("Unknown --format %q; use text, json or csv.", format)
+	}
+	return ExitOK
+}
+
+func cmdReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	format := formatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if code := checkFormat(*format); code != ExitOK {
+		return code
+	}
+	if err := runReport(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+func cmdStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	format := formatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if code := checkFormat(*format); code != ExitOK {
+		return code
+	}
+	perf := PerformanceState{}
+	if err := loadJSON(PERFORMANCE_FILE, &perf); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return ExitError
+	}
+	if err := writePerformanceOutput(perf, *format); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+func showDayPlan(date time.Time, format string) int {
+	sessions, err := readDayPlan(date)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return ExitError
+	}
+	if err := writeDayPlanOutput(date, sessions, format); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+func cmdToday(args []string) int {
+	fs := flag.NewFlagSet("today", flag.ContinueOnError)
+	dateFlag := fs.String("date", "today", "day to show (YYYY-MM-DD, today, tomorrow)")
+	format := formatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if code := checkFormat(*format); code != ExitOK {
+		return code
+	}
+	date, err := parseDayArg(*dateFlag)
+	if err != nil {
+		return usageError("Invalid --date %q: use YYYY-MM-DD.", *dateFlag)
+	}
+	return showDayPlan(date, *format)
+}
+
+func cmdStart(args []string) int {
+	if len(args) > 1 {
+		return usageError("Usage: start [session-number]")
+	}
+	if len(args) == 0 {
+		sessions, err := readDayPlan(currentDay())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return ExitError
+		}
+		due := dueSession(sessions, clock.Now())
+		if due == -1 {
+			fmt.Println("[INFO] No pending study or revision session today.")
+			return ExitOK
+		}
+		fmt.Printf("[NOW] Starting the session due now: [%d] %s%s: %s\n", due+1, sessionTime(sessions[due]), sessions[due].Subject, sessionTitle(sessions[due]))
+		args = []string{strconv.Itoa(due + 1)}
+	}
+	sessions, idx, today, code := todaySession(args[0])
+	if code != ExitOK {
+		return code
+	}
+	_, sessions = runStudyTimer(sessions, idx, 0, today)
+	if sessions[idx].Status == "Missed" {
+		return ExitError
+	}
+	return ExitOK
+}
+
+func cmdComplete(args []string) int {
+	fs := flag.NewFlagSet("complete", flag.ContinueOnError)
+	minutes := fs.Float64("minutes", -1, "minutes actually studied")
+	grade := fs.Int("grade", RECALL_GRADE_DEFAULT, "recall grade 0-5 for revision sessions")
+	understanding := fs.Int("understanding", 0, "self-rated understanding 1-5")
+	focus := fs.Int("focus", 3, "self-rated focus 1-5")
+	notes := fs.String("notes", "", "free-text notes")
+	if err := fs.Parse(reorderFlags(fs, args)); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 {
+		return usageError("Usage: complete <session-number> [flags]")
+	}
+	if *grade < 0 || *grade > 5 {
+		return usageError("--grade must be between 0 and 5.")
+	}
+	if *understanding != 0 && (*understanding < 1 || *understanding > 5 || *focus < 1 || *focus > 5) {
+		return usageError("--understanding and --focus must be between 1 and 5.")
+	}
+	if *understanding == 0 {
+		// A self-assessment is recorded only with an understanding score.
+		rejected := ""
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "focus" || f.Name == "notes" {
+				rejected = f.Name
+			}
+		})
+		if rejected != "" {
+			return usageError("--%s needs --understanding: a self-assessment always includes it.", rejected)
+		}
+	}
+	sessions, idx, today, code := todaySession(fs.Arg(0))
+	if code != ExitOK {
+		return code
+	}
+	elapsed := int(sessions[idx].Duration * 3600)
+	if *minutes >= 0 {
+		elapsed = int(*minutes * 60)
+	}
+	var rating *SessionRating
+	if *understanding != 0 {
+		rating = &SessionRating{Understanding: *understanding, Focus: *focus, Notes: *notes}
+	}
+	completeSession(sessions, idx, today, elapsed, *grade, rating, nil)
+	fmt.Printf("[ACTION] Session %d (%s: %s) marked as Completed.\n", idx+1, sessions[idx].Subject, sessions[idx].Chapter)
+	return ExitOK
+}
+
+func cmdMiss(args []string) int {
+	fs := flag.NewFlagSet("miss", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show the re-balance without applying it")
+	if err := fs.Parse(reorderFlags(fs, args)); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 {
+		return usageError("Usage: miss <session-number> [--dry-run]")
+	}
+	sessions, idx, today, code := todaySession(fs.Arg(0))
+	if code != ExitOK {
+		return code
+	}
+	r := planMissSessions(sessions, []int{idx}, today)
+	if r == nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Session %d is not a pending study or revision session.\n", idx+1)
+		return ExitError
+	}
+	if *dryRun {
+		r.Preview()
+		return ExitOK
+	}
+	fmt.Printf("[ACTION] Marking session %d (%s: %s) as MISSED.\n", idx+1, sessions[idx].Subject, sessions[idx].Chapter)
+	r.Commit()
+	return ExitOK
+}
+
+func cmdGenerate(args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show the per-day changes without writing them")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if !*dryRun {
+		generateSchedule()
+		return ExitOK
+	}
+	state, _ := loadState()
+	if plan := planSchedule(state); plan != nil {
+		printPlanPreview(plan.Changes, nil)
+	}
+	return ExitOK
+}
+
+// reorderFlags moves positional arguments after the flags so that both
+// "complete 2 --grade 5" and "complete --grade 5 2" parse. Boolean flags of fs
+// do not take the following argument as their value.
+func reorderFlags(fs *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		if f := fs.Lookup(strings.TrimLeft(arg, "-")); f != nil {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+				continue
+			}
+		}
+		if !strings.Contains(arg, "=") && i+1 < len(args) {
+			flags = append(flags, args[i+1])
+			i++
+		}
+	}
+	return append(flags, positional...)
+}
+
+func cmdConfig(args []string) int {
+	if len(args) == 0 {
+		return usageError("Usage: config get [key] | config set <key> <value>")
+	}
+	data, _ := json.Marshal(rawConfig)
+	fields := map[string]json.RawMessage{}
+	json.Unmarshal(data, &fields)
+
+	switch args[0] {
+	case "get":
+		if len(args) == 1 {
+			pretty, _ := json.MarshalIndent(rawConfig, "", "  ")
+			fmt.Println(string(pretty))
+			return ExitOK
+		}
+		value, ok := fields[args[1]]
+		if !ok {
+			return usageError("Unknown config key %q.", args[1])
+		}
+		fmt.Println(string(value))
+		return ExitOK
+	case "set":
+		if len(args) != 3 {
+			return usageError("Usage: config set <key> <value>")
+		}
+		newConfig, err := setConfigValue(rawConfig, args[1], args[2])
+		if err != nil {
+			return usageError("%v", err)
+		}
+		rawConfig = newConfig
+		saveConfig(rawConfig)
+		data, _ := json.Marshal(rawConfig)
+		json.Unmarshal(data, &fields)
+		fmt.Printf("[INFO] %s = %s\n", args[1], string(fields[args[1]]))
+		for _, problem := range validateRotationPolicy(rawConfig) {
+			fmt.Println("[ROTATION] " + problem)
+		}
+		fmt.Println("Run 'generate' to re-balance the schedule with the new settings.")
+		return ExitOK
+	}
+	return usageError("Unknown config action %q; use get or set.", args[0])
+}
+
+// setConfigValue returns a copy of c with one scalar key (by its JSON name)
+// changed. value is parsed as JSON when possible, otherwise taken as a string.
+func setConfigValue(c Config, key, value string) (Config, error) {
+	raw := json.RawMessage(value)
+	if !json.Valid(raw) {
+		raw, _ = json.Marshal(value)
+	}
+	return applyConfigJSON(c, key, raw)
+}
+
+// settableConfigKeys are the scalar config keys 'config set' and the API can
+// change. Structured keys (rotation, timetable, calendar, ...) are edited in
+// config.json, where their validators report problems in context.
+var settableConfigKeys = map[string]bool{
+	"syllabus_end_date":          true,
+	"exam_date":                  true,
+	"daily_study_hrs":            true,
+	"max_session_hrs":            true,
+	"daily_buffer_mins":          true,
+	"weekly_rest_day":            true,
+	"rest_day_activity":          true,
+	"initial_difficulty_rating":  true,
+	"difficulty_adjustment_rate": true,
+	"revision_model":             true,
+	"performance_window_days":    true,
+}
+
+// applyConfigJSON returns a copy of c with one scalar key set to a JSON
+// value, which must have the key's type; weekly_rest_day also accepts a day
+// name.
+func applyConfigJSON(c Config, key string, raw json.RawMessage) (Config, error) {
+	if !settableConfigKeys[key] {
+		return c, fmt.Errorf("unknown or non-scalar config key %q", key)
+	}
+	data, _ := json.Marshal(c)
+	fields := map[string]json.RawMessage{}
+	json.Unmarshal(data, &fields)
+
+	if string(raw) == "null" {
+		return c, fmt.Errorf("%s cannot be null", key)
+	}
+	var name string
+	if key == "weekly_rest_day" && json.Unmarshal(raw, &name) == nil {
+		if day, ok := parseWeekday(name); ok {
+			raw = json.RawMessage(strconv.Itoa(int(day)))
+		}
+	}
+	fields[key] = raw
+	updated, _ := json.Marshal(fields)
+	newConfig := Config{}
+	if err := json.Unmarshal(updated, &newConfig); err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			return c, fmt.Errorf("%s must be a %s, not a %s", key, typeErr.Type, typeErr.Value)
+		}
+		return c, fmt.Errorf("invalid value for %s: %v", key, err)
+	}
+	if strings.HasSuffix(key, "_date") {
+		if _, err := time.Parse(TIME_FORMAT, strings.Trim(string(fields[key]), `"`)); err != nil {
+			return c, fmt.Errorf("%s must be a YYYY-MM-DD date", key)
+		}
+	}
+	if err := checkConfigValue(newConfig, key); err != nil {
+		return c, err
+	}
+	return newConfig, nil
+}
+
+// checkConfigValue rejects a value of key in c that the scheduler cannot
+// work with. Only the key being changed is checked, so that one bad value
+// never blocks correcting another.
+func checkConfigValue(c Config, key string) error {
+	switch key {
+	case "daily_study_hrs":
+		if c.DailyStudyHrs <= 0 || c.DailyStudyHrs > 24 {
+			return fmt.Errorf("daily_study_hrs must be more than 0 and at most 24")
+		}
+	case "max_session_hrs":
+		if c.MaxSessionHrs < 0.25 || c.MaxSessionHrs > 24 {
+			return fmt.Errorf("max_session_hrs must be between 0.25 and 24")
+		}
+	case "daily_buffer_mins":
+		if c.DailyBufferMins < 0 || float64(c.DailyBufferMins) >= c.DailyStudyHrs*60 {
+			return fmt.Errorf("daily_buffer_mins must be at least 0 and less than daily_study_hrs (%d mins)", int(c.DailyStudyHrs*60))
+		}
+	case "weekly_rest_day":
+		if c.WeeklyRestDay < time.Sunday || c.WeeklyRestDay > time.Saturday {
+			return fmt.Errorf("weekly_rest_day must be a day name or 0 (Sunday) to 6 (Saturday)")
+		}
+	case "initial_difficulty_rating":
+		if c.InitialDifficultyRating < 1 || c.InitialDifficultyRating > 5 {
+			return fmt.Errorf("initial_difficulty_rating must be between 1 and 5")
+		}
+	case "difficulty_adjustment_rate":
+		if c.DifficultyAdjustmentRate < 0 || c.DifficultyAdjustmentRate > 1 {
+			return fmt.Errorf("difficulty_adjustment_rate must be between 0 and 1")
+		}
+	case "revision_model":
+		switch c.RevisionModel {
+		case RevisionModelFixed, RevisionModelSM2, RevisionModelFSRS:
+		default:
+			return fmt.Errorf("revision_model must be %s, %s or %s", RevisionModelFixed, RevisionModelSM2, RevisionModelFSRS)
+		}
+	case "performance_window_days":
+		if c.PerformanceWindowDays < 0 {
+			return fmt.Errorf("performance_window_days cannot be negative (0 means all time)")
+		}
+	}
+	return nil
+}
+
+func cmdPlan(args []string) int {
+	if len(args) < 1 || args[0] != "show" {
+		return usageError("Usage: plan show <date> [--format F]")
+	}
+	fs := flag.NewFlagSet("plan show", flag.ContinueOnError)
+	format := formatFlag(fs)
+	if err := fs.Parse(reorderFlags(fs, args[1:])); err != nil {
+		return ExitUsage
+	}
+	if code := checkFormat(*format); code != ExitOK {
+		return code
+	}
+	dateArg := "today"
+	if fs.NArg() > 0 {
+		dateArg = fs.Arg(0)
+	}
+	date, err := parseDayArg(dateArg)
+	if err != nil {
+		return usageError("Invalid date %q: use YYYY-MM-DD, today or tomorrow.", dateArg)
+	}
+	return showDayPlan(date, *format)
+}
+
+func cmdServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", DEFAULT_API_ADDR, "listen address")
+	allowRemote := fs.Bool("allow-remote", false, "allow a listen address other than loopback; the API has no authentication")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if !*allowRemote && !isLoopbackHost(*addr) {
+		return usageError("%s is not a loopback address; the API has no authentication, pass --allow-remote to serve it anyway.", *addr)
+	}
+	if err := runServer(*addr, *allowRemote); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+func cmdSimulate(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	days := fs.Int("days", 0, "days to simulate (default: through syllabus_end_date)")
+	completion := fs.Float64("complete", SIM_DEFAULT_COMPLETION, "probability that a session is completed")
+	seed := fs.Int64("seed", SIM_DEFAULT_SEED, "random seed; the same seed reproduces the same run")
+	fresh := fs.Bool("fresh", false, "start from a new state instead of a copy of the current one")
+	format := fs.String("format", FormatText, "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *completion < 0 || *completion > 1 {
+		return usageError("--complete must be between 0 and 1")
+	}
+	if *format != FormatText && *format != FormatJSON {
+		return usageError("Unknown --format %q; use text or json.", *format)
+	}
+	result, err := runSimulation(*days, *completion, *seed, *fresh)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Simulation failed: %v\n", err)
+		return ExitError
+	}
+	if *format == FormatJSON {
+		if err := writeJSONOutput(result); err != nil {
+			return ExitError
+		}
+		return ExitOK
+	}
+	printSimulation(result)
+	return ExitOK
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// MockTestsData is the json form of mock list. weak_chapters are the
+// ChapterWorkload objects with a mock_weakness, weakest first.
+type MockTestsData struct {
+	SchemaVersion int               `json:"schema_version"`
+	Tests         []MockTestResult  `json:"tests"`
+	WeakChapters  []ChapterWorkload `json:"weak_chapters"`
+}
+
+func cmdMock(args []string) int {
+	if len(args) == 0 {
+		return usageError("Usage: mock log [flags] | mock list [--format F]")
+	}
+	switch args[0] {
+	case "log":
+		fs := flag.NewFlagSet("mock log", flag.ContinueOnError)
+		dateArg := fs.String("date", "today", "test date (YYYY-MM-DD, today)")
+		name := fs.String("name", "", "label for the test")
+		var subjects, chapters stringList
+		fs.Var(&subjects, "subject", "Subject=scored/max (repeatable)")
+		fs.Var(&chapters, "chapter", "ID=scored/max[:errors] or ID=:errors (repeatable)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return ExitUsage
+		}
+		date, err := parseDayArg(*dateArg)
+		if err != nil {
+			return usageError("Invalid date %q: use YYYY-MM-DD or today.", *dateArg)
+		}
+		result := MockTestResult{Date: date.Format(TIME_FORMAT), Name: *name}
+		for _, spec := range subjects {
+			score, err := parseSubjectScore(spec)
+			if err != nil {
+				return usageError("%v", err)
+			}
+			result.Subjects = append(result.Subjects, score)
+		}
+		var scores []MockChapterScore
+		for _, spec := range chapters {
+			score, err := parseChapterScore(spec)
+			if err != nil {
+				return usageError("%v", err)
+			}
+			scores = append(scores, score)
+		}
+		state, _ := loadState()
+		if err := addChapterScores(&result, scores, state.Workload); err != nil {
+			return usageError("%v", err)
+		}
+		if len(result.Subjects) == 0 {
+			return usageError("Give at least one --subject or --chapter score.")
+		}
+		if err := recordMockTest(result); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Could not record mock test: %v\n", err)
+			return ExitError
+		}
+		marks, maxMarks := result.Total()
+		fmt.Printf("[MOCK] Recorded %s: %.0f/%.0f, %d chapter scores.\n", result.Date, marks, maxMarks, len(scores))
+		fmt.Println("Run 'generate' to move weak chapters' revisions forward.")
+		return ExitOK
+	case "list":
+		fs := flag.NewFlagSet("mock list", flag.ContinueOnError)
+		format := formatFlag(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return ExitUsage
+		}
+		if code := checkFormat(*format); code != ExitOK {
+			return code
+		}
+		results, err := loadMockTests()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return ExitError
+		}
+		state, _ := loadState()
+		if err := writeMockTestsOutput(results, weakChapters(state), *format); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return ExitError
+		}
+		return ExitOK
+	}
+	return usageError("Unknown mock action %q; use log or list.", args[0])
+}
+
+func writeMockTestsOutput(results []MockTestResult, weak []ChapterWorkload, format string) error {
+	switch format {
+	case FormatJSON:
+		if results == nil {
+			results = []MockTestResult{}
+		}
+		if weak == nil {
+			weak = []ChapterWorkload{}
+		}
+		return writeJSONOutput(MockTestsData{SchemaVersion: REPORT_SCHEMA_VERSION, Tests: results, WeakChapters: weak})
+	case FormatCSV:
+		// One row per subject total and per chapter score; chapter_id is empty
+		// on subject rows.
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"date", "name", "subject", "chapter_id", "marks", "max_marks", "errors"})
+		for _, r := range results {
+			for _, s := range r.Subjects {
+				if s.MaxMarks > 0 {
+					w.Write([]string{r.Date, r.Name, s.Subject, "", formatCSVFloat(s.Marks), formatCSVFloat(s.MaxMarks), ""})
+				}
+				for _, c := range s.Chapters {
+					w.Write([]string{r.Date, r.Name, s.Subject, c.ChapterID, formatCSVFloat(c.Marks), formatCSVFloat(c.MaxMarks), strconv.Itoa(c.Errors)})
+				}
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+	printMockTests(results, weak)
+	return nil
+}
+
+func cmdTopic(args []string) int {
+	if len(args) < 2 || (args[0] != "list" && args[0] != "done") || (args[0] == "done" && len(args) != 3) {
+		return usageError("Usage: topic list <chapter-id> | topic done <chapter-id> <n|name>")
+	}
+	state, _ := loadState()
+	wl, ok := state.Workload[args[1]]
+	if !ok {
+		return usageError("Unknown chapter ID %q.", args[1])
+	}
+	if args[0] == "list" {
+		printSubTopics(wl)
+		return ExitOK
+	}
+
+	name := args[2]
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 1 || n > len(wl.SubTopics) {
+			return usageError("%s has %d sub-topics; pick 1-%d.", wl.ID, len(wl.SubTopics), len(wl.SubTopics))
+		}
+		name = wl.SubTopics[n-1].Name
+	}
+	today := currentDay()
+	wl, err := completeSubTopic(wl, name, today)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	event := newSessionEvent(EventTopic, today, Session{Subject: wl.Subject, Chapter: wl.Chapter, ChapterID: wl.ID, Type: "Study"}, 0)
+	event.Topic = name
+	if err := appendEvent(event); err != nil {
+		fmt.Println("[WARN] Could not write to event log:", err)
+	}
+	state.Workload[wl.ID] = wl
+	if err := saveState(state); err != nil {
+		return ExitError
+	}
+	fmt.Printf("[TOPIC] %s: %q done, %.1f hrs left in the chapter.\n", wl.ID, name, wl.RemainingTime)
+	if wl.IsStudyCompleted {
+		fmt.Printf("[TOPIC] %s study complete; first revision on %s.\n", wl.ID, wl.NextRevisionDate)
+	}
+	fmt.Println("Run 'generate' to re-plan the remaining sub-topics.")
+	return ExitOK
+}
+
+func cmdCalendar(args []string) int {
+	const usage = "Usage: calendar list | calendar add <date> <kind> [--to D] [--hours H] [--note TEXT] | calendar remove <date>"
+	if len(args) == 0 {
+		return usageError(usage)
+	}
+	switch args[0] {
+	case "list":
+		if len(rawConfig.Calendar) == 0 {
+			fmt.Println("[CALENDAR] No overrides; every day but the weekly rest day has daily_study_hrs.")
+			return ExitOK
+		}
+		for i, o := range rawConfig.Calendar {
+			fmt.Printf("  %d. %s\n", i+1, o)
+		}
+		for _, problem := range validateCalendar(rawConfig) {
+			fmt.Println(ColorYellow + "[CALENDAR] " + problem + ColorReset)
+		}
+		return ExitOK
+	case "add":
+		fs := flag.NewFlagSet("calendar add", flag.ContinueOnError)
+		to := fs.String("to", "", "last day of the override, inclusive")
+		hours := fs.Float64("hours", -1, "study hours that day (default: from the kind)")
+		note := fs.String("note", "", "label shown in the plan")
+		if err := fs.Parse(reorderFlags(fs, args[1:])); err != nil {
+			return ExitUsage
+		}
+		if fs.NArg() != 2 {
+			return usageError(usage)
+		}
+		date, err := parseDayArg(fs.Arg(0))
+		if err != nil {
+			return usageError("Invalid date %q: use YYYY-MM-DD, today or tomorrow.", fs.Arg(0))
+		}
+		o := CalendarOverride{Date: date.Format(TIME_FORMAT), Kind: strings.ToLower(fs.Arg(1)), Note: *note}
+		if *to != "" {
+			end, err := parseDayArg(*to)
+			if err != nil {
+				return usageError("Invalid --to date %q.", *to)
+			}
+			o.To = end.Format(TIME_FORMAT)
+		}
+		if *hours >= 0 {
+			o.Hours = hours
+		}
+		if problems := validateCalendar(Config{Calendar: []CalendarOverride{o}}); len(problems) > 0 {
+			return usageError("%s", strings.TrimPrefix(problems[0], "calendar[0]: "))
+		}
+		rawConfig.Calendar = append(rawConfig.Calendar, o)
+		saveConfig(rawConfig)
+		fmt.Printf("[CALENDAR] Added %s\n", o)
+		return replanFrom(date)
+	case "remove":
+		if len(args) != 2 {
+			return usageError(usage)
 		}
-	}
-
-	fmt.Println("\n🔄 REVISIONS DUE TODAY")
-	if len(revisionDueChapters) == 0 {
-		fmt.Println("  -> No revisions are currently due for today.")
-	} else {
-		for _, wl := range revisionDueChapters {
-			fmt.Printf("  - [DUE | Rev #%d of %d] %s: %s (Priority: %.2f)\n", wl.RevisionCount+1, MAX_REVISIONS, wl.Subject, wl.Chapter, wl.PriorityScore)
+		date, err := parseDayArg(args[1])
+		if err != nil {
+			return usageError("Invalid date %q: use YYYY-MM-DD, today or tomorrow.", args[1])
 		}
-	}
-
-	fmt.Println("\n📅 UPCOMING REVISIONS")
-	if len(nextRevisionChapters) == 0 {
-		fmt.Println("  -> No upcoming revisions scheduled.")
-	} else {
-		for i, wl := range nextRevisionChapters {
-			if i >= 3 {
-				break
+		day := date.Format(TIME_FORMAT)
+		kept := []CalendarOverride{}
+		for _, o := range rawConfig.Calendar {
+			if o.Date != day {
+				kept = append(kept, o)
 			}
-			fmt.Printf("  - [Next: %s | Rev #%d of %d] %s: %s\n", wl.NextRevisionDate, wl.RevisionCount+1, MAX_REVISIONS, wl.Subject, wl.Chapter)
 		}
-		if len(nextRevisionChapters) > 3 {
-			fmt.Printf("  ... and %d more upcoming revisions.\n", len(nextRevisionChapters)-3)
+		if len(kept) == len(rawConfig.Calendar) {
+			return usageError("No calendar override starts on %s.", day)
 		}
+		rawConfig.Calendar = kept
+		saveConfig(rawConfig)
+		fmt.Printf("[CALENDAR] Removed the override on %s.\n", day)
+		return replanFrom(date)
 	}
+	return usageError("Unknown calendar action %q; use list, add or remove.", args[0])
+}
 
-	total := float64(len(allChapters))
-	completed := 0.0
-	for _, wl := range allChapters {
-		if wl.IsStudyCompleted {
-			completed++
-		}
+// replanFrom moves LastScheduledDate back to date (never before today) when
+// the schedule already runs past it, so that the next 'generate' re-plans
+// the days a calendar change touched.
+func replanFrom(date time.Time) int {
+	if today := currentDay(); date.Before(today) {
+		date = today
 	}
-	studyProgress := 100.0
-	if total > 0 {
-		studyProgress = (completed / total) * 100
+	state, _ := loadState()
+	if last, err := time.Parse(TIME_FORMAT, state.LastScheduledDate); err == nil && date.Before(last) {
+		state.LastScheduledDate = date.Format(TIME_FORMAT)
+		if err := saveState(state); err != nil {
+			return ExitError
+		}
 	}
-
-	fmt.Println("\n-----------------------------------------------------------------")
-	fmt.Printf(ColorGreen+"✅ Overall Chapter Completion: %.1f%% (%d of %d chapters)"+ColorReset+"\n", studyProgress, int(completed), int(total))
-	fmt.Println("-----------------------------------------------------------------")
+	fmt.Printf("Run 'generate' to re-plan from %s.\n", date.Format(TIME_FORMAT))
+	return ExitOK
 }
 
-func readFloat(reader *bufio.Reader, prompt string, defaultValue float64) float64 {
-	fmt.Printf("%s (Current: %.1f): ", prompt, defaultValue)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return defaultValue
+func cmdMusic(args []string) int {
+	if len(args) != 2 || args[0] != "download" {
+		return usageError("Usage: music download <url>")
 	}
-	val, err := strconv.ParseFloat(input, 64)
-	if err != nil {
-		fmt.Println("[ERROR] Invalid number format. Using current value.")
-		return defaultValue
+	if err := downloadMusic(args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return ExitError
 	}
-	return val
+	return ExitOK
 }
 
-func readInt(reader *bufio.Reader, prompt string, defaultValue int) int {
-	fmt.Printf("%s (Current: %d): ", prompt, defaultValue)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return defaultValue
-	}
-	val, err := strconv.Atoi(input)
-	if err != nil {
-		fmt.Println("[ERROR] Invalid integer format. Using current value.")
-		return defaultValue
-	}
-	return val
+// ------------------ HTTP API ------------------
+
+const DEFAULT_API_ADDR = "127.0.0.1:8765"
+
+// activeSession is a study Timer driven by requests rather than a blocking
+// loop (the HTTP API and the TUI). The exported fields are its JSON view and
+// are filled in by snapshot.
+type activeSession struct {
+	Date           string     `json:"date"`
+	SessionIndex   int        `json:"session_index"` // 1-based, as in the CLI
+	Session        Session    `json:"session"`
+	State          TimerState `json:"state"`
+	ElapsedSeconds int        `json:"elapsed_seconds"`
+	TotalSeconds   int        `json:"total_seconds"`
+	timer          *Timer
 }
-func runDownloader() error {
 
-	if !isCommandAvailable("yt-dlp") || !isCommandAvailable("ffmpeg") {
-		return fmt.Errorf("external dependencies missing. Please install 'yt-dlp' and 'ffmpeg' using pacman")
+// newActiveSession starts timing a session, resuming from saved progress when
+// it belongs to the same chapter.
+func newActiveSession(today time.Time, sessions []Session, idx int) *activeSession {
+	initial := 0
+	if p, ok := loadProgress(today); ok && p.ChapterID == sessions[idx].ChapterID {
+		initial = p.ElapsedSeconds
 	}
-	OUTPUT_DIR :="study_music"
-
-	if err := os.MkdirAll(OUTPUT_DIR, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory '%s': %w", OUTPUT_DIR, err)
+	t := &activeSession{
+		Date:         today.Format(TIME_FORMAT),
+		SessionIndex: idx + 1,
+		Session:      sessions[idx],
+		TotalSeconds: int(sessions[idx].Duration * 3600),
 	}
+	t.timer = NewTimer(t.TotalSeconds, initial, clock)
+	t.timer.Observe(sessionRecorder(today, sessions[idx]))
+	t.timer.Apply(TimerStart)
+	return t
+}
 
-	fmt.Println(ColorYellow + "\n--- Music Downloader ---" + ColorReset)
-	fmt.Printf("Downloads will be saved to the './%s' folder.\n", OUTPUT_DIR)
-
-	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Print("\nEnter YouTube URL: ")
-
-	url, _ := reader.ReadString('\n')
-	url = strings.TrimSpace(url)
+// stop finishes the timer and returns the elapsed seconds together with the
+// final focus metrics for completeSession.
+func (t *activeSession) stop() (int, FocusMetrics) {
+	t.timer.Apply(TimerFinish)
+	return t.timer.Elapsed(), t.timer.Focus()
+}
 
-	if url == "" {
-		fmt.Println("No URL entered. Returning to Main Menu...")
-		return nil
-	}
+// snapshot returns a copy with the JSON fields brought up to date.
+func (t *activeSession) snapshot() activeSession {
+	c := *t
+	c.State = t.timer.State()
+	c.ElapsedSeconds = t.timer.Elapsed()
+	return c
+}
 
-	fmt.Printf("Processing URL: %s\n", url)
-	fmt.Printf("Downloading and converting audio to MP3 (192K quality)...\n")
+type apiServer struct {
+	mu    sync.Mutex // serialises every request that touches state files
+	timer *activeSession
+}
 
-	cmd := exec.Command("yt-dlp",
-		"-x", 
-		"--audio-format", "mp3",
-		"--audio-quality", "192K",
+// finishRequest is the optional body of POST /api/sessions/{n}/finish.
+type finishRequest struct {
+	Grade         *int   `json:"grade"`         // 0-5, revisions only
+	Understanding int    `json:"understanding"` // 1-5, 0 = no rating
+	Focus         *int   `json:"focus"`         // 1-5, default 3; needs understanding
+	Notes         string `json:"notes"`         // needs understanding
+}
 
-		"--output", fmt.Sprintf("%s/%%(title)s.%%(ext)s", OUTPUT_DIR),
-		url,
-	)
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+func writeAPIError(w http.ResponseWriter, status int, format string, a ...interface{}) {
+	writeAPIJSON(w, status, map[string]string{"error": fmt.Sprintf(format, a...)})
+}
 
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("\n❌ Download/Conversion failed for %s. Check yt-dlp output above.\n", url)
-		return nil 
+// isLoopbackHost reports whether host, with or without a port, names this
+// machine's loopback interface.
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
 	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
 
-	fmt.Printf("\n✅ Success! MP3 file saved in the '%s' directory.\n\n", OUTPUT_DIR)
+// sameOrigin guards the API against other web pages the user has open. A
+// request must name a loopback Host, which defeats DNS rebinding, unless
+// remote access was allowed; and a browser request, which carries an Origin,
+// must come from the API's own origin, which stops cross-site form posts.
+func sameOrigin(next http.Handler, allowRemote bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRemote && !isLoopbackHost(r.Host) {
+			writeAPIError(w, http.StatusForbidden, "host %q is not allowed", r.Host)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeAPIError(w, http.StatusForbidden, "origin %q is not allowed", origin)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-	return nil
+func runServer(addr string, allowRemote bool) error {
+	fmt.Printf(ColorGreen+"[SERVE] API listening on http://%s/api/ (Ctrl+C to stop)"+ColorReset+"\n", addr)
+	return http.ListenAndServe(addr, newAPIHandler(allowRemote))
 }
 
-func isCommandAvailable(name string) bool {
-	_, err := exec.LookPath(name)
-	return err == nil
+func newAPIHandler(allowRemote bool) http.Handler {
+	srv := &apiServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/plan", srv.handlePlan)
+	mux.HandleFunc("GET /api/report", srv.handleReport)
+	mux.HandleFunc("GET /api/timer", srv.handleTimer)
+	mux.HandleFunc("POST /api/sessions/{n}/{action}", srv.handleSessionAction)
+	mux.HandleFunc("GET /api/config", srv.handleGetConfig)
+	mux.HandleFunc("PATCH /api/config", srv.handlePatchConfig)
+	return sameOrigin(mux, allowRemote)
 }
 
-func readDate(reader *bufio.Reader, prompt string, defaultValue string) string {
-	fmt.Printf("%s (Format YYYY-MM-DD, Current: %s): ", prompt, defaultValue)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return defaultValue
+func (srv *apiServer) handlePlan(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	rawConfig = loadConfig()
+	date, err := parseDayArg(r.URL.Query().Get("date"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid date %q: use YYYY-MM-DD, today or tomorrow", r.URL.Query().Get("date"))
+		return
 	}
-	_, err := time.Parse(TIME_FORMAT, input)
+	sessions, err := readDayPlan(date)
 	if err != nil {
-		fmt.Println("[ERROR] Invalid date format. Using current value.")
-		return defaultValue
+		writeAPIError(w, http.StatusNotFound, "%v", err)
+		return
 	}
-	return input
+	writeAPIJSON(w, http.StatusOK, DayPlanData{
+		SchemaVersion: REPORT_SCHEMA_VERSION,
+		Date:          date.Format(TIME_FORMAT),
+		Weekday:       date.Weekday().String(),
+		Sessions:      sessions,
+	})
 }
 
-func readWeekday(reader *bufio.Reader, prompt string, defaultValue time.Weekday) time.Weekday {
-	dayNames := map[string]time.Weekday{
-		"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
-		"wednesday": time.Wednesday, "thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
-	}
-	fmt.Printf("%s (Current: %s): ", prompt, defaultValue)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
-	if input == "" {
-		return defaultValue
-	}
-	if day, ok := dayNames[input]; ok {
-		return day
-	}
-	fmt.Println("[ERROR] Invalid day. Enter full day name (e.g., monday). Using current value.")
-	return defaultValue
+func (srv *apiServer) handleReport(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	rawConfig = loadConfig()
+	state, _ := loadState()
+	writeAPIJSON(w, http.StatusOK, buildReport(&state, currentDay()))
 }
 
-func promptConfig(currentConfig Config) Config {
-	reader := bufio.NewReader(os.Stdin)
-	newConfig := currentConfig
-	configChanged := false
-
-	fmt.Println("\n--- Configure Scheduler Parameters ---")
-
-	newSyllabusEndDate := readDate(reader, "Syllabus Completion Target Date", newConfig.SyllabusEndDate)
-	if newSyllabusEndDate != newConfig.SyllabusEndDate { configChanged = true }
-	newConfig.SyllabusEndDate = newSyllabusEndDate
-
-	newExamDate := readDate(reader, "Final Exam Date (for reference)", newConfig.ExamDate)
-	if newExamDate != newConfig.ExamDate { configChanged = true }
-	newConfig.ExamDate = newExamDate
-
-	newDailyStudyHrs := readFloat(reader, "Total Daily Study Hours (Excluding Buffer/Breaks)", newConfig.DailyStudyHrs)
-	if newDailyStudyHrs != newConfig.DailyStudyHrs { configChanged = true }
-	newConfig.DailyStudyHrs = newDailyStudyHrs
+func (srv *apiServer) handleTimer(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.timer == nil {
+		writeAPIJSON(w, http.StatusOK, map[string]interface{}{"state": "idle"})
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, srv.timer.snapshot())
+}
 
-	newMaxSessionHrs := readFloat(reader, "Maximum Hours per Single Session", newConfig.MaxSessionHrs)
-	if newMaxSessionHrs != newConfig.MaxSessionHrs { configChanged = true }
-	newConfig.MaxSessionHrs = newMaxSessionHrs
+func (srv *apiServer) handleSessionAction(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	rawConfig = loadConfig()
 
-	newDailyBufferMins := readInt(reader, "Daily Buffer/Review Time (in minutes)", newConfig.DailyBufferMins)
-	if newDailyBufferMins != newConfig.DailyBufferMins { configChanged = true }
-	newConfig.DailyBufferMins = newDailyBufferMins
+	today := currentDay()
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "session number must be an integer")
+		return
+	}
+	sessions, err := readDayPlan(today)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "%v", err)
+		return
+	}
+	if n < 1 || n > len(sessions) || (sessions[n-1].Type != "Study" && sessions[n-1].Type != "Revision") {
+		writeAPIError(w, http.StatusNotFound, "no study or revision session %d today", n)
+		return
+	}
+	idx := n - 1
+	action := r.PathValue("action")
 
-	newWeeklyRestDay := readWeekday(reader, "Weekly Rest Day (e.g., sunday)", newConfig.WeeklyRestDay)
-	if newWeeklyRestDay != newConfig.WeeklyRestDay { configChanged = true }
-	newConfig.WeeklyRestDay = newWeeklyRestDay
+	if action == "start" {
+		if srv.timer != nil {
+			writeAPIError(w, http.StatusConflict, "session %d is already active", srv.timer.SessionIndex)
+			return
+		}
+		if sessions[idx].Status != "Pending" {
+			writeAPIError(w, http.StatusConflict, "session %d is already %s", n, sessions[idx].Status)
+			return
+		}
+		t := newActiveSession(today, sessions, idx)
+		srv.timer = t
+		writeAPIJSON(w, http.StatusOK, t.snapshot())
+		return
+	}
 
-	newRestDayActivity := newConfig.RestDayActivity
-	fmt.Printf("Rest Day Activity (Current: %s): ", newConfig.RestDayActivity)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-	if input != "" && input != newConfig.RestDayActivity {
-		newRestDayActivity = input
-		configChanged = true
+	t := srv.timer
+	if t == nil || t.SessionIndex != n || t.Date != today.Format(TIME_FORMAT) {
+		writeAPIError(w, http.StatusConflict, "session %d is not active; start it first", n)
+		return
 	}
-	newConfig.RestDayActivity = newRestDayActivity
 
-	if configChanged {
-		rawConfig = newConfig
-		saveConfig(rawConfig)
-		deleteScheduleState() 
-		fmt.Printf("\n[INFO] Configuration updated and saved. Max Session Hrs is now: %.1f hrs.\n", rawConfig.MaxSessionHrs)
-		fmt.Println("Please run [3] RE-GENERATE to calculate a new study path using these settings.")
-	} else {
-		fmt.Println("\n[INFO] No configuration changes detected. Schedule state retained.")
+	switch action {
+	case "pause":
+		if !t.timer.Apply(TimerPause) {
+			writeAPIError(w, http.StatusConflict, "session %d is already paused", n)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, t.snapshot())
+	case "resume":
+		if !t.timer.Apply(TimerResume) {
+			writeAPIError(w, http.StatusConflict, "session %d is not paused", n)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, t.snapshot())
+	case "finish":
+		var req finishRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeAPIError(w, http.StatusBadRequest, "invalid body: %v", err)
+				return
+			}
+		}
+		grade := RECALL_GRADE_DEFAULT
+		if req.Grade != nil {
+			if *req.Grade < 0 || *req.Grade > 5 {
+				writeAPIError(w, http.StatusBadRequest, "grade must be between 0 and 5")
+				return
+			}
+			grade = *req.Grade
+		}
+		if req.Understanding == 0 && (req.Focus != nil || req.Notes != "") {
+			// Same rule as 'complete': a self-assessment always has an understanding score.
+			writeAPIError(w, http.StatusBadRequest, "focus and notes need understanding")
+			return
+		}
+		var rating *SessionRating
+		if req.Understanding != 0 {
+			focus := 3
+			if req.Focus != nil {
+				focus = *req.Focus
+			}
+			if req.Understanding < 1 || req.Understanding > 5 || focus < 1 || focus > 5 {
+				writeAPIError(w, http.StatusBadRequest, "understanding and focus must be between 1 and 5")
+				return
+			}
+			rating = &SessionRating{Understanding: req.Understanding, Focus: focus, Notes: req.Notes}
+		}
+		elapsed, focus := t.stop()
+		completeSession(sessions, idx, today, elapsed, grade, rating, &focus)
+		srv.timer = nil
+		writeAPIJSON(w, http.StatusOK, sessions[idx])
+	case "miss":
+		t.timer.Apply(TimerMiss)
+		srv.timer = nil
+		deleteProgress()
+		updated := missSessions(sessions, []int{idx}, today)
+		writeAPIJSON(w, http.StatusOK, DayPlanData{
+			SchemaVersion: REPORT_SCHEMA_VERSION,
+			Date:          today.Format(TIME_FORMAT),
+			Weekday:       today.Weekday().String(),
+			Sessions:      updated,
+		})
+	default:
+		writeAPIError(w, http.StatusNotFound, "unknown action %q; use start, pause, resume, finish or miss", action)
 	}
-	return newConfig
 }
 
-func runMainMenu() {
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		rawConfig = loadConfig()
-		fmt.Println("\n--- Adaptive NEET Scheduler Menu ---")
-		fmt.Println(ColorGreen + "[1] Start TIMER CLI (Daily Study)" + ColorReset)
-		fmt.Println(ColorBlue + "[2] View FULL REPORT(Syllabus Status)" + ColorReset)
-		fmt.Println(ColorYellow + "[3] RE-GENERATE Schedule (Initialize or Re-balance)" + ColorReset)
-		fmt.Println("[4] CHANGE CONFIGURATION (Dates, Times, etc.)")
-		fmt.Println("[5] Music Download")
-		fmt.Println("[q] Quit")
-		fmt.Print("\n> Enter your choice: ")
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
-		switch input {
-		case "1":
-			runTimerCLI()
-		case "2", "report":
-			runFullReport()
-		case "3", "generate":
-			fmt.Println("\n[ACTION] Running Schedule Generation...")
-			generateSchedule()
-		case "4", "config":
-			promptConfig(rawConfig)
-		case "5":
+func (srv *apiServer) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	rawConfig = loadConfig()
+	writeAPIJSON(w, http.StatusOK, rawConfig)
+}
 
-			if err := runDownloader(); err != nil {
-				fmt.Fprintf(os.Stderr, "\n[CRITICAL ERROR] Downloader failed: %v\n", err)
-			}
-		case "6" :
-				perf := PerformanceState{}
-	if err := loadJSON("performance_state.json", &perf); err != nil {
-		fmt.Println("[INFO] No performance data yet. Run some sessions first.")
-	} else {
-		fmt.Println("\n--- PERFORMANCE REPORT ---")
-		fmt.Printf("Total Sessions Tracked : %d\n", perf.TotalSessions)
-		fmt.Printf("Completed Sessions     : %d\n", perf.CompletedSessions)
-		fmt.Printf("Missed Sessions        : %d\n", perf.MissedSessions)
-		fmt.Printf("Consistency Factor     : %.1f%%\n", perf.ConsistencyFactor*100.0)
-		fmt.Printf("Average Focus (score)  : %.2f\n\n", perf.AverageFocusScore)
+// handlePatchConfig applies a JSON object of scalar config keys, e.g.
+// {"daily_study_hrs": 7.5, "weekly_rest_day": "sunday"}. Either every key is
+// applied or none is.
+func (srv *apiServer) handlePatchConfig(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	rawConfig = loadConfig()
+
+	changes := map[string]json.RawMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid body: %v", err)
+		return
 	}
-		case "q":
-			stopMusic()
-			fmt.Println("\nExiting application. Goodbye! 👋")
+	newConfig := rawConfig
+	for key, raw := range changes {
+		updated, err := applyConfigJSON(newConfig, key, raw)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "%v", err)
 			return
-		default:
-			fmt.Println("[ERROR] Invalid choice. Please enter '1', '2', '3', '4', or 'q'.")
 		}
+		newConfig = updated
 	}
+	rawConfig = newConfig
+	saveConfig(rawConfig)
+	writeAPIJSON(w, http.StatusOK, rawConfig)
 }
 
 func main() {
 
-	if len(os.Args) > 1 && os.Args[1] == "generate" {
-		rawConfig = loadConfig()
-		generateSchedule()
-		return
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
 	}
 
 	rawConfig = loadConfig()
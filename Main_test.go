@@ -0,0 +1,1956 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run with -update to rewrite testdata/golden from the current generator.
+var updateGolden = flag.Bool("update", false, "rewrite golden plan files")
+
+// goldenDir is resolved before any test changes directory.
+var goldenDir, _ = filepath.Abs(filepath.Join("testdata", "golden"))
+
+// testToday is a Monday, so the default Sunday rest day falls on day 7.
+var testToday = time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+func day(offset int) string {
+	return testToday.AddDate(0, 0, offset).Format(TIME_FORMAT)
+}
+
+func testConfig() Config {
+	return Config{
+		SyllabusEndDate:          day(9),
+		ExamDate:                 day(14),
+		DailyStudyHrs:            4.0,
+		MaxSessionHrs:            1.5,
+		DailyBufferMins:          30,
+		WeeklyRestDay:            time.Sunday,
+		RestDayActivity:          "Mock Test & Review",
+		InitialDifficultyRating:  3.0,
+		DifficultyAdjustmentRate: 0.1,
+		RevisionModel:            RevisionModelFixed,
+		PerformanceWindowDays:    14,
+		InitialWorkload: []ChapterWorkload{
+			{ID: "PH001", Subject: "Physics", Chapter: "Laws of Motion", InitialTotalTime: 4.0, Weightage: 2.0, InitialRevisionIntervalDays: 2},
+			{ID: "PH002", Subject: "Physics", Chapter: "Gravitation", InitialTotalTime: 3.0, Weightage: 1.5, InitialRevisionIntervalDays: 3},
+			{ID: "CH001", Subject: "Chemistry", Chapter: "Structure of Atom", InitialTotalTime: 3.5, Weightage: 1.0, InitialRevisionIntervalDays: 4},
+			{ID: "CH002", Subject: "Chemistry", Chapter: "Equilibrium", InitialTotalTime: 4.5, Weightage: 1.4, InitialRevisionIntervalDays: 3},
+			{ID: "BI001", Subject: "Biology", Chapter: "Cell Cycle", InitialTotalTime: 3.0, Weightage: 1.2, InitialRevisionIntervalDays: 4},
+			{ID: "BI002", Subject: "Biology", Chapter: "Ecosystem", InitialTotalTime: 2.5, Weightage: 1.0, InitialRevisionIntervalDays: 5},
+		},
+	}
+}
+
+// useSandbox runs the rest of the test in an empty temporary directory with cfg
+// as config.json, the clock pinned to testToday and a fixed random seed. All
+// state, plan and log files therefore land in the temp directory.
+func useSandbox(t *testing.T, cfg Config) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	savedClock, savedConfig, savedLog := clock, rawConfig, logOut
+	t.Cleanup(func() { clock, rawConfig, logOut = savedClock, savedConfig, savedLog })
+	clock = &manualClock{now: testToday}
+	seedRandom(1)
+
+	rawConfig = cfg
+	if err := saveJSON(CONFIG_FILE, cfg); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+// useConfig sets rawConfig for a test that touches no files and restores it
+// afterwards.
+func useConfig(t *testing.T, cfg Config) {
+	t.Helper()
+	saved := rawConfig
+	t.Cleanup(func() { rawConfig = saved })
+	rawConfig = cfg
+}
+
+// captureStdout runs f with os.Stdout, and logOut as it is by default,
+// redirected to a pipe and returns what was written to it.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedStdout := os.Stdout
+	os.Stdout, logOut = w, w
+	defer func() { os.Stdout = savedStdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-out
+}
+
+func chapterIDs(chapters []ChapterWorkload) []string {
+	ids := []string{}
+	for _, ch := range chapters {
+		ids = append(ids, ch.ID)
+	}
+	return ids
+}
+
+func TestCalculateQuotas(t *testing.T) {
+	tests := []struct {
+		name          string
+		lastScheduled string
+		endOffset     int
+		restDay       time.Weekday
+		workload      []ChapterWorkload
+		wantDays      int
+		wantRemaining float64
+		wantWT        float64
+	}{
+		{
+			name:      "study days exclude the rest day",
+			endOffset: 6, // Monday through Sunday
+			restDay:   time.Sunday,
+			workload: []ChapterWorkload{
+				{ID: "A", RemainingTime: 3, Difficulty: 2.5, Weightage: 1},
+			},
+			wantDays:      6,
+			wantRemaining: 3,
+			wantWT:        3 * 1.5 * 2,
+		},
+		{
+			name:      "studied chapters count one weighted revision",
+			endOffset: 6,
+			restDay:   time.Sunday,
+			workload: []ChapterWorkload{
+				{ID: "A", IsStudyCompleted: true, NextRevisionDate: day(4), Difficulty: 5, Weightage: 2},
+			},
+			wantDays:      6,
+			wantRemaining: 0,
+			wantWT:        (5.0 / 5.0) * 2 * (10.0 / 4.0) * REVISION_TIME_HRS,
+		},
+		{
+			name:      "finished chapters add nothing",
+			endOffset: 6,
+			restDay:   time.Sunday,
+			workload: []ChapterWorkload{
+				{ID: "A", IsStudyCompleted: true, RevisionCount: MAX_REVISIONS, Difficulty: 3, Weightage: 1},
+				{ID: "B", RemainingTime: 2, Difficulty: 5, Weightage: 0.5},
+			},
+			wantDays:      6,
+			wantRemaining: 2,
+			wantWT:        2 * 2 * 1,
+		},
+		{
+			name:          "counting starts at a future LastScheduledDate",
+			lastScheduled: day(3),
+			endOffset:     6, // Thursday through Sunday
+			restDay:       time.Saturday,
+			workload: []ChapterWorkload{
+				{ID: "A", RemainingTime: 1, Difficulty: 0, Weightage: 1.5},
+			},
+			wantDays:      3,
+			wantRemaining: 1,
+			wantWT:        3,
+		},
+		{
+			name:          "past end date leaves no study days and no quota",
+			endOffset:     -1,
+			restDay:       time.Sunday,
+			workload:      []ChapterWorkload{{ID: "A", RemainingTime: 1, Difficulty: 0, Weightage: 1}},
+			wantDays:      0,
+			wantRemaining: 1,
+			wantWT:        2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.SyllabusEndDate = day(tt.endOffset)
+			cfg.WeeklyRestDay = tt.restDay
+			useSandbox(t, cfg)
+
+			state := ScheduleState{Workload: map[string]ChapterWorkload{}, LastScheduledDate: tt.lastScheduled}
+			for _, wl := range tt.workload {
+				state.Workload[wl.ID] = wl
+			}
+			chapters := calculateQuotas(&state)
+
+			if len(chapters) != len(tt.workload) {
+				t.Fatalf("got %d chapters, want %d", len(chapters), len(tt.workload))
+			}
+			if state.NetStudyDays != tt.wantDays {
+				t.Errorf("NetStudyDays = %d, want %d", state.NetStudyDays, tt.wantDays)
+			}
+			if !floatEqual(state.TotalRemainingTime, tt.wantRemaining) {
+				t.Errorf("TotalRemainingTime = %v, want %v", state.TotalRemainingTime, tt.wantRemaining)
+			}
+			if !floatEqual(state.TotalWeightedWorkload, tt.wantWT) {
+				t.Errorf("TotalWeightedWorkload = %v, want %v", state.TotalWeightedWorkload, tt.wantWT)
+			}
+			wantQuota := 0.0
+			if tt.wantDays > 0 {
+				wantQuota = tt.wantWT / float64(tt.wantDays)
+			}
+			if !floatEqual(state.DailyQuotaWT, wantQuota) {
+				t.Errorf("DailyQuotaWT = %v, want %v", state.DailyQuotaWT, wantQuota)
+			}
+		})
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
+
+func TestAnalyzeFeasibility(t *testing.T) {
+	tests := []struct {
+		name      string
+		dailyHrs  float64
+		feasible  bool
+		shortfall float64
+		projected string
+		timetable []string        // default study blocks, if any
+		options   map[string]bool // kind -> sufficient
+	}{
+		// 9 study days of 3.5 hrs against 20.5 hrs of study.
+		{name: "fits", dailyHrs: 4.0, feasible: true, projected: day(5)},
+		// 9 study days of 2 hrs: 2.5 hrs short, one Sunday adds only 2.
+		{name: "overloaded", dailyHrs: 2.5, shortfall: 2.5, projected: day(11), options: map[string]bool{
+			"extra_hours":   true,
+			"drop_rest_day": false,
+			"move_end_date": true,
+		}},
+		// The timetable allows 3 hrs * 0.9 - 0.5 = 2.2 hrs a day, so the
+		// 0.5 extra hrs/day suggested for the 2.5 hr shortfall add only 1.8.
+		{name: "timetable caps extra hours", dailyHrs: 2.5, shortfall: 2.5, projected: day(11), timetable: []string{"06:00-09:00"}, options: map[string]bool{
+			"extra_hours":   false,
+			"drop_rest_day": false,
+			"move_end_date": true,
+		}},
+		// With the timetable as the only limit, more daily hours gain nothing.
+		{name: "timetable is the limit", dailyHrs: 4.0, shortfall: 8.8, projected: day(17), timetable: []string{"06:00-08:00"}, options: map[string]bool{
+			"drop_rest_day": false,
+			"move_end_date": true,
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DailyStudyHrs = tc.dailyHrs
+			if tc.timetable != nil {
+				cfg.Timetable = map[string][]string{"default": tc.timetable}
+			}
+			useSandbox(t, cfg)
+			state := initializeState(cfg)
+
+			f := analyzeFeasibility(state, testToday)
+			if f.StudyDays != 9 || f.RestDays != 1 {
+				t.Errorf("study/rest days = %d/%d, want 9/1", f.StudyDays, f.RestDays)
+			}
+			if !floatEqual(f.StudyHours, 20.5) {
+				t.Errorf("StudyHours = %v, want 20.5", f.StudyHours)
+			}
+			if f.Feasible != tc.feasible || !floatEqual(f.ShortfallHours, tc.shortfall) {
+				t.Errorf("Feasible = %v, ShortfallHours = %v; want %v, %v", f.Feasible, f.ShortfallHours, tc.feasible, tc.shortfall)
+			}
+			if f.ProjectedCompletion != tc.projected {
+				t.Errorf("ProjectedCompletion = %s, want %s", f.ProjectedCompletion, tc.projected)
+			}
+			bySubject := 0.0
+			for _, s := range f.Shortfall {
+				bySubject += s.Hours
+			}
+			if !floatEqual(bySubject, f.ShortfallHours) {
+				t.Errorf("per-subject shortfall sums to %v, want %v", bySubject, f.ShortfallHours)
+			}
+			got := map[string]bool{}
+			for _, o := range f.Options {
+				got[o.Kind] = o.Sufficient
+			}
+			if len(got) != len(tc.options) || (len(got) > 0 && !reflect.DeepEqual(got, tc.options)) {
+				t.Errorf("options = %v, want %v", got, tc.options)
+			}
+			var out strings.Builder
+			printFeasibility(&out, f)
+			if want := map[bool]string{true: "[FEASIBILITY]", false: "[OVERLOAD]"}[f.Feasible]; !strings.Contains(out.String(), want) {
+				t.Errorf("printFeasibility wrote %q, want a %s line", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestUpdatePerformance(t *testing.T) {
+	session := func(typ, status string) Session {
+		return Session{Subject: "Physics", Chapter: "Units", ChapterID: "PH001", Duration: 1, Type: typ, Status: status}
+	}
+	tests := []struct {
+		name                     string
+		windowDays               int
+		total, completed, missed int
+	}{
+		{name: "all time", total: 6, completed: 3, missed: 2},
+		// day(-10) falls outside a 3-day window.
+		{name: "3-day window", windowDays: 3, total: 2, completed: 1, missed: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.PerformanceWindowDays = tc.windowDays
+			useSandbox(t, cfg)
+			writeDayPlan(testToday.AddDate(0, 0, -10), []Session{
+				session("Study", "Completed"), session("Revision", "Completed"), session("Study", "Missed"),
+				session("Study", "Pending"), session("Break", "Completed"),
+			})
+			writeDayPlan(testToday.AddDate(0, 0, -1), []Session{session("Study", "Completed"), session("Revision", "Missed")})
+			writeDayPlan(testToday, []Session{session("Study", "Completed")}) // today is not counted yet
+
+			var runs []PerformanceState
+			for i := 0; i < 2; i++ {
+				updatePerformance()
+				var perf PerformanceState
+				if err := loadJSON(PERFORMANCE_FILE, &perf); err != nil {
+					t.Fatal(err)
+				}
+				runs = append(runs, perf)
+			}
+			for i, perf := range runs {
+				if perf.TotalSessions != tc.total || perf.CompletedSessions != tc.completed || perf.MissedSessions != tc.missed {
+					t.Errorf("run %d: total/completed/missed = %d/%d/%d, want %d/%d/%d", i+1,
+						perf.TotalSessions, perf.CompletedSessions, perf.MissedSessions, tc.total, tc.completed, tc.missed)
+				}
+			}
+			if want := float64(tc.completed) / float64(tc.total); !floatEqual(runs[1].ConsistencyFactor, want) {
+				t.Errorf("ConsistencyFactor = %v, want %v", runs[1].ConsistencyFactor, want)
+			}
+			if !reflect.DeepEqual(runs[0], runs[1]) {
+				t.Errorf("a second run changed the state:\n%+v\n%+v", runs[0], runs[1])
+			}
+		})
+	}
+}
+
+func TestPrioritizeChapters(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []ChapterWorkload
+		want  []string
+	}{
+		{
+			name: "highest priority first",
+			input: []ChapterWorkload{
+				{ID: "A", RemainingTime: 1, PriorityScore: 2},
+				{ID: "B", RemainingTime: 1, PriorityScore: 9},
+				{ID: "C", RemainingTime: 1, PriorityScore: 5},
+			},
+			want: []string{"B", "C", "A"},
+		},
+		{
+			name: "studied and exhausted chapters are dropped",
+			input: []ChapterWorkload{
+				{ID: "A", RemainingTime: 1, PriorityScore: 1},
+				{ID: "B", IsStudyCompleted: true, RemainingTime: 1, PriorityScore: 9},
+				{ID: "C", RemainingTime: 0.0005, PriorityScore: 8},
+			},
+			want: []string{"A"},
+		},
+		{
+			name: "ties keep their input order",
+			input: []ChapterWorkload{
+				{ID: "A", RemainingTime: 1, PriorityScore: 3},
+				{ID: "B", RemainingTime: 1, PriorityScore: 3},
+				{ID: "C", RemainingTime: 1, PriorityScore: 3},
+			},
+			want: []string{"A", "B", "C"},
+		},
+		{
+			name:  "nothing to study",
+			input: nil,
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chapterIDs(prioritizeChapters(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordRevision(t *testing.T) {
+	// Last reviewed 9 days ago, so FSRS retrievability is 1/(1+9/90) = 0.91.
+	base := ChapterWorkload{ID: "A", Difficulty: 3, InitialRevisionIntervalDays: 3, IsStudyCompleted: true, LastReviewDate: day(-9)}
+	sm2 := func(ease float64, streak, interval int) ChapterWorkload {
+		wl := base
+		wl.EaseFactor, wl.RecallStreak, wl.IntervalDays = ease, streak, interval
+		return wl
+	}
+	fsrs := base
+	fsrs.Stability = 10
+
+	tests := []struct {
+		name       string
+		model      string
+		wl         ChapterWorkload
+		grade      int
+		days       int     // until the next revision, 0 = none
+		ease       float64 // SM-2 only
+		streak     int     // SM-2 only
+		stability  float64 // FSRS only
+		revisions  int
+		examInDays int // 0 = far away
+	}{
+		{name: "fixed", model: RevisionModelFixed, wl: ChapterWorkload{InitialRevisionIntervalDays: 3, RevisionCount: 1}, grade: 1, days: 9, revisions: 2},
+		{name: "fixed last revision", model: RevisionModelFixed, wl: ChapterWorkload{InitialRevisionIntervalDays: 3, RevisionCount: MAX_REVISIONS - 1}, grade: 5, days: 0, revisions: MAX_REVISIONS},
+		{name: "sm2 perfect recall grows by ease", model: RevisionModelSM2, wl: sm2(2.5, 2, 6), grade: 5, days: 16, ease: 2.6, streak: 3, revisions: 1},
+		{name: "sm2 second success", model: RevisionModelSM2, wl: sm2(2.5, 1, 3), grade: 4, days: 6, ease: 2.5, streak: 2, revisions: 1},
+		{name: "sm2 hard recall", model: RevisionModelSM2, wl: sm2(2.5, 0, 0), grade: 3, days: 3, ease: 2.36, streak: 1, revisions: 1},
+		{name: "sm2 lapse restarts", model: RevisionModelSM2, wl: sm2(2.5, 2, 6), grade: 1, days: 1, ease: 1.96, streak: 0, revisions: 1},
+		{name: "sm2 ease floor", model: RevisionModelSM2, wl: sm2(SM2_MIN_EASE, 4, 20), grade: 0, days: 1, ease: SM2_MIN_EASE, streak: 0, revisions: 1},
+		{name: "sm2 past the exam", model: RevisionModelSM2, wl: sm2(2.5, 2, 6), grade: 5, days: 0, ease: 2.6, streak: 3, revisions: 1, examInDays: 10},
+		{name: "fsrs good recall", model: RevisionModelFSRS, wl: fsrs, grade: 4, days: 24, stability: 24.338, revisions: 1},
+		{name: "fsrs easy recall", model: RevisionModelFSRS, wl: fsrs, grade: 5, days: 47, stability: 47.423, revisions: 1},
+		{name: "fsrs hard recall", model: RevisionModelFSRS, wl: fsrs, grade: 3, days: 14, stability: 14.158, revisions: 1},
+		{name: "fsrs lapse", model: RevisionModelFSRS, wl: fsrs, grade: 1, days: 3, stability: 2.816, revisions: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.RevisionModel = tc.model
+			cfg.ExamDate = "2026-01-01"
+			if tc.examInDays > 0 {
+				cfg.ExamDate = day(tc.examInDays)
+			}
+			useConfig(t, cfg)
+
+			wl := tc.wl
+			recordRevision(&wl, tc.grade, testToday)
+			want := ""
+			if tc.days > 0 {
+				want = day(tc.days)
+			}
+			if wl.NextRevisionDate != want || wl.RevisionCount != tc.revisions || wl.LastRecallGrade != tc.grade || wl.LastReviewDate != day(0) {
+				t.Errorf("next %q, revisions %d, grade %d, reviewed %s; want %q, %d, %d, %s",
+					wl.NextRevisionDate, wl.RevisionCount, wl.LastRecallGrade, wl.LastReviewDate, want, tc.revisions, tc.grade, day(0))
+			}
+			switch tc.model {
+			case RevisionModelSM2:
+				if math.Abs(wl.EaseFactor-tc.ease) > 1e-9 || wl.RecallStreak != tc.streak {
+					t.Errorf("ease %v, streak %d; want %v, %d", wl.EaseFactor, wl.RecallStreak, tc.ease, tc.streak)
+				}
+			case RevisionModelFSRS:
+				if math.Abs(wl.Stability-tc.stability) > 0.001 {
+					t.Errorf("stability %v, want %v", wl.Stability, tc.stability)
+				}
+			}
+		})
+	}
+
+	// Finishing a chapter's study schedules its first revision.
+	for _, model := range []string{RevisionModelFixed, RevisionModelSM2, RevisionModelFSRS} {
+		cfg := testConfig()
+		cfg.RevisionModel = model
+		useConfig(t, cfg)
+		wl := ChapterWorkload{InitialRevisionIntervalDays: 3, Difficulty: 3}
+		startRevisionCycle(&wl, testToday)
+		if !wl.IsStudyCompleted || wl.NextRevisionDate != day(3) {
+			t.Errorf("%s: startRevisionCycle next revision %q, want %s", model, wl.NextRevisionDate, day(3))
+		}
+		if model == RevisionModelSM2 && (wl.EaseFactor != SM2_DEFAULT_EASE || wl.IntervalDays != 3) {
+			t.Errorf("sm2: start ease %v, interval %d; want %v, 3", wl.EaseFactor, wl.IntervalDays, SM2_DEFAULT_EASE)
+		}
+		if model == RevisionModelFSRS && wl.Stability != 3 {
+			t.Errorf("fsrs: start stability %v, want 3", wl.Stability)
+		}
+	}
+}
+
+func TestGetDueRevisions(t *testing.T) {
+	useConfig(t, testConfig())
+	state := ScheduleState{Workload: map[string]ChapterWorkload{
+		"OVERDUE":  {ID: "OVERDUE", IsStudyCompleted: true, NextRevisionDate: day(-3)},
+		"TODAY":    {ID: "TODAY", IsStudyCompleted: true, NextRevisionDate: day(0)},
+		"FUTURE":   {ID: "FUTURE", IsStudyCompleted: true, NextRevisionDate: day(1)},
+		"STUDYING": {ID: "STUDYING", RemainingTime: 2, NextRevisionDate: day(-1)},
+		"RETIRED":  {ID: "RETIRED", IsStudyCompleted: true, RevisionCount: MAX_REVISIONS, NextRevisionDate: day(-1)},
+		"NODATE":   {ID: "NODATE", IsStudyCompleted: true},
+	}}
+
+	tests := []struct {
+		date time.Time
+		want []string
+	}{
+		{testToday.AddDate(0, 0, -5), []string{}},
+		{testToday.AddDate(0, 0, -1), []string{"OVERDUE"}},
+		{testToday, []string{"OVERDUE", "TODAY"}},
+		{testToday.AddDate(0, 0, 7), []string{"FUTURE", "OVERDUE", "TODAY"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date.Format(TIME_FORMAT), func(t *testing.T) {
+			if got := chapterIDs(getDueRevisions(state, tt.date)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMissedSession(t *testing.T) {
+	useConfig(t, testConfig())
+	base := ChapterWorkload{ID: "A", RemainingTime: 2, Difficulty: 3, IsStudyCompleted: false}
+
+	study := applyMissedSession(base, Session{ChapterID: "A", Type: "Study", Duration: 1.5}, testToday)
+	if !floatEqual(study.RemainingTime, base.RemainingTime) {
+		t.Errorf("missed study: RemainingTime = %v, want it unchanged at %v", study.RemainingTime, base.RemainingTime)
+	}
+	if study.Difficulty <= base.Difficulty {
+		t.Errorf("missed study: Difficulty = %v, want more than %v", study.Difficulty, base.Difficulty)
+	}
+
+	revising := ChapterWorkload{ID: "A", IsStudyCompleted: true, RevisionCount: 2, NextRevisionDate: day(-2), Difficulty: 3}
+	rev := applyMissedSession(revising, Session{ChapterID: "A", Type: "Revision", Duration: REVISION_TIME_HRS}, testToday)
+	if rev.NextRevisionDate != day(1) {
+		t.Errorf("missed revision: NextRevisionDate = %s, want %s", rev.NextRevisionDate, day(1))
+	}
+	if rev.RevisionCount != 1 {
+		t.Errorf("missed revision: RevisionCount = %d, want 1", rev.RevisionCount)
+	}
+
+	first := ChapterWorkload{ID: "A", IsStudyCompleted: true, NextRevisionDate: day(0), Difficulty: 3}
+	if got := applyMissedSession(first, Session{ChapterID: "A", Type: "Revision"}, testToday).RevisionCount; got != 0 {
+		t.Errorf("missed first revision: RevisionCount = %d, want 0", got)
+	}
+
+	exam := applyMissedSession(revising, Session{ChapterID: "A", Chapter: "A" + EXAM_REVISION_SUFFIX, Type: "Revision"}, testToday)
+	if exam.NextRevisionDate != revising.NextRevisionDate || exam.RevisionCount != revising.RevisionCount {
+		t.Errorf("missed exam revision changed the revision schedule: %s #%d", exam.NextRevisionDate, exam.RevisionCount)
+	}
+}
+
+func TestExamPhase(t *testing.T) {
+	cfg := testConfig()
+	useSandbox(t, cfg)
+	generateSchedule()
+
+	capacity := cfg.DailyStudyHrs - float64(cfg.DailyBufferMins)/60.0
+	revisedChapters := map[string]bool{}
+	for offset, want := range map[int]struct {
+		mock     bool
+		rest     bool
+		revision float64
+	}{
+		10: {mock: true, revision: capacity - EXAM_MOCK_TEST_HRS},
+		11: {revision: capacity * EXAM_TAPER_FACTOR},
+		12: {revision: capacity * EXAM_TAPER_FACTOR},
+		13: {rest: true},
+	} {
+		date := testToday.AddDate(0, 0, offset)
+		sessions, err := readDayPlan(date)
+		if err != nil {
+			t.Fatalf("%s: %v", day(offset), err)
+		}
+		mock, rest, revision := false, false, 0.0
+		for _, s := range sessions {
+			switch {
+			case s.Type == "Mock":
+				mock = true
+			case s.Type == "Rest":
+				rest = true
+			case isExamRevision(s):
+				revision += s.Duration
+				revisedChapters[s.ChapterID] = true
+			case s.Type == "Study" || s.Type == "Revision":
+				t.Errorf("%s: unexpected %s session in the exam phase: %+v", day(offset), s.Type, s)
+			}
+		}
+		if mock != want.mock || rest != want.rest || !floatEqual(revision, want.revision) {
+			t.Errorf("%s: mock=%v rest=%v revision=%.2f hrs, want mock=%v rest=%v revision=%.2f hrs",
+				day(offset), mock, rest, revision, want.mock, want.rest, want.revision)
+		}
+	}
+	if len(revisedChapters) < 4 {
+		t.Errorf("exam phase revised only %d chapters", len(revisedChapters))
+	}
+	if _, err := os.Stat(planStore.Path(testToday.AddDate(0, 0, 14))); err == nil {
+		t.Error("exam day has a plan")
+	}
+}
+
+func TestExamRevisionsOnlyStudiedChapters(t *testing.T) {
+	cfg := testConfig()
+	cfg.DailyBufferMins = 90 // leaves some chapters unstudied by the syllabus end
+	useSandbox(t, cfg)
+	generateSchedule()
+
+	studied := map[string]float64{}
+	examRevisions := []string{}
+	for offset := 0; offset <= 13; offset++ {
+		sessions, _ := readDayPlan(testToday.AddDate(0, 0, offset))
+		for _, s := range sessions {
+			if s.Type == "Study" {
+				studied[s.ChapterID] += s.Duration
+			} else if isExamRevision(s) {
+				examRevisions = append(examRevisions, s.ChapterID)
+			}
+		}
+	}
+	total := map[string]float64{}
+	for _, wl := range cfg.InitialWorkload {
+		total[wl.ID] = wl.InitialTotalTime
+	}
+	for _, id := range examRevisions {
+		if studied[id] < total[id]-0.001 {
+			t.Errorf("exam revision of %s, which has only %.2f of %.2f study hrs planned", id, studied[id], total[id])
+		}
+	}
+	if len(examRevisions) == 0 {
+		t.Error("no exam revisions planned")
+	}
+
+	state, _ := loadState()
+	before := state.Workload["PH001"]
+	session := Session{Subject: "Physics", Chapter: before.Chapter + EXAM_REVISION_SUFFIX, ChapterID: "PH001", Duration: EXAM_REVISION_HRS, Type: "Revision"}
+	after := applyCompletedSession(before, session, int(EXAM_REVISION_HRS*3600), testToday, 5, nil)
+	if after.RevisionCount != before.RevisionCount || after.NextRevisionDate != before.NextRevisionDate || after.IsStudyCompleted {
+		t.Errorf("completing an exam revision changed the revision schedule: %+v", after)
+	}
+}
+
+func TestParseChapterScore(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    MockChapterScore
+		wantErr bool
+	}{
+		{spec: "PH002=8/16", want: MockChapterScore{ChapterID: "PH002", Marks: 8, MaxMarks: 16}},
+		{spec: "ph002=8/16:3", want: MockChapterScore{ChapterID: "PH002", Marks: 8, MaxMarks: 16, Errors: 3}},
+		{spec: "CH001=:2", want: MockChapterScore{ChapterID: "CH001", Errors: 2}},
+		{spec: "CH001", wantErr: true},
+		{spec: "CH001=", wantErr: true},
+		{spec: "CH001=9/8", wantErr: true},
+		{spec: "CH001=4/8:x", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := parseChapterScore(tc.spec)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseChapterScore(%q) error = %v, wantErr %v", tc.spec, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && got != tc.want {
+			t.Errorf("parseChapterScore(%q) = %+v, want %+v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestApplyMockScore(t *testing.T) {
+	useConfig(t, testConfig())
+	studied := ChapterWorkload{ID: "A", IsStudyCompleted: true, Difficulty: 3, Weightage: 1, RevisionCount: 1, NextRevisionDate: day(10)}
+
+	weak := applyMockScore(studied, MockChapterScore{ChapterID: "A", Marks: 2, MaxMarks: 10}, testToday)
+	if weak.Difficulty <= studied.Difficulty {
+		t.Errorf("weak score: Difficulty = %v, want above %v", weak.Difficulty, studied.Difficulty)
+	}
+	// MockWeakness is the plain mean over every result so far.
+	first := (MOCK_TARGET_ACCURACY - 0.2) / MOCK_TARGET_ACCURACY
+	third := (MOCK_TARGET_ACCURACY - 0.4) / MOCK_TARGET_ACCURACY
+	means := []struct {
+		score MockChapterScore
+		want  float64
+	}{
+		{MockChapterScore{ChapterID: "A", Marks: 2, MaxMarks: 10}, first},
+		{MockChapterScore{ChapterID: "A", Marks: 10, MaxMarks: 10}, first / 2},
+		{MockChapterScore{ChapterID: "A", Marks: 4, MaxMarks: 10}, (first + third) / 3},
+	}
+	averaged := studied
+	for i, m := range means {
+		averaged = applyMockScore(averaged, m.score, testToday)
+		if !floatEqual(averaged.MockWeakness, m.want) || averaged.MockScores != i+1 {
+			t.Errorf("after %d scores: MockWeakness = %v over %d scores, want %v over %d", i+1, averaged.MockWeakness, averaged.MockScores, m.want, i+1)
+		}
+	}
+	if weak.NextRevisionDate != day(1) {
+		t.Errorf("weak score: NextRevisionDate = %s, want %s", weak.NextRevisionDate, day(1))
+	}
+
+	strong := applyMockScore(studied, MockChapterScore{ChapterID: "A", Marks: 10, MaxMarks: 10}, testToday)
+	if strong.Difficulty >= studied.Difficulty || strong.MockWeakness != 0 || strong.NextRevisionDate != studied.NextRevisionDate {
+		t.Errorf("strong score: got %+v", strong)
+	}
+
+	// With the fixed model, a weak mock reopens one revision of a finished chapter.
+	finished := ChapterWorkload{ID: "A", IsStudyCompleted: true, Difficulty: 3, RevisionCount: MAX_REVISIONS, NextRevisionDate: day(-3)}
+	reopened := applyMockScore(finished, MockChapterScore{ChapterID: "A", Errors: 4}, testToday)
+	if !hasRevisionsLeft(reopened) {
+		t.Errorf("weak score on a finished chapter left no revision: %+v", reopened)
+	}
+
+	// Revision priority grows with weakness.
+	state := ScheduleState{Workload: map[string]ChapterWorkload{"A": studied, "B": studied}}
+	b := state.Workload["B"]
+	b.ID, b.MockWeakness = "B", 0.5
+	state.Workload["B"] = b
+	calculateQuotas(&state)
+	if state.Workload["B"].PriorityScore <= state.Workload["A"].PriorityScore {
+		t.Errorf("weak chapter priority %v not above %v", state.Workload["B"].PriorityScore, state.Workload["A"].PriorityScore)
+	}
+}
+
+func TestRecordMockTestReplays(t *testing.T) {
+	cfg := testConfig()
+	useSandbox(t, cfg)
+	generateSchedule()
+
+	result := MockTestResult{Date: day(0), Subjects: []MockSubjectScore{{Subject: "Physics", Marks: 60, MaxMarks: 180}}}
+	if err := addChapterScores(&result, []MockChapterScore{{ChapterID: "PH001", Marks: 1, MaxMarks: 12}}, initializeState(cfg).Workload); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordMockTest(result); err != nil {
+		t.Fatal(err)
+	}
+	results, err := loadMockTests()
+	if err != nil || len(results) != 1 || !reflect.DeepEqual(results[0], result) {
+		t.Fatalf("loadMockTests() = %+v, %v; want [%+v]", results, err, result)
+	}
+
+	state, _ := loadState()
+	events, err := loadEventLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := replayEventLog(cfg, events)
+	if got, want := replayed.Workload["PH001"], state.Workload["PH001"]; got.Difficulty != want.Difficulty || got.MockWeakness != want.MockWeakness {
+		t.Errorf("replayed PH001 = %+v, want %+v", got, want)
+	}
+	if weak := weakChapters(state); len(weak) != 1 || weak[0].ID != "PH001" {
+		t.Errorf("weakChapters = %v, want [PH001]", chapterIDs(weak))
+	}
+	if err := recordMockTest(MockTestResult{Date: day(0), Subjects: []MockSubjectScore{{Subject: "Physics", Chapters: []MockChapterScore{{ChapterID: "XX9", Errors: 1}}}}}); err == nil {
+		t.Error("recordMockTest accepted an unknown chapter")
+	}
+	if err := recordMockTest(MockTestResult{Date: day(0), Subjects: []MockSubjectScore{{Subject: "History", Marks: 10, MaxMarks: 20}}}); err == nil {
+		t.Error("recordMockTest accepted a subject outside the syllabus")
+	}
+	unknown := MockTestResult{Subjects: []MockSubjectScore{{Subject: "Histroy", Marks: 1, MaxMarks: 2}}}
+	if err := addChapterScores(&unknown, nil, state.Workload); err == nil {
+		t.Error("addChapterScores accepted a subject outside the syllabus")
+	}
+	lower := MockTestResult{Subjects: []MockSubjectScore{{Subject: "physics", Marks: 1, MaxMarks: 2}}}
+	if err := addChapterScores(&lower, nil, state.Workload); err != nil || lower.Subjects[0].Subject != "Physics" {
+		t.Errorf("addChapterScores(physics) = %v, subject %q; want the syllabus spelling", err, lower.Subjects[0].Subject)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		cmd     TimerCommand
+		ok      bool
+		state   TimerState
+		elapsed int
+	}
+	tests := []struct {
+		name    string
+		total   int
+		initial int
+		steps   []step
+		focus   FocusMetrics
+	}{
+		{
+			name:  "pause, resume and finish early",
+			total: 600,
+			steps: []step{
+				{cmd: TimerStart, ok: true, state: TimerRunning},
+				{advance: time.Minute, cmd: TimerPause, ok: true, state: TimerPaused, elapsed: 60},
+				{advance: 30 * time.Second, cmd: TimerTick, ok: true, state: TimerPaused, elapsed: 60},
+				{cmd: TimerResume, ok: true, state: TimerRunning, elapsed: 60},
+				{advance: 2 * time.Minute, cmd: TimerToggleMusic, ok: true, state: TimerRunning, elapsed: 180},
+				{cmd: TimerFinish, ok: true, state: TimerFinished, elapsed: 180},
+			},
+			focus: FocusMetrics{PauseCount: 1, PausedSeconds: 30, MusicToggles: 1, ElapsedSeconds: 180, PlannedSeconds: 600, EarlyFinish: true},
+		},
+		{
+			name:  "tick finishes at the total",
+			total: 120,
+			steps: []step{
+				{cmd: TimerStart, ok: true, state: TimerRunning},
+				{advance: time.Minute, cmd: TimerTick, ok: true, state: TimerRunning, elapsed: 60},
+				{advance: 5 * time.Minute, cmd: TimerTick, ok: true, state: TimerFinished, elapsed: 120},
+			},
+			focus: FocusMetrics{ElapsedSeconds: 120, PlannedSeconds: 120},
+		},
+		{
+			name:  "miss while paused",
+			total: 600,
+			steps: []step{
+				{cmd: TimerStart, ok: true, state: TimerRunning},
+				{advance: time.Minute, cmd: TimerPause, ok: true, state: TimerPaused, elapsed: 60},
+				{advance: time.Minute, cmd: TimerMiss, ok: true, state: TimerMissed, elapsed: 60},
+			},
+			focus: FocusMetrics{PauseCount: 1, PausedSeconds: 60, ElapsedSeconds: 60, PlannedSeconds: 600, EarlyFinish: true},
+		},
+		{
+			name:  "miss before starting",
+			total: 600,
+			steps: []step{
+				{cmd: TimerMiss, ok: true, state: TimerMissed},
+			},
+			focus: FocusMetrics{PlannedSeconds: 600, EarlyFinish: true},
+		},
+		{
+			name:    "resumed session counts only this run",
+			total:   300,
+			initial: 100,
+			steps: []step{
+				{cmd: TimerStart, ok: true, state: TimerRunning, elapsed: 100},
+				{advance: 50 * time.Second, cmd: TimerFinish, ok: true, state: TimerFinished, elapsed: 150},
+			},
+			focus: FocusMetrics{ElapsedSeconds: 50, PlannedSeconds: 200, EarlyFinish: true},
+		},
+		{
+			name:  "invalid transitions change nothing",
+			total: 600,
+			steps: []step{
+				{cmd: TimerPause, state: TimerIdle},
+				{cmd: TimerResume, state: TimerIdle},
+				{cmd: TimerFinish, state: TimerIdle},
+				{cmd: TimerTick, state: TimerIdle},
+				{cmd: TimerToggleMusic, state: TimerIdle},
+				{cmd: TimerStart, ok: true, state: TimerRunning},
+				{advance: time.Minute, cmd: TimerStart, state: TimerRunning, elapsed: 60},
+				{cmd: TimerResume, state: TimerRunning, elapsed: 60},
+				{cmd: TimerCommand("rewind"), state: TimerRunning, elapsed: 60},
+				{cmd: TimerFinish, ok: true, state: TimerFinished, elapsed: 60},
+				{advance: time.Minute, cmd: TimerStart, state: TimerFinished, elapsed: 60},
+				{cmd: TimerPause, state: TimerFinished, elapsed: 60},
+				{cmd: TimerTick, state: TimerFinished, elapsed: 60},
+				{cmd: TimerMiss, state: TimerFinished, elapsed: 60},
+			},
+			focus: FocusMetrics{ElapsedSeconds: 60, PlannedSeconds: 600, EarlyFinish: true},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clk := &manualClock{now: testToday}
+			timer := NewTimer(tc.total, tc.initial, clk)
+			var events []TimerEvent
+			timer.Observe(func(e TimerEvent) { events = append(events, e) })
+
+			for i, st := range tc.steps {
+				clk.Advance(st.advance)
+				from, seen := timer.State(), len(events)
+				if ok := timer.Apply(st.cmd); ok != st.ok {
+					t.Fatalf("step %d: Apply(%s) in %s = %v, want %v", i, st.cmd, from, ok, st.ok)
+				}
+				if timer.State() != st.state || timer.Elapsed() != st.elapsed || timer.Remaining() != tc.total-st.elapsed {
+					t.Fatalf("step %d: after %s state %s, elapsed %d, remaining %d; want %s, %d, %d",
+						i, st.cmd, timer.State(), timer.Elapsed(), timer.Remaining(), st.state, st.elapsed, tc.total-st.elapsed)
+				}
+				if !st.ok {
+					if len(events) != seen {
+						t.Fatalf("step %d: rejected %s notified the observer: %+v", i, st.cmd, events[seen:])
+					}
+					continue
+				}
+				want := TimerEvent{Command: st.cmd, From: from, State: st.state, Elapsed: st.elapsed, Remaining: tc.total - st.elapsed}
+				if len(events) != seen+1 || events[seen] != want {
+					t.Fatalf("step %d: events %+v, want one %+v", i, events[seen:], want)
+				}
+			}
+
+			want := tc.focus
+			want.Score = computeFocusScore(want)
+			if focus := timer.Focus(); focus != want {
+				t.Errorf("Focus() = %+v, want %+v", focus, want)
+			}
+			if want := timer.State() == TimerFinished || timer.State() == TimerMissed; timer.Done() != want {
+				t.Errorf("Done() = %v in state %s", timer.Done(), timer.State())
+			}
+		})
+	}
+}
+
+func TestTUIQuitWhileRating(t *testing.T) {
+	useSandbox(t, testConfig())
+	generateSchedule()
+	sessions, err := readDayPlan(testToday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := 0
+	for idx < len(sessions) && sessions[idx].Type != "Study" {
+		idx++
+	}
+	if idx == len(sessions) {
+		t.Fatal("today's plan has no study session")
+	}
+
+	m := &tuiModel{today: testToday, sessions: sessions, cursor: idx, mode: tuiModeBrowse}
+	m.active = newActiveSession(testToday, sessions, idx)
+	clock.(*manualClock).Advance(20 * time.Minute)
+	m.endSession()
+	m.handleKey('4')
+	if m.mode != tuiModeFocus {
+		t.Fatalf("mode after the understanding key = %s, want %s", m.mode, tuiModeFocus)
+	}
+	if m.handleKey('q') {
+		t.Fatal("'q' while rating did not quit")
+	}
+	m.quit()
+
+	saved, err := readDayPlan(testToday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved[idx].Status != "Completed" {
+		t.Errorf("session status after quitting = %s, want Completed", saved[idx].Status)
+	}
+	events, err := loadEventLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := events[len(events)-1]
+	if last.Rating == nil || *last.Rating != (SessionRating{Understanding: 4, Focus: 3}) || last.ElapsedSeconds != 20*60 {
+		t.Errorf("last event = %+v, want the session with understanding 4, focus 3 and 20 minutes", last)
+	}
+}
+
+func TestAdjustWorkloadJournalsAndReplans(t *testing.T) {
+	useSandbox(t, testConfig())
+	generateSchedule()
+
+	sessions, err := readDayPlan(testToday)
+	if err != nil {
+		t.Fatalf("read today's plan: %v", err)
+	}
+	var missed []Session
+	for _, s := range sessions {
+		if s.Type == "Study" {
+			missed = append(missed, s)
+		}
+	}
+	if len(missed) == 0 {
+		t.Fatal("today's plan has no study sessions")
+	}
+
+	adjustWorkload(missed, testToday)
+
+	state, _ := loadState()
+	if state.LastScheduledDate <= day(0) {
+		t.Errorf("LastScheduledDate = %s, want a date after today", state.LastScheduledDate)
+	}
+	events, err := loadEventLog()
+	if err != nil {
+		t.Fatalf("read event log: %v", err)
+	}
+	reschedules := 0
+	for _, e := range events {
+		if e.Kind == EventReschedule {
+			reschedules++
+		}
+	}
+	if reschedules != len(missed) {
+		t.Errorf("journalled %d reschedule events, want %d", reschedules, len(missed))
+	}
+	if _, err := readDayPlan(testToday.AddDate(0, 0, 1)); err != nil {
+		t.Errorf("tomorrow was not re-planned: %v", err)
+	}
+}
+
+func TestRegenerationFreezesWorkedSessions(t *testing.T) {
+	useSandbox(t, testConfig())
+	generateSchedule()
+
+	sessions, err := readDayPlan(testToday)
+	if err != nil || len(sessions) == 0 || sessions[0].Type != "Study" {
+		t.Fatalf("unexpected plan for today: %+v, %v", sessions, err)
+	}
+	done := sessions[0]
+	completeSession(sessions, 0, testToday, int(done.Duration*3600), RECALL_GRADE_DEFAULT, nil, nil)
+	before, _ := loadState()
+
+	// What the timer does on start-up when the schedule is behind.
+	before.LastScheduledDate = day(0)
+	saveState(before)
+	generateSchedule()
+
+	after, err := readDayPlan(testToday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) == 0 || !reflect.DeepEqual(after[0], sessions[0]) {
+		t.Fatalf("completed session was not kept: got %+v", after)
+	}
+	if got := studyHours(after); got > testConfig().DailyStudyHrs*1.15 {
+		t.Errorf("today now has %.2f study hrs, more than a day's capacity", got)
+	}
+	state, _ := loadState()
+	if got, want := state.Workload[done.ChapterID].RemainingTime, before.Workload[done.ChapterID].RemainingTime; !floatEqual(got, want) {
+		t.Errorf("regeneration changed RemainingTime of %s from %v to %v", done.ChapterID, want, got)
+	}
+	if _, err := os.Stat(PLAN_CHANGES_FILE); err != nil {
+		t.Errorf("no plan change record: %v", err)
+	}
+}
+
+func TestMissDryRunLeavesPlansUntouched(t *testing.T) {
+	useSandbox(t, testConfig())
+	generateSchedule()
+	plansBefore := readPlanDir(t, SCHEDULE_DIR)
+	stateBefore, err := os.ReadFile(STATE_FILE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, _ := readDayPlan(testToday)
+	rebalance := planMissSessions(sessions, []int{0}, testToday)
+	if rebalance == nil || len(rebalance.Changes()) == 0 {
+		t.Fatal("missing a session produced no plan changes")
+	}
+	if got := rebalance.Changes()[0].Date; got != day(0) {
+		t.Errorf("first changed day = %s, want %s", got, day(0))
+	}
+
+	stateAfter, _ := os.ReadFile(STATE_FILE)
+	if string(stateAfter) != string(stateBefore) {
+		t.Error("dry run modified the schedule state")
+	}
+	plansAfter := readPlanDir(t, SCHEDULE_DIR)
+	if len(plansAfter) != len(plansBefore) {
+		t.Fatalf("dry run changed the number of plan files: %d -> %d", len(plansBefore), len(plansAfter))
+	}
+	for name, content := range plansBefore {
+		if plansAfter[name] != content {
+			t.Errorf("dry run rewrote %s", name)
+		}
+	}
+	if _, err := os.Stat(EVENT_LOG_FILE); err == nil {
+		events, _ := loadEventLog()
+		for _, e := range events {
+			if e.Kind == EventMiss {
+				t.Errorf("dry run journaled a miss: %+v", e)
+			}
+		}
+	}
+
+	after := rebalance.Commit()
+	if len(after) == 0 || after[0].Status != "Missed" {
+		t.Errorf("committed plan for today = %+v, want first session Missed", after)
+	}
+}
+
+func TestSessionMovesPairsRemovedAndAdded(t *testing.T) {
+	study := Session{Subject: "Physics", Chapter: "Units", ChapterID: "PH001", Duration: 1.5, Type: "Study", Status: "Pending"}
+	changes := []DayPlanDiff{
+		{Date: day(0), Removed: []Session{study}},
+		{Date: day(2), Added: []Session{study}},
+	}
+	movedTo, movedFrom := sessionMoves(changes)
+	if got := movedTo[[2]int{0, 0}]; got != day(2) {
+		t.Errorf("movedTo = %q, want %q", got, day(2))
+	}
+	if got := movedFrom[[2]int{1, 0}]; got != day(0) {
+		t.Errorf("movedFrom = %q, want %q", got, day(0))
+	}
+}
+
+func TestDiffDayPlan(t *testing.T) {
+	study := Session{Subject: "Physics", Chapter: "Gravitation", Duration: 1.5, ChapterID: "PH002", Type: "Study", Status: "Pending"}
+	longer := study
+	longer.Duration = 2
+	revision := Session{Subject: "Biology", Chapter: "Ecosystem (Revision #1)", Duration: 0.5, ChapterID: "BI002", Type: "Revision", Status: "Pending"}
+	buffer := Session{Subject: "Buffer", Chapter: "Recovery/Review", Duration: 0.5, Type: "Buffer", Status: "Pending"}
+
+	tests := []struct {
+		name          string
+		before, after []Session
+		added         int
+		removed       int
+		hoursDelta    float64
+	}{
+		{"identical", []Session{study, buffer}, []Session{study, buffer}, 0, 0, 0},
+		{"reordered", []Session{study, revision}, []Session{revision, study}, 0, 0, 0},
+		{"new file", nil, []Session{study, buffer}, 2, 0, 1.5},
+		{"revision dropped", []Session{revision, study}, []Session{study}, 0, 1, -0.5},
+		{"session resized", []Session{study}, []Session{longer}, 1, 1, 0.5},
+		{"duplicate sessions counted", []Session{study, study}, []Session{study}, 0, 1, -1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := diffDayPlan(testToday, tt.before, tt.after)
+			if len(d.Added) != tt.added || len(d.Removed) != tt.removed {
+				t.Errorf("added %d, removed %d; want %d, %d", len(d.Added), len(d.Removed), tt.added, tt.removed)
+			}
+			if !floatEqual(d.HoursAfter-d.HoursBefore, tt.hoursDelta) {
+				t.Errorf("hours delta = %v, want %v", d.HoursAfter-d.HoursBefore, tt.hoursDelta)
+			}
+			if d.Empty() != (tt.added == 0 && tt.removed == 0) {
+				t.Errorf("Empty() = %v", d.Empty())
+			}
+		})
+	}
+}
+
+// ------------------ Golden plans ------------------
+
+func TestValidateRotationPolicy(t *testing.T) {
+	cfg := testConfig()
+	if problems := validateRotationPolicy(cfg); len(problems) != 0 {
+		t.Errorf("default policy: unexpected problems %v", problems)
+	}
+	cfg.Rotation = RotationPolicy{
+		SubjectsPerDay:    1,
+		RepeatAfterDays:   3,
+		WeeklyHourTargets: map[string]float64{"Maths": 2, "Physics": 40},
+		FixedWeekdays:     map[string][]string{"funday": {"Physics"}, "sunday": {"Biology"}, "monday": {"Chemistry", "Biology"}},
+	}
+	problems := strings.Join(validateRotationPolicy(cfg), "\n")
+	for _, want := range []string{
+		`"funday" is not a weekday`,
+		"Sunday is the weekly rest day",
+		"2 subjects on Monday but only 1 subjects per day",
+		`unknown subject "Maths"`,
+		"add up to 42.0 hrs",
+		"needs 3 freely rotating subjects, there are 1",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("problems do not mention %q:\n%s", want, problems)
+		}
+	}
+}
+
+func TestRotationPolicyFixedWeekdays(t *testing.T) {
+	cfg := testConfig()
+	cfg.Rotation = RotationPolicy{SubjectsPerDay: 1, FixedWeekdays: map[string][]string{"tuesday": {"Biology"}}}
+	useSandbox(t, cfg)
+	generateSchedule()
+
+	for offset := 0; offset <= 9; offset++ {
+		date := testToday.AddDate(0, 0, offset)
+		sessions, err := readDayPlan(date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subjects := map[string]bool{}
+		for _, s := range sessions {
+			if s.Type == "Study" {
+				subjects[s.Subject] = true
+			}
+		}
+		if len(subjects) > 1 {
+			t.Errorf("%s: %d subjects studied, policy allows 1", day(offset), len(subjects))
+		}
+		if date.Weekday() == time.Tuesday {
+			if !subjects["Biology"] {
+				t.Errorf("%s: Biology not studied on its fixed Tuesday: %v", day(offset), subjects)
+			}
+		} else if subjects["Biology"] {
+			t.Errorf("%s: Biology studied outside its fixed weekday", day(offset))
+		}
+	}
+}
+
+func TestRotationWeeklyTargets(t *testing.T) {
+	useSandbox(t, testConfig())
+	policy := RotationPolicy{SubjectsPerDay: 1, RepeatAfterDays: 1, WeeklyHourTargets: map[string]float64{"Physics": 1, "Biology": 5}}
+	r := newSubjectRotation(policy, testToday, nil)
+	r.record(testToday, []Session{{Subject: "Physics", Type: "Study", Duration: 1.5, Status: "Pending"}})
+
+	tomorrow := testToday.AddDate(0, 0, 1)
+	if got := r.pick(tomorrow, []string{"Physics", "Chemistry"}); !reflect.DeepEqual(got, []string{"Chemistry"}) {
+		t.Errorf("pick = %v, want [Chemistry] once Physics met its target", got)
+	}
+	if got := r.pick(tomorrow, []string{"Physics"}); !reflect.DeepEqual(got, []string{"Physics"}) || len(r.Issues) != 1 {
+		t.Errorf("pick = %v with issues %v, want [Physics] and one issue", got, r.Issues)
+	}
+
+	// A subject below its target is preferred over one without a target.
+	for seed := int64(1); seed <= 5; seed++ {
+		seedRandom(seed)
+		if got := r.pick(tomorrow, []string{"Chemistry", "Biology"}); !reflect.DeepEqual(got, []string{"Biology"}) {
+			t.Errorf("seed %d: pick = %v, want [Biology], 5 hrs below its target", seed, got)
+		}
+	}
+
+	// The next week judges the (complete) week that started on testToday.
+	r.startDay(testToday.AddDate(0, 0, 7))
+	if last := r.Issues[len(r.Issues)-1]; !strings.Contains(last, "Biology target (0.0 of 5.0 hrs)") {
+		t.Errorf("last issue = %q, want the Biology shortfall", last)
+	}
+}
+
+func TestGenerateScheduleGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(*Config)
+		setup  func(t *testing.T) // before generation, with the state file in place
+		after  func(t *testing.T) // after generation
+	}{
+		{name: "default"},
+		{
+			name:   "rest-day-wednesday",
+			config: func(c *Config) { c.WeeklyRestDay = time.Wednesday },
+		},
+		{
+			name:   "buffer-90-mins",
+			config: func(c *Config) { c.DailyBufferMins = 90 },
+		},
+		{
+			name: "tiny-syllabus",
+			config: func(c *Config) {
+				c.SyllabusEndDate = day(4)
+				c.InitialWorkload = []ChapterWorkload{
+					{ID: "PH001", Subject: "Physics", Chapter: "Units and Measurement", InitialTotalTime: 2.0, Weightage: 1.0, InitialRevisionIntervalDays: 2},
+				}
+			},
+		},
+		{
+			name: "past-due-revisions",
+			setup: func(t *testing.T) {
+				state, _ := loadState()
+				for id, due := range map[string]string{"PH002": day(-3), "BI002": day(-1)} {
+					wl := state.Workload[id]
+					wl.RemainingTime = 0
+					wl.IsStudyCompleted = true
+					wl.RevisionCount = 1
+					wl.NextRevisionDate = due
+					state.Workload[id] = wl
+				}
+				saveState(state)
+			},
+		},
+		{
+			name: "missed-sessions",
+			after: func(t *testing.T) {
+				sessions, err := readDayPlan(testToday)
+				if err != nil {
+					t.Fatalf("read today's plan: %v", err)
+				}
+				pending := []int{}
+				for i, s := range sessions {
+					if s.Type == "Study" || s.Type == "Revision" {
+						pending = append(pending, i)
+					}
+				}
+				missSessions(sessions, pending, testToday)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			if tt.config != nil {
+				tt.config(&cfg)
+			}
+			useSandbox(t, cfg)
+			loadState()
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			generateSchedule()
+			if tt.after != nil {
+				tt.after(t)
+			}
+			compareGoldenPlans(t, filepath.Join(goldenDir, tt.name))
+		})
+	}
+}
+
+// compareGoldenPlans checks every file in plans/ against dir, file by file.
+func compareGoldenPlans(t *testing.T, dir string) {
+	t.Helper()
+	got := readPlanDir(t, SCHEDULE_DIR)
+
+	if *updateGolden {
+		os.RemoveAll(dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range got {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+
+	want := readPlanDir(t, dir)
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("%s differs from golden:\n--- got ---\n%s\n--- want ---\n%s", name, got[name], content)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("unexpected plan file %s (run go test -update to accept)", name)
+		}
+	}
+}
+
+func readPlanDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read %s: %v", dir, err)
+	}
+	plans := map[string]string{}
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plans[f.Name()] = string(data)
+	}
+	return plans
+}
+
+// ------------------ Plan parsers ------------------
+
+func TestDecodePlan(t *testing.T) {
+	useConfig(t, testConfig())
+	sessions := []Session{
+		{Subject: "Physics", Chapter: "Laws of Motion", Duration: 1.5, ChapterID: "PH001", Type: "Study", Status: "Completed"},
+		{Subject: "Biology", Chapter: "Ecosystem (Revision #2)", Duration: 0.5, ChapterID: "BI002", Type: "Revision", Status: "Missed"},
+		{Subject: "Buffer", Chapter: "Recovery/Review", Duration: 0.5, Type: "Buffer", Status: "Pending"},
+	}
+
+	tests := []struct {
+		name       string
+		content    string
+		wantFormat int
+		want       []Session
+		wantErr    bool
+	}{
+		{
+			name:       "v2 round trip",
+			content:    encodePlan(testToday, sessions),
+			wantFormat: planFormatV2,
+			want:       sessions,
+		},
+		{
+			name: "legacy block",
+			content: "DATE: 2025-01-06 (Monday)\n\n" +
+				"SESSION 1:\n  Subject:  Physics\n  Chapter:  Laws of Motion\n  Duration: 1.50 hrs\n  Status:   Completed\n  Type:     Study\n  ID:       PH001\n\n" +
+				"SESSION 2:\n  Subject:  Biology\n  Chapter:  Ecosystem (Revision #2)\n  Duration: 0.50 hrs\n  Status:   Missed\n  Type:     Revision\n  ID:       BI002\n\n" +
+				"BUFFER:\n  Subject:  Buffer\n  Chapter:  Recovery/Review\n  Duration: 0.50 hrs\n  Status:   Pending\n  Type:     Buffer\n",
+			wantFormat: planFormatLegacyBlock,
+			want:       sessions,
+		},
+		{
+			name: "legacy pipe recovers chapter IDs",
+			content: "Physics | Laws of Motion | 1.5 | Study | Completed\n" +
+				"Biology | Ecosystem (Revision #2) | 0.5 | Revision | Missed\n" +
+				"Buffer | Recovery/Review | 0.5 | Buffer | Pending\n",
+			wantFormat: planFormatLegacyPipe,
+			want:       sessions,
+		},
+		{
+			name:       "legacy pipe with unknown chapter",
+			content:    "Physics | Not In Syllabus | 1 | Study | Pending\n",
+			wantFormat: planFormatLegacyPipe,
+			want:       []Session{{Subject: "Physics", Chapter: "Not In Syllabus", Duration: 1, Type: "Study", Status: "Pending"}},
+		},
+		{
+			name:       "newer schema version",
+			content:    PLAN_FILE_HEADER + "\nVERSION: 99\nDATE: 2025-01-06 (Monday)\n\n",
+			wantFormat: planFormatV2,
+			wantErr:    true,
+		},
+		{
+			name:       "unrecognised",
+			content:    "just some notes\n",
+			wantFormat: planFormatUnknown,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, format, err := decodePlan(tt.content)
+			if format != tt.wantFormat {
+				t.Errorf("format = %d, want %d", format, tt.wantFormat)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sessions =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanStoreMigratesLegacyFiles(t *testing.T) {
+	useSandbox(t, testConfig())
+	store := NewPlanStore(SCHEDULE_DIR)
+	if err := os.MkdirAll(SCHEDULE_DIR, 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := "Physics | Gravitation | 1.0 | Study | Pending\n"
+	if err := os.WriteFile(store.Path(testToday), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := store.MigrateAll()
+	if err != nil || n != 1 {
+		t.Fatalf("MigrateAll = %d, %v; want 1, nil", n, err)
+	}
+	data, _ := os.ReadFile(store.Path(testToday))
+	if !strings.HasPrefix(string(data), PLAN_FILE_HEADER) {
+		t.Fatalf("file was not rewritten in the current format:\n%s", data)
+	}
+	sessions, err := store.Load(testToday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ChapterID != "PH002" {
+		t.Errorf("migrated sessions = %+v, want one session for PH002", sessions)
+	}
+	if n, _ := store.MigrateAll(); n != 0 {
+		t.Errorf("second MigrateAll rewrote %d files, want 0", n)
+	}
+}
+
+func TestCheckPrerequisites(t *testing.T) {
+	workload := testConfig().InitialWorkload
+	workload[0].Prerequisites = []string{"PH002", "XX001"}
+	if problems, cycle := checkPrerequisites(workload); len(problems) != 1 || cycle != nil {
+		t.Errorf("checkPrerequisites = %v, %v; want one unknown ID and no cycle", problems, cycle)
+	}
+
+	workload[1].Prerequisites = []string{"CH002"}
+	workload[3].Prerequisites = []string{"PH001"}
+	_, cycle := checkPrerequisites(workload)
+	if want := []string{"CH002", "PH001", "PH002", "CH002"}; !reflect.DeepEqual(cycle, want) {
+		t.Errorf("cycle = %v, want %v", cycle, want)
+	}
+
+	cfg := Config{InitialWorkload: workload}
+	validatePrerequisites(&cfg)
+	if len(prerequisiteGraph(cfg)) != 0 {
+		t.Errorf("prerequisites kept despite the cycle: %v", prerequisiteGraph(cfg))
+	}
+}
+
+func TestPrerequisiteOrdering(t *testing.T) {
+	cfg := testConfig()
+	// PH001 and CH001 outrank what they depend on, so only the prerequisites
+	// hold them back.
+	cfg.InitialWorkload[0].Prerequisites = []string{"PH002"}
+	cfg.InitialWorkload[2].Prerequisites = []string{"CH002"}
+	useSandbox(t, cfg)
+
+	state, _ := loadState()
+	blocked := blockedChapters(state)
+	if ids := []string{}; len(blocked) != 2 {
+		t.Fatalf("blockedChapters = %v, want CH001 and PH001", blocked)
+	} else {
+		for _, b := range blocked {
+			ids = append(ids, b.ChapterID+"<"+strings.Join(b.WaitingFor, ","))
+		}
+		if want := []string{"CH001<CH002", "PH001<PH002"}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("blocked = %v, want %v", ids, want)
+		}
+	}
+
+	generateSchedule()
+	// position records the plan order of each study session as day*100+index.
+	first, last := map[string]int{}, map[string]int{}
+	for offset := 0; offset <= 9; offset++ {
+		sessions, err := readDayPlan(testToday.AddDate(0, 0, offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, s := range sessions {
+			if s.Type != "Study" {
+				continue
+			}
+			position := offset*100 + i
+			if _, ok := first[s.ChapterID]; !ok {
+				first[s.ChapterID] = position
+			}
+			last[s.ChapterID] = position
+		}
+	}
+	for chapter, prerequisite := range map[string]string{"PH001": "PH002", "CH001": "CH002"} {
+		if _, ok := first[chapter]; !ok {
+			t.Errorf("%s never scheduled", chapter)
+			continue
+		}
+		if first[chapter] < last[prerequisite] {
+			t.Errorf("%s starts at %d before %s finishes at %d", chapter, first[chapter], prerequisite, last[prerequisite])
+		}
+	}
+}
+
+func TestCoverSubTopics(t *testing.T) {
+	wl := ChapterWorkload{ID: "PH001", SubTopics: []SubTopic{{Name: "a", Hours: 1}, {Name: "b", Hours: 2}, {Name: "c", Hours: 1}}}
+	if got := coverSubTopics(&wl, 1.5, nil); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("coverSubTopics = %v, want [a b]", got)
+	}
+	if got := coverSubTopics(&wl, 1, []string{"c"}); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("coverSubTopics with c first = %v, want [c]", got)
+	}
+	syncRemainingTime(&wl)
+	if !wl.SubTopics[0].Completed || wl.SubTopics[1].Completed || !wl.SubTopics[2].Completed || !floatEqual(wl.RemainingTime, 1.5) {
+		t.Errorf("after covering: %+v, remaining %v", wl.SubTopics, wl.RemainingTime)
+	}
+
+	wl, err := completeSubTopic(wl, "b", testToday)
+	if err != nil || !wl.IsStudyCompleted || wl.RemainingTime > 0.001 {
+		t.Errorf("completeSubTopic(b) = %+v, %v; want the chapter's study complete", wl, err)
+	}
+	if _, err := completeSubTopic(wl, "z", testToday); err == nil {
+		t.Error("completeSubTopic accepted an unknown sub-topic")
+	}
+}
+
+func TestSubTopicSessions(t *testing.T) {
+	cfg := testConfig()
+	cfg.InitialWorkload[0].SubTopics = []SubTopic{{Name: "Newton's laws", Hours: 1}, {Name: "Friction", Hours: 2}, {Name: "Circular motion", Hours: 2}}
+	useSandbox(t, cfg)
+	if wl := initializeState(cfg).Workload["PH001"]; !floatEqual(wl.InitialTotalTime, 5) || !floatEqual(wl.RemainingTime, 5) {
+		t.Fatalf("PH001 hours = %v/%v, want both derived from sub-topics (5)", wl.RemainingTime, wl.InitialTotalTime)
+	}
+	generateSchedule()
+
+	sessions, err := readDayPlan(testToday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := -1
+	for i, s := range sessions {
+		if s.ChapterID == "PH001" {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		t.Fatalf("PH001 not planned today: %+v", sessions)
+	}
+	if want := []string{"Newton's laws", "Friction"}; !reflect.DeepEqual(sessions[idx].Topics, want) {
+		t.Errorf("first PH001 session covers %v, want %v", sessions[idx].Topics, want)
+	}
+
+	completeSession(sessions, idx, testToday, 3600, RECALL_GRADE_DEFAULT, nil, nil)
+	state, _ := loadState()
+	wl := state.Workload["PH001"]
+	if !wl.SubTopics[0].Completed || wl.SubTopics[1].Completed || !floatEqual(wl.RemainingTime, 4) {
+		t.Errorf("after one hour: %+v, remaining %v", wl.SubTopics, wl.RemainingTime)
+	}
+	events, err := loadEventLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed := replayEventLog(cfg, events).Workload["PH001"]; !reflect.DeepEqual(replayed.SubTopics, wl.SubTopics) {
+		t.Errorf("replayed sub-topics = %+v, want %+v", replayed.SubTopics, wl.SubTopics)
+	}
+
+	// A state from before the sub-topics were configured adopts them, with
+	// the hour already studied credited to the first one.
+	old := initializeState(testConfig())
+	ph := old.Workload["PH001"]
+	ph.RemainingTime = 3
+	old.Workload["PH001"] = ph
+	if !adoptSubTopics(&old, cfg) || !old.Workload["PH001"].SubTopics[0].Completed || !floatEqual(old.Workload["PH001"].RemainingTime, 4) {
+		t.Errorf("adopted PH001 = %+v", old.Workload["PH001"])
+	}
+}
+
+func TestStudyActivity(t *testing.T) {
+	mix := map[string]float64{ActivityTheory: 0.4, ActivityProblems: 0.6}
+	tests := []struct {
+		remaining, duration float64
+		mix                 map[string]float64
+		want                string
+	}{
+		{10, 2, mix, ActivityTheory},
+		{7, 2, mix, ActivityProblems}, // midpoint at 40% is past the theory share
+		{1, 1, mix, ActivityProblems},
+		{1, 1, DEFAULT_ACTIVITY_MIX, ActivityPYQ},
+		{10, 2, map[string]float64{ActivityTheory: -1}, ActivityTheory}, // unusable mix: default
+	}
+	for _, tt := range tests {
+		wl := ChapterWorkload{InitialTotalTime: 10, RemainingTime: tt.remaining}
+		if got := studyActivity(tt.mix, wl, tt.duration); got != tt.want {
+			t.Errorf("studyActivity(%v, remaining %v, %vh) = %s, want %s", tt.mix, tt.remaining, tt.duration, got, tt.want)
+		}
+	}
+
+	cfg := testConfig()
+	cfg.ActivityMix = map[string]map[string]float64{
+		"Physics": {ActivityProblems: 1},
+		"CH001":   {"notes": 1},
+		"Maths":   {ActivityTheory: 1},
+	}
+	if problems := validateActivityMix(cfg); len(problems) != 3 {
+		t.Errorf("validateActivityMix = %q, want 3 problems", problems)
+	}
+	if got := activityMixFor(cfg, cfg.InitialWorkload[0]); got[ActivityProblems] != 1 {
+		t.Errorf("PH001 mix = %v, want the Physics entry", got)
+	}
+	if got := activityMixFor(cfg, cfg.InitialWorkload[3]); !reflect.DeepEqual(got, DEFAULT_ACTIVITY_MIX) {
+		t.Errorf("CH002 mix = %v, want the default", got)
+	}
+}
+
+func TestGeneratedSessionActivities(t *testing.T) {
+	cfg := testConfig()
+	cfg.ActivityMix = map[string]map[string]float64{"Physics": {ActivityProblems: 1}}
+	useSandbox(t, cfg)
+	generateSchedule()
+
+	counts := map[string]int{}
+	for offset := 0; offset <= 14; offset++ {
+		sessions, err := readDayPlan(testToday.AddDate(0, 0, offset))
+		if err != nil {
+			continue
+		}
+		for _, s := range sessions {
+			switch {
+			case s.Type == "Study" && s.Subject == "Physics" && s.Activity != ActivityProblems:
+				t.Errorf("%s: Physics study session is %q, want problems only", day(offset), s.Activity)
+			case s.Type == "Revision" && s.Activity != ActivityFlashcards && s.Activity != ActivityPYQ:
+				t.Errorf("%s: revision session has activity %q", day(offset), s.Activity)
+			case s.Type == "Mock" && s.Activity != ActivityMock:
+				t.Errorf("%s: mock session has activity %q", day(offset), s.Activity)
+			}
+			counts[s.Activity]++
+		}
+	}
+	for _, activity := range []string{ActivityTheory, ActivityProblems, ActivityPYQ, ActivityMock} {
+		if counts[activity] == 0 {
+			t.Errorf("no %s sessions planned: %v", activity, counts)
+		}
+	}
+}
+
+func TestTimetableConfig(t *testing.T) {
+	if b, err := parseTimeBlock("06:30-08:00"); err != nil || b != (TimeBlock{Start: 390, End: 480}) {
+		t.Errorf("parseTimeBlock = %+v, %v", b, err)
+	}
+	for _, bad := range []string{"06:30", "08:00-06:00", "6-8", "10:00-25:00"} {
+		if _, err := parseTimeBlock(bad); err == nil {
+			t.Errorf("parseTimeBlock(%q) accepted", bad)
+		}
+	}
+
+	cfg := testConfig()
+	cfg.Timetable = map[string][]string{
+		"default":  {"06:00-08:00", "16:00-21:00"},
+		"Saturday": {"09:00-12:00", "11:00-13:00"},
+		"someday":  {"09:00-10:00"},
+		"monday":   {"9-10"},
+	}
+	if problems := validateTimetable(cfg); len(problems) != 3 {
+		t.Errorf("validateTimetable = %q, want 3 problems", problems)
+	}
+	if blocks, ok := timetableBlocks(cfg, time.Saturday); !ok || len(blocks) != 1 {
+		t.Errorf("Saturday blocks = %v, %v; want the overlapping block dropped", blocks, ok)
+	}
+	if blocks, ok := timetableBlocks(cfg, time.Tuesday); !ok || len(blocks) != 2 {
+		t.Errorf("Tuesday blocks = %v, %v; want the default entry", blocks, ok)
+	}
+	if blocks, ok := timetableBlocks(cfg, time.Monday); !ok || len(blocks) != 0 {
+		t.Errorf("Monday blocks = %v, %v; want none (its only block is invalid)", blocks, ok)
+	}
+	if _, ok := timetableBlocks(testConfig(), time.Monday); ok {
+		t.Error("a config without timetable has blocks")
+	}
+}
+
+func TestScheduleTimes(t *testing.T) {
+	cfg := testConfig()
+	cfg.Timetable = map[string][]string{"default": {"06:00-07:00", "16:00-19:00"}}
+	useSandbox(t, cfg)
+	tomorrow := testToday.AddDate(0, 0, 1)
+	sessions := []Session{
+		{Subject: "Physics", ChapterID: "PH001", Type: "Study", Duration: 1.5, Status: "Pending"},
+		{Subject: "Chemistry", ChapterID: "CH001", Type: "Study", Duration: 1.5, Status: "Pending"},
+		{Subject: "Biology", ChapterID: "BI001", Type: "Revision", Duration: 0.5, Status: "Pending"},
+		bufferSession(),
+	}
+	timed, unplaced := scheduleTimes(tomorrow, sessions, clock.Now())
+
+	blocks, _ := timetableBlocks(cfg, tomorrow.Weekday())
+	planned, prevEnd, prevStudy := 0.0, -1, false
+	for _, s := range timed {
+		if s.Type == "Break" {
+			continue
+		}
+		planned += s.Duration
+		if s.Start == "" {
+			continue
+		}
+		start, _ := parseClock(s.Start)
+		end, _ := parseClock(s.End)
+		inside := false
+		for _, b := range blocks {
+			inside = inside || (start >= b.Start && end <= b.End)
+		}
+		if !inside || start < prevEnd {
+			t.Errorf("%s %s-%s is outside the timetable or overlaps the session before", s.Subject, s.Start, s.End)
+		}
+		if isStudy := s.Type != "Buffer"; isStudy && prevStudy && start == prevEnd {
+			t.Errorf("%s at %s follows a study session without a break", s.Subject, s.Start)
+		}
+		prevEnd, prevStudy = end, s.Type != "Buffer"
+	}
+	if !floatEqual(planned, 4.0) || !floatEqual(unplaced, 0.5) {
+		t.Errorf("timed plan holds %.2f hrs with %.2f unplaced, want all 4.00 with the buffer's 0.50 unplaced", planned, unplaced)
+	}
+	if timed[0].ChapterID != "CH001" || timed[0].Start != "06:00" || !floatEqual(timed[0].Duration, 1) {
+		t.Errorf("first session = %+v, want the first hour of CH001 split into the morning gap", timed[0])
+	}
+
+	// Today, only the rest of the day is used and worked sessions keep their time.
+	clock = &manualClock{now: tomorrow.Add(17 * time.Hour)}
+	done := Session{Subject: "Physics", ChapterID: "PH001", Type: "Study", Duration: 1, Status: "Completed", Start: "16:00", End: "17:00"}
+	timed, _ = scheduleTimes(tomorrow, []Session{done, sessions[2]}, clock.Now())
+	if len(timed) != 2 || timed[0].Start != "16:00" || timed[1].Start != "17:00" {
+		t.Errorf("re-timed today = %+v, want the completed session kept and the revision from 17:00", timed)
+	}
+}
+
+func TestDueSession(t *testing.T) {
+	at := func(hour, minute int) time.Time { return testToday.Add(time.Duration(hour*60+minute) * time.Minute) }
+	sessions := []Session{
+		{Type: "Study", Status: "Completed", Start: "06:00", End: "07:00"},
+		{Type: "Break", Status: "Pending", Start: "07:00", End: "07:10"},
+		{Type: "Study", Status: "Pending", Start: "07:10", End: "08:00"},
+		{Type: "Revision", Status: "Pending", Start: "16:00", End: "17:00"},
+		{Type: "Study", Status: "Pending"},
+	}
+	tests := []struct {
+		now  time.Time
+		want int
+	}{
+		{at(6, 30), 2}, // the completed session's slot: propose the next one
+		{at(7, 30), 2},
+		{at(12, 0), 3},
+		{at(20, 0), 2}, // everything late: the first pending session
+	}
+	for _, tt := range tests {
+		if got := dueSession(sessions, tt.now); got != tt.want {
+			t.Errorf("dueSession at %s = %d, want %d", tt.now.Format("15:04"), got, tt.want)
+		}
+	}
+	if got := dueSession(sessions[:2], at(7, 0)); got != -1 {
+		t.Errorf("dueSession with nothing pending = %d, want -1", got)
+	}
+}
+
+func TestGenerateWithTimetable(t *testing.T) {
+	cfg := testConfig()
+	cfg.Timetable = map[string][]string{"default": {"06:00-07:30", "17:00-19:00"}}
+	useSandbox(t, cfg)
+	generateSchedule()
+
+	for offset := 0; offset <= 13; offset++ {
+		date := testToday.AddDate(0, 0, offset)
+		sessions, err := readDayPlan(date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		capacity, _ := timetableStudyHours(date, 0)
+		if got := studyHours(sessions); got > capacity+0.001 {
+			t.Errorf("%s: %.2f study hrs planned, timetable allows %.2f", day(offset), got, capacity)
+		}
+		for _, s := range sessions {
+			if s.Type != "Rest" && s.Start == "" {
+				t.Errorf("%s: %s session %s has no time", day(offset), s.Type, s.Chapter)
+			}
+		}
+	}
+}
+
+func TestCalendarOverrides(t *testing.T) {
+	three := 3.0
+	cfg := testConfig()
+	cfg.Calendar = []CalendarOverride{
+		{Date: day(2), Kind: CalendarRest, Note: "Holiday"},
+		{Date: day(3), To: day(4), Kind: CalendarHalf},
+		{Date: day(4), Kind: CalendarExam},
+		{Date: day(6), Kind: CalendarTravel, Hours: &three},
+	}
+	useSandbox(t, cfg)
+
+	tests := []struct {
+		offset int
+		want   float64
+	}{
+		{1, 4}, {2, 0}, {3, 2}, {4, 1}, {6, 3}, {13, 0},
+	}
+	for _, tt := range tests {
+		if got := studyHoursOn(testToday.AddDate(0, 0, tt.offset)); !floatEqual(got, tt.want) {
+			t.Errorf("studyHoursOn(%s) = %.2f, want %.2f", day(tt.offset), got, tt.want)
+		}
+	}
+	if s := restDaySession(testToday.AddDate(0, 0, 2)); s.Chapter != "Holiday" {
+		t.Errorf("rest session on the holiday = %q, want its note", s.Chapter)
+	}
+
+	// Days 0-9 less the holiday; the travel day replaces the Sunday rest.
+	state := initializeState(cfg)
+	calculateQuotas(&state)
+	if state.NetStudyDays != 9 {
+		t.Errorf("NetStudyDays = %d, want 9", state.NetStudyDays)
+	}
+
+	bad := testConfig()
+	bad.Calendar = []CalendarOverride{
+		{Date: "2025-13-01", Kind: CalendarRest},
+		{Date: day(3), To: day(1), Kind: CalendarRest},
+		{Date: day(3), Kind: "party"},
+	}
+	if problems := validateCalendar(bad); len(problems) != 3 {
+		t.Errorf("validateCalendar = %q, want 3 problems", problems)
+	}
+}
+
+func TestGenerateWithCalendar(t *testing.T) {
+	cfg := testConfig()
+	cfg.Calendar = []CalendarOverride{
+		{Date: day(2), Kind: CalendarSick},
+		{Date: day(3), Kind: CalendarHalf},
+		{Date: day(1)}, // no kind: ignored, not a rest day
+	}
+	useSandbox(t, cfg)
+	generateSchedule()
+
+	plans := map[int][]Session{}
+	for _, offset := range []int{1, 2, 3} {
+		sessions, err := readDayPlan(testToday.AddDate(0, 0, offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plans[offset] = sessions
+	}
+	if len(plans[2]) != 1 || plans[2][0].Type != "Rest" || plans[2][0].Chapter != "Sick day" {
+		t.Errorf("sick day plan = %+v, want a single rest session", plans[2])
+	}
+	if plans[1][0].Type == "Rest" {
+		t.Errorf("a calendar entry without kind made %s a rest day", day(1))
+	}
+	full, half := studyHours(plans[1]), studyHours(plans[3])
+	if half <= 0 || half >= full {
+		t.Errorf("half day has %.2f study hrs, full day %.2f", half, full)
+	}
+}
+
+func TestReportJSONFirstRun(t *testing.T) {
+	useSandbox(t, testConfig())
+	os.Remove(CONFIG_FILE) // first run: no config and no state yet
+
+	var code int
+	out := captureStdout(t, func() { code = runCommand([]string{"report", "--format", "json"}) })
+	if code != ExitOK {
+		t.Fatalf("report exited with %d", code)
+	}
+	var report ReportData
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("stdout is not a json report: %v\n%s", err, out)
+	}
+	if report.SchemaVersion != REPORT_SCHEMA_VERSION || report.ChaptersTotal == 0 {
+		t.Errorf("report = version %d with %d chapters", report.SchemaVersion, report.ChaptersTotal)
+	}
+}
+
+func TestValidateSubTopics(t *testing.T) {
+	cfg := testConfig()
+	cfg.InitialWorkload[0].SubTopics = []SubTopic{{Name: "Vectors; scalars", Hours: 1}, {Name: " ", Hours: 1}}
+	useSandbox(t, cfg)
+	validateSubTopics(&cfg)
+
+	topics := []string{cfg.InitialWorkload[0].SubTopics[0].Name, cfg.InitialWorkload[0].SubTopics[1].Name}
+	if want := []string{"Vectors, scalars", "Part 2"}; !reflect.DeepEqual(topics, want) {
+		t.Fatalf("sub-topic names = %q, want %q", topics, want)
+	}
+	session := Session{Subject: "Physics", Chapter: "Units", ChapterID: "PH001", Duration: 1, Type: "Study", Status: "Pending", Topics: topics}
+	writeDayPlan(testToday, []Session{session})
+	if got, err := readDayPlan(testToday); err != nil || !reflect.DeepEqual(got[0].Topics, topics) {
+		t.Errorf("topics read back as %q, want %q", got[0].Topics, topics)
+	}
+}
+
+func TestAPIRequestGuard(t *testing.T) {
+	useSandbox(t, testConfig())
+	generateSchedule()
+	handler := newAPIHandler(false)
+
+	tests := []struct {
+		name, method, path, host, origin, body string
+		want                                   int
+	}{
+		{"loopback", "GET", "/api/config", "127.0.0.1:8765", "", "", http.StatusOK},
+		{"localhost", "GET", "/api/config", "localhost:8765", "", "", http.StatusOK},
+		{"same origin", "PATCH", "/api/config", "127.0.0.1:8765", "http://127.0.0.1:8765", `{"daily_study_hrs": 5}`, http.StatusOK},
+		{"rebound host", "GET", "/api/report", "evil.example:8765", "", "", http.StatusForbidden},
+		{"cross-site post", "POST", "/api/sessions/1/miss", "127.0.0.1:8765", "https://evil.example", "", http.StatusForbidden},
+		{"string for number", "PATCH", "/api/config", "127.0.0.1:8765", "", `{"daily_study_hrs": "5"}`, http.StatusBadRequest},
+		{"number for string", "PATCH", "/api/config", "127.0.0.1:8765", "", `{"rest_day_activity": 5}`, http.StatusBadRequest},
+		{"structured key", "PATCH", "/api/config", "127.0.0.1:8765", "", `{"rotation": {"subjects_per_day": -3}}`, http.StatusBadRequest},
+		{"start", "POST", "/api/sessions/1/start", "127.0.0.1:8765", "", "", http.StatusOK},
+		{"focus without understanding", "POST", "/api/sessions/1/finish", "127.0.0.1:8765", "", `{"focus": 4}`, http.StatusBadRequest},
+		{"notes without understanding", "POST", "/api/sessions/1/finish", "127.0.0.1:8765", "", `{"notes": "ok"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Host = tt.host
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, rec.Code, tt.want, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+	if rawConfig.DailyStudyHrs != 5 {
+		t.Errorf("daily_study_hrs = %v after the patches, want 5", rawConfig.DailyStudyHrs)
+	}
+	if plan, _ := readDayPlan(testToday); plan[0].Status != "Pending" {
+		t.Errorf("rejected requests changed session 1 to %s", plan[0].Status)
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	tests := []struct {
+		key, value string
+		ok         bool
+	}{
+		{"daily_study_hrs", "6.5", true},
+		{"daily_study_hrs", "-2", false},
+		{"max_session_hrs", "0", false},
+		{"daily_buffer_mins", "300", false},
+		{"weekly_rest_day", "saturday", true},
+		{"weekly_rest_day", "9", false},
+		{"revision_model", "fsrs", true},
+		{"revision_model", "leitner", false},
+		{"syllabus_end_date", "2025-02-30", false},
+		{"initial_workload", "[]", false},
+		{"rotation", `{"subjects_per_day":-3}`, false},
+		{"calendar", "[]", false},
+		{"no_such_key", "1", false},
+	}
+	for _, tt := range tests {
+		_, err := setConfigValue(testConfig(), tt.key, tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("config set %s %s: err = %v, want ok = %v", tt.key, tt.value, err, tt.ok)
+		}
+	}
+}